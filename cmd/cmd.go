@@ -16,14 +16,17 @@ package cmd
 
 import (
 	"bufio"
+	"bytes"
 	"flag"
 	"fmt"
 	"os"
 	"path"
+	"regexp"
 	"strconv"
 	"strings"
 
 	"github.com/stella-go/stella/creator/proj"
+	"github.com/stella-go/stella/generator"
 	"github.com/stella-go/stella/generator/curd"
 	"github.com/stella-go/stella/generator/model"
 	"github.com/stella-go/stella/generator/parser"
@@ -34,6 +37,8 @@ import (
 	"github.com/stella-go/stella/version"
 )
 
+var bannerDate = regexp.MustCompile(`Auto Generate by github\.com/stella-go/stella \S+ on (\d{4}/\d{2}/\d{2})\.`)
+
 func Generate() {
 	flagSet := flag.NewFlagSet("stella generate", flag.ExitOnError)
 	flagSet.Usage = func() {
@@ -60,15 +65,36 @@ Usage:
 	c := flagSet.Bool("curd", false, "generate curd")
 	asc := flagSet.String("asc", "", "order by")
 	desc := flagSet.String("desc", "", "reverse order by")
-	logic := flagSet.String("logic", "", "logic delete")
-	round := flagSet.String("round", "s", "round time [s/ms/μs]")
+	logic := flagSet.String("logic", "", "logic delete [table.]column=deleted[:undeleted] or [table.]timestamp_column, comma separated")
+	round := flagSet.String("round", "auto", "round time [auto/s/ms/μs/none], auto follows the precision of every column")
+	timezone := flagSet.String("timezone", "", "time zone of the DATETIME and DATE values [utc/local]")
+	dialect := flagSet.String("dialect", curd.MySQL, "sql dialect [mysql/postgres/sqlite/sqlserver]")
+	versioned := flagSet.Bool("version", false, "optimistic locking on the integer columns named version, a column commented with @version locks without it")
 
 	generateRouter := flagSet.Bool("router", false, "generate router")
 	generateService := flagSet.Bool("service", false, "generate service")
+	curdService := flagSet.Bool("curd-service", false, "service calling the generated curd functions")
+	mock := flagSet.Bool("mock", false, "generate a mock of the service interface")
+	cache := flagSet.Bool("cache", false, "generate a caching decorator of the service")
+	hooks := flagSet.Bool("hooks", false, "call the before and after hooks of the service writes")
 
 	panicStyle := flagSet.Bool("panic", false, "panic style")
+	ctx := flagSet.Bool("ctx", false, "context.Context aware functions")
+	tx := flagSet.Bool("tx", false, "generate transaction helpers")
+	keyset := flagSet.Bool("keyset", false, "generate keyset pagination")
+	filter := flagSet.Bool("filter", false, "generate filter queries")
+	sort := flagSet.Bool("sort", false, "dynamic order by on sortable columns")
+	generateTest := flagSet.Bool("test", false, "generate curd tests")
+	projection := flagSet.Bool("projection", false, "queries selecting only the given columns")
+	aggregate := flagSet.Bool("aggregate", false, "sum, max and min of the numeric columns")
+	stream := flagSet.Bool("stream", false, "streaming iteration over the rows, requires go 1.23")
+	prepare := flagSet.Bool("prepare", false, "prepared statements for the fixed sql")
+	mask := flagSet.Bool("mask", false, "updates of the listed columns, nil ones set to null")
+	repository := flagSet.Bool("repository", false, "generic repository instead of the functions of every table")
+	audit := flagSet.Bool("audit", false, "record the creates, updates and deletes in the audit_log table, requires -ctx")
 
 	banner := flagSet.Bool("banner", true, "output banner")
+	check := flagSet.Bool("check", false, "check generated files are up to date")
 
 	h := flagSet.Bool("h", false, "print help info")
 	help := flagSet.Bool("help", false, "print help info")
@@ -77,7 +103,70 @@ Usage:
 		flagSet.Usage()
 		return
 	}
-	generate(*p, *i, *sub, *o, *std, *f, *banner, *m, *gorm, *c, *logic, *asc, *desc, *round, *generateRouter, *generateService, *panicStyle)
+	switch *dialect {
+	case curd.MySQL, curd.Postgres, curd.SQLite, curd.SQLServer:
+	default:
+		fmt.Printf("unknown dialect \"%s\"\n", *dialect)
+		os.Exit(1)
+	}
+	switch *timezone {
+	case "", "utc", "local":
+	default:
+		fmt.Printf("unknown time zone \"%s\"\n", *timezone)
+		os.Exit(1)
+	}
+	if *repository && (*tx || *keyset || *filter || *sort || *generateTest || *projection || *aggregate || *stream || *prepare || *mask) {
+		fmt.Println("-repository can not be combined with -tx, -keyset, -filter, -sort, -test, -projection, -aggregate, -stream, -prepare or -mask")
+		os.Exit(1)
+	}
+	if *audit && (!*ctx || *repository || *generateTest) {
+		fmt.Println("-audit requires -ctx and can not be combined with -repository or -test")
+		os.Exit(1)
+	}
+	if *curdService && (*gorm || *repository) {
+		fmt.Println("-curd-service can not be combined with -gorm or -repository")
+		os.Exit(1)
+	}
+	if *hooks && *tx && *generateService && !*curdService && !*gorm {
+		fmt.Println("-hooks with -tx binds the service to the transaction, generate it with -curd-service or -gorm")
+		os.Exit(1)
+	}
+	if *generateService && !*curdService && !*c {
+		needs := make([]string, 0)
+		if *logic != "" {
+			needs = append(needs, "-logic")
+		}
+		if *keyset {
+			needs = append(needs, "-keyset")
+		}
+		if *filter {
+			needs = append(needs, "-filter")
+		}
+		if *sort {
+			needs = append(needs, "-sort")
+		}
+		if *projection {
+			needs = append(needs, "-projection")
+		}
+		if *mask {
+			needs = append(needs, "-mask")
+		}
+		if len(needs) > 0 {
+			fmt.Printf("-service with %s calls the functions of -curd, generate it with -curd\n", strings.Join(needs, ", "))
+			os.Exit(1)
+		}
+	}
+	drift := generate(&generateOptions{
+		pkg: *p, input: *i, sub: *sub, output: *o, std: *std, file: *f, banner: *banner, check: *check,
+		model: *m, gorm: *gorm, curd: *c, repository: *repository, test: *generateTest, audit: *audit,
+		router: *generateRouter, service: *generateService, curdService: *curdService, mock: *mock, cache: *cache, hooks: *hooks,
+		logic: *logic, asc: *asc, desc: *desc, round: *round, timezone: *timezone, dialect: *dialect,
+		panicStyle: *panicStyle, ctx: *ctx, tx: *tx, keyset: *keyset, filter: *filter, sort: *sort, projection: *projection,
+		aggregate: *aggregate, stream: *stream, prepare: *prepare, mask: *mask, versioned: *versioned,
+	})
+	if drift {
+		os.Exit(1)
+	}
 }
 
 func readFileWithStdin(input string, sub string) string {
@@ -156,70 +245,149 @@ func readFileWithStdin(input string, sub string) string {
 	return sql
 }
 
-func generate(pkg string, input string, sub string, output string, std bool, file string, banner bool, m bool, gorm bool, c bool, logic string, asc string, desc string, round string, generateRouter bool, generateService bool, panicStyle bool) {
-	sql := readFileWithStdin(input, sub)
+// generateOptions are the flags of stella generate.
+type generateOptions struct {
+	pkg, input, sub, output, file string
+	std, banner, check            bool
+
+	model, gorm, curd, repository, test, audit            bool
+	router, service, curdService, mock, cache, hooks      bool
+	logic, asc, desc, round, timezone, dialect            string
+	panicStyle, ctx, tx, keyset, filter, sort, projection bool
+	aggregate, stream, prepare, mask, versioned           bool
+}
+
+func (g *generateOptions) curdOptions() *curd.Options {
+	return &curd.Options{
+		Banner: g.banner, Logic: g.logic, Asc: g.asc, Desc: g.desc, Round: g.round, Zone: g.timezone, Dialect: g.dialect,
+		Ctx: g.ctx, Tx: g.tx, Keyset: g.keyset, Filter: g.filter, Sort: g.sort, Projection: g.projection,
+		Aggregate: g.aggregate, Stream: g.stream, Prepare: g.prepare, Mask: g.mask, Audit: g.audit,
+	}
+}
+
+func (g *generateOptions) serviceOptions() *service.Options {
+	return &service.Options{
+		Banner: g.banner, Logic: g.logic, Ctx: g.ctx, Tx: g.tx, Keyset: g.keyset, Filter: g.filter, Sort: g.sort,
+		Projection: g.projection, Mask: g.mask, Hooks: g.hooks, Gorm: g.gorm, Curd: g.curdService, Panic: g.panicStyle,
+	}
+}
+
+func (g *generateOptions) routerOptions() *router.Options {
+	return &router.Options{
+		Banner: g.banner, Ctx: g.ctx, Keyset: g.keyset, Filter: g.filter, Sort: g.sort, Projection: g.projection, Mask: g.mask,
+	}
+}
+
+func generate(g *generateOptions) bool {
+	sql := readFileWithStdin(g.input, g.sub)
 	statements := parser.Parse(sql)
 	if len(statements) == 0 {
-		return
+		return false
 	}
+	generator.MarkVersions(statements, g.versioned)
+	if (g.service || g.router) && !g.curd {
+		for _, statement := range statements {
+			if version := generator.VersionColumn(statement); version != nil {
+				fmt.Fprintf(os.Stderr, "the version of %s is checked by the functions of -curd, %s is not locked without -curd\n", statement.TableName.Name, version.ColumnName.Name)
+				version.Version = false
+			}
+		}
+	}
+	drift := false
 
-	if generateRouter {
+	if g.router {
 		{
-			p, f, o := fill(pkg, output, file, "router")
+			p, f, o := fill(g.pkg, g.output, g.file, "router")
 			filename := f + "_auto.go"
 			content := func() string {
-				if panicStyle {
-					return router.GeneratePanic(p, statements, banner)
+				if g.panicStyle {
+					return router.GeneratePanic(p, statements, g.routerOptions())
 				} else {
-					return router.Generate(p, statements, banner)
+					return router.Generate(p, statements, g.routerOptions())
 				}
 			}()
-			writeFileTryFormat(std, o, filename, content)
+			drift = writeOrCheck(g.check, g.std, o, filename, content) || drift
 		}
 		{
-			_, f, o := fill(pkg, output, file, "doc")
+			_, f, o := fill(g.pkg, g.output, g.file, "doc")
 			filename := f + "_auto.md"
-			content := router.GenerateDoc(statements, banner)
-			writeFileTryFormat(std, o, filename, content)
+			content := router.GenerateDoc(statements, g.banner, g.keyset, g.filter, g.sort, g.projection, g.mask, g.round, g.timezone)
+			drift = writeOrCheck(g.check, g.std, o, filename, content) || drift
 		}
 	}
 
-	if generateService {
-		p, f, o := fill(pkg, output, file, "service")
+	if g.service {
+		p, f, o := fill(g.pkg, g.output, g.file, "service")
 		filename := f + "_auto.go"
+		options := g.serviceOptions()
 		content := func() string {
-			if gorm {
-				return service.GenerateGorm(p, statements, banner)
+			if g.curdService {
+				if g.panicStyle {
+					return service.GenerateCurdPanic(p, statements, options)
+				}
+				return service.GenerateCurd(p, statements, options)
+			}
+			if g.gorm {
+				return service.GenerateGorm(p, statements, options)
 			} else {
-				if panicStyle {
-					return service.GeneratePanic(p, statements, banner)
+				if g.panicStyle {
+					return service.GeneratePanic(p, statements, options)
 				} else {
-					return service.Generate(p, statements, banner)
+					return service.Generate(p, statements, options)
 				}
 			}
 		}()
-		writeFileTryFormat(std, o, filename, content)
+		drift = writeOrCheck(g.check, g.std, o, filename, content) || drift
+		if g.cache {
+			filename := f + "_cache_auto.go"
+			drift = writeOrCheck(g.check, g.std, o, filename, service.GenerateCache(p, statements, options)) || drift
+			filename = f + "_cache_redis_auto.go"
+			drift = writeOrCheck(g.check, g.std, o, filename, service.GenerateRedisCache(p, g.banner)) || drift
+		}
+		if g.mock {
+			o := path.Join(path.Dir(o), "servicemock")
+			filename := f + "_mock_auto.go"
+			content := service.GenerateMock(path.Base(o), statements, options)
+			drift = writeOrCheck(g.check, g.std, o, filename, content) || drift
+		}
 	}
 
-	if m {
-		p, f, o := fill(pkg, output, file, "model")
+	if g.model {
+		p, f, o := fill(g.pkg, g.output, g.file, "model")
 		filename := f + "_auto.go"
-		content := model.Generate(p, statements, banner, gorm)
-		writeFileTryFormat(std, o, filename, content)
+		content := model.Generate(p, statements, g.banner, g.gorm, g.round)
+		drift = writeOrCheck(g.check, g.std, o, filename, content) || drift
 	}
 
-	if c {
-		p, f, o := fill(pkg, output, file, "model")
+	if g.curd {
+		p, f, o := fill(g.pkg, g.output, g.file, "model")
 		filename := f + "_curd_auto.go"
+		options := g.curdOptions()
 		content := func() string {
-			if panicStyle {
-				return curd.GeneratePanic(p, statements, banner, logic, asc, desc, round)
+			if g.repository {
+				return curd.GenerateRepository(p, statements, options)
+			}
+			if g.panicStyle {
+				return curd.GeneratePanic(p, statements, options)
 			} else {
-				return curd.Generate(p, statements, banner, logic, asc, desc, round)
+				return curd.Generate(p, statements, options)
 			}
 		}()
-		writeFileTryFormat(std, o, filename, content)
+		drift = writeOrCheck(g.check, g.std, o, filename, content) || drift
+		if g.repository {
+			drift = writeOrCheck(g.check, g.std, o, "repository_auto.go", curd.GenerateRepositoryRuntime(p, options, g.panicStyle)) || drift
+		}
+		if g.audit {
+			filename := f + "_audit_log_auto.sql"
+			drift = writeOrCheck(g.check, g.std, o, filename, curd.GenerateAuditLog(g.dialect, g.banner)) || drift
+		}
+		if g.test {
+			filename := f + "_curd_auto_test.go"
+			content := curd.GenerateTest(p, statements, options, g.panicStyle)
+			drift = writeOrCheck(g.check, g.std, o, filename, content) || drift
+		}
 	}
+	return drift
 }
 
 func fill(pkg string, output string, file string, defaultValue string) (string, string, string) {
@@ -247,6 +415,45 @@ func isExists(path string) (bool, error) {
 	return false, err
 }
 
+func writeOrCheck(check bool, std bool, output string, filename string, content string) bool {
+	if check {
+		return checkFileTryFormat(output, filename, content)
+	}
+	writeFileTryFormat(std, output, filename, content)
+	return false
+}
+
+func checkFileTryFormat(output string, filename string, content string) bool {
+	fullPath := path.Join(output, filename)
+	expected := []byte(content)
+	bts, err := gofmt.Source(fullPath, expected, false)
+	if err == nil && bts != nil {
+		expected = bts
+	}
+	actual, err := os.ReadFile(fullPath)
+	if err != nil && !os.IsNotExist(err) {
+		printError("read outputh file error", err)
+		return true
+	}
+	// the banner carries the generation date, keep the one on disk so it never counts as drift
+	if match := bannerDate.FindSubmatch(actual); match != nil {
+		expected = bannerDate.ReplaceAllFunc(expected, func(b []byte) []byte {
+			return bytes.Replace(b, bannerDate.FindSubmatch(b)[1], match[1], 1)
+		})
+	}
+	if bytes.Equal(actual, expected) {
+		return false
+	}
+	fmt.Fprintf(os.Stderr, "output file \"%s\" is out of date\n", fullPath)
+	data, err := gofmt.Diff(actual, expected, fullPath)
+	if err != nil {
+		printError("diff file error", err)
+		return true
+	}
+	os.Stdout.Write(data)
+	return true
+}
+
 func writeFileTryFormat(std bool, output string, filename string, content string) {
 	if std || output == "" || filename == "" {
 		fmt.Println(content)
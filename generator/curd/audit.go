@@ -0,0 +1,552 @@
+// Copyright 2010-2024 the original author or authors.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+// 	http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package curd
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/stella-go/stella/generator"
+	"github.com/stella-go/stella/generator/parser"
+	"github.com/stella-go/stella/version"
+)
+
+var auditLogDDL = map[string]string{
+	MySQL: "CREATE TABLE IF NOT EXISTS `audit_log` (" + `
+    ` + "`id`" + ` BIGINT NOT NULL AUTO_INCREMENT,
+    ` + "`table_name`" + ` VARCHAR(64) NOT NULL,
+    ` + "`primary_key`" + ` VARCHAR(255) NOT NULL,
+    ` + "`operation`" + ` VARCHAR(16) NOT NULL,
+    ` + "`changes`" + ` TEXT NOT NULL,
+    ` + "`actor`" + ` VARCHAR(128) NOT NULL DEFAULT '',
+    ` + "`created_at`" + ` DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+    PRIMARY KEY (` + "`id`" + `),
+    KEY ` + "`idx_audit_log_row` (`table_name`, `primary_key`)" + `
+);
+`,
+	Postgres: `CREATE TABLE IF NOT EXISTS "audit_log" (
+    "id" BIGSERIAL PRIMARY KEY,
+    "table_name" VARCHAR(64) NOT NULL,
+    "primary_key" VARCHAR(255) NOT NULL,
+    "operation" VARCHAR(16) NOT NULL,
+    "changes" TEXT NOT NULL,
+    "actor" VARCHAR(128) NOT NULL DEFAULT '',
+    "created_at" TIMESTAMP(6) NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS "idx_audit_log_row" ON "audit_log" ("table_name", "primary_key");
+`,
+	SQLite: `CREATE TABLE IF NOT EXISTS "audit_log" (
+    "id" INTEGER PRIMARY KEY AUTOINCREMENT,
+    "table_name" VARCHAR(64) NOT NULL,
+    "primary_key" VARCHAR(255) NOT NULL,
+    "operation" VARCHAR(16) NOT NULL,
+    "changes" TEXT NOT NULL,
+    "actor" VARCHAR(128) NOT NULL DEFAULT '',
+    "created_at" DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS "idx_audit_log_row" ON "audit_log" ("table_name", "primary_key");
+`,
+	SQLServer: `CREATE TABLE [audit_log] (
+    [id] BIGINT IDENTITY(1,1) PRIMARY KEY,
+    [table_name] NVARCHAR(64) NOT NULL,
+    [primary_key] NVARCHAR(255) NOT NULL,
+    [operation] NVARCHAR(16) NOT NULL,
+    [changes] NVARCHAR(MAX) NOT NULL,
+    [actor] NVARCHAR(128) NOT NULL DEFAULT '',
+    [created_at] DATETIME2(6) NOT NULL DEFAULT SYSDATETIME()
+);
+
+CREATE INDEX [idx_audit_log_row] ON [audit_log] ([table_name], [primary_key]);
+`,
+}
+
+// GenerateAuditLog generates the DDL of the audit_log table the audited writes of -audit insert into.
+func GenerateAuditLog(dialect string, banner bool) string {
+	bannerS := ""
+	if banner {
+		bannerS = fmt.Sprintf("-- Auto Generate by github.com/stella-go/stella %s on %s.\n\n", version.VERSION, time.Now().Format("2006/01/02"))
+	}
+	ddl, ok := auditLogDDL[dialect]
+	if !ok {
+		ddl = auditLogDDL[MySQL]
+	}
+	return bannerS + ddl
+}
+
+// auditLines record the changes of the audited writes, %[1]s runs them on the prepared statements of -prepare,
+// %[2]s is the insert into audit_log and %[3]s the statement passed to ExecContext.
+const auditLines = `
+
+// AuditChange is the old and the new value of a column in the changes of an audit_log row.
+type AuditChange struct {
+    Old json.RawMessage ` + "`json:\"old\"`" + `
+    New json.RawMessage ` + "`json:\"new\"`" + `
+}
+
+type auditActorKey struct{}
+
+// WithAuditActor returns a copy of ctx whose audited writes are recorded as made by actor.
+func WithAuditActor(ctx context.Context, actor string) context.Context {
+    return context.WithValue(ctx, auditActorKey{}, actor)
+}
+
+func auditActor(ctx context.Context) string {
+    actor, _ := ctx.Value(auditActorKey{}).(string)
+    return actor
+}
+
+// audited runs fn in a transaction begun on db, or on db itself when it is already a transaction.
+func audited(ctx context.Context, db DataSource, fn func(tx DataSource) error) error {%[1]s
+    beginner, ok := db.(interface {
+        BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error)
+    })
+    if !ok {
+        return fn(db)
+    }
+    tx, err := beginner.BeginTx(ctx, nil)
+    if err != nil {
+        return t.Error(err)
+    }
+    defer func() {
+        if r := recover(); r != nil {
+            tx.Rollback()
+            panic(r)
+        }
+    }()
+    err = fn(tx)
+    if err != nil {
+        tx.Rollback()
+        return err
+    }
+    err = tx.Commit()
+    if err != nil {
+        return t.Error(err)
+    }
+    return nil
+}
+
+// auditLog inserts the audit_log row of a write, the changes are the columns whose old and new values differ.
+func auditLog(ctx context.Context, db DataSource, table string, key []interface{}, operation string, before map[string]interface{}, after map[string]interface{}) error {
+    changes := make(map[string]AuditChange)
+    for _, values := range []map[string]interface{}{before, after} {
+        for column := range values {
+            oldValue, err := json.Marshal(before[column])
+            if err != nil {
+                return t.Error(err)
+            }
+            newValue, err := json.Marshal(after[column])
+            if err != nil {
+                return t.Error(err)
+            }
+            if string(oldValue) != string(newValue) {
+                changes[column] = AuditChange{Old: oldValue, New: newValue}
+            }
+        }
+    }
+    bts, err := json.Marshal(changes)
+    if err != nil {
+        return t.Error(err)
+    }
+    keys := make([]string, 0, len(key))
+    for _, k := range key {
+        keys = append(keys, fmt.Sprint(k))
+    }
+    SQL := "%[2]s"
+    _, err = db.ExecContext(ctx, %[3]s, table, strings.Join(keys, ","), operation, string(bts), auditActor(ctx))
+    if err != nil {
+        return t.Error(err)
+    }
+    return nil
+}`
+
+const auditPanicLines = `
+
+// AuditChange is the old and the new value of a column in the changes of an audit_log row.
+type AuditChange struct {
+    Old json.RawMessage ` + "`json:\"old\"`" + `
+    New json.RawMessage ` + "`json:\"new\"`" + `
+}
+
+type auditActorKey struct{}
+
+// WithAuditActor returns a copy of ctx whose audited writes are recorded as made by actor.
+func WithAuditActor(ctx context.Context, actor string) context.Context {
+    return context.WithValue(ctx, auditActorKey{}, actor)
+}
+
+func auditActor(ctx context.Context) string {
+    actor, _ := ctx.Value(auditActorKey{}).(string)
+    return actor
+}
+
+// audited runs fn in a transaction begun on db, or on db itself when it is already a transaction.
+func audited(ctx context.Context, db DataSource, fn func(tx DataSource)) {%[1]s
+    beginner, ok := db.(interface {
+        BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error)
+    })
+    if !ok {
+        fn(db)
+        return
+    }
+    tx, err := beginner.BeginTx(ctx, nil)
+    t.AssertErrorNil(err)
+    defer func() {
+        if r := recover(); r != nil {
+            tx.Rollback()
+            panic(r)
+        }
+    }()
+    fn(tx)
+    t.AssertErrorNil(tx.Commit())
+}
+
+// auditLog inserts the audit_log row of a write, the changes are the columns whose old and new values differ.
+func auditLog(ctx context.Context, db DataSource, table string, key []interface{}, operation string, before map[string]interface{}, after map[string]interface{}) {
+    changes := make(map[string]AuditChange)
+    for _, values := range []map[string]interface{}{before, after} {
+        for column := range values {
+            oldValue, err := json.Marshal(before[column])
+            t.AssertErrorNil(err)
+            newValue, err := json.Marshal(after[column])
+            t.AssertErrorNil(err)
+            if string(oldValue) != string(newValue) {
+                changes[column] = AuditChange{Old: oldValue, New: newValue}
+            }
+        }
+    }
+    bts, err := json.Marshal(changes)
+    t.AssertErrorNil(err)
+    keys := make([]string, 0, len(key))
+    for _, k := range key {
+        keys = append(keys, fmt.Sprint(k))
+    }
+    SQL := "%[2]s"
+    _, err = db.ExecContext(ctx, %[3]s, table, strings.Join(keys, ","), operation, string(bts), auditActor(ctx))
+    t.AssertErrorNil(err)
+}`
+
+// auditQueriesLines run the audited writes on the prepared statements in a transaction of Queries.
+const auditQueriesLines = `
+    if q, ok := db.(*Queries); ok && q.tx == nil {
+        return q.WithTx(ctx, nil, fn)
+    }`
+
+const auditQueriesPanicLines = `
+    if q, ok := db.(*Queries); ok && q.tx == nil {
+        q.WithTx(ctx, nil, fn)
+        return
+    }`
+
+// auditLines adds the runtime of the audited writes, -audit goes with -ctx.
+func (g *gen) auditLines(panicStyle bool) string {
+	lines, queriesLines := auditLines, auditQueriesLines
+	if panicStyle {
+		lines, queriesLines = auditPanicLines, auditQueriesPanicLines
+	}
+	if !g.Prepare {
+		queriesLines = ""
+	}
+	columns := make([]string, 0)
+	for _, column := range []string{"table_name", "primary_key", "operation", "changes", "actor"} {
+		columns = append(columns, g.ident(column))
+	}
+	SQL := "insert into " + g.ident("audit_log") + " (" + strings.Join(columns, ", ") + ") values (?, ?, ?, ?, ?)"
+	return fmt.Sprintf(lines, queriesLines, SQL, g.bind("SQL"))
+}
+
+// audit declares every Create, Upsert, Update, Delete and UnDelete by a key the templates named unexported with -audit,
+// each calls the unexported function in a transaction which loads the old row, writes and inserts the audit_log row.
+// The bulk writes can not record the rows they change one by one and are not generated with -audit.
+func audit(statement *parser.Statement, g *gen, logic *generator.Logic, panicStyle bool) (string, []string) {
+	modelName := generator.FirstUpperCamelCase(statement.TableName.Name)
+	columns := make([]string, 0)
+	for _, col := range auditedColumns(statement) {
+		columns = append(columns, fmt.Sprintf("\"%s\": s.%s", col.ColumnName.Name, generator.FirstUpperCamelCase(col.ColumnName.Name)))
+	}
+	keys := getPrimaryKeys(statement)
+	if len(keys) == 0 {
+		if pairs := generator.UniqKeyPairs(statement); len(pairs) > 0 {
+			keys = pairs[0]
+		}
+	}
+	funcLines := fmt.Sprintf(`func auditColumns%s(s *%s) map[string]interface{} {
+    if s == nil {
+        return nil
+    }
+    return map[string]interface{}{%s}
+}
+`, modelName, modelName, strings.Join(columns, ", "))
+	params := "ctx context.Context, db DataSource, s *" + modelName
+	funcLines += auditFunc("Create"+modelName, params, "", auditCreate(statement, keys, auditCall("Create"+modelName, "s"), panicStyle), panicStyle)
+	imports := make([]string, 0)
+	for _, pair := range generator.UniqKeyPairs(statement) {
+		key := keysName(pair)
+		query := "Query" + modelName + "By" + key
+		if logic != nil {
+			query += "WithDeleted"
+		}
+		if g.upsertable(pair) {
+			name := "Upsert" + modelName + "By" + key
+			results := "(UpsertResult, error)"
+			if panicStyle {
+				results = "UpsertResult"
+			}
+			body, imported := auditUpsert(statement, g, keys, pair, auditCall(name, "s, update..."), query, panicStyle)
+			imports = append(imports, imported...)
+			funcLines += auditFunc(name, params+", update ...string", results, body, panicStyle)
+		}
+		names := []string{"Update" + modelName + "By" + key, "Delete" + modelName + "By" + key}
+		if logic != nil && logic.Undeleted != "" {
+			names = append(names, "UnDelete"+modelName+"By"+key)
+		}
+		for _, name := range names {
+			funcLines += auditFunc(name, params, "", auditWrite(statement, keys, name, auditCall(name, "s"), query, panicStyle), panicStyle)
+		}
+		if g.Mask {
+			name := "Update" + modelName + "By" + key + "Fields"
+			funcLines += auditFunc(name, params+", fields []"+modelName+"Column", "", auditWrite(statement, keys, name, auditCall(name, "s, fields"), query, panicStyle), panicStyle)
+		}
+	}
+	return funcLines, imports
+}
+
+// auditedColumns returns the columns whose values are recorded in the changes, every column but the ones commented with @sensitive.
+func auditedColumns(statement *parser.Statement) []*parser.ColumnDefinition {
+	columns := make([]*parser.ColumnDefinition, 0)
+	for _, col := range statement.Columns {
+		if col.Comment != nil && strings.Contains(col.Comment.Comment, "@sensitive") {
+			continue
+		}
+		columns = append(columns, col)
+	}
+	return columns
+}
+
+func keysName(keys []*parser.ColumnDefinition) string {
+	fields := make([]string, 0)
+	for _, col := range keys {
+		fields = append(fields, generator.FirstUpperCamelCase(col.ColumnName.Name))
+	}
+	return strings.Join(fields, "")
+}
+
+// auditCall is the call of the unexported variant of name on the transaction with args after ctx and tx.
+func auditCall(name string, args string) string {
+	return fmt.Sprintf("%s%s(ctx, tx, %s)", strings.ToLower(name[:1]), name[1:], args)
+}
+
+// auditFunc declares name running body, which calls its unexported variant, the results are the count of the writes by default.
+func auditFunc(name string, params string, results string, body string, panicStyle bool) string {
+	if results == "" {
+		results = "(int64, error)"
+		if panicStyle {
+			results = "int64"
+		}
+	}
+	return fmt.Sprintf("func %s(%s) %s {\n%s}\n", name, params, results, body)
+}
+
+// auditCreate records the values of the created row, the key of an auto increment column is the returned id.
+func auditCreate(statement *parser.Statement, keys []*parser.ColumnDefinition, call string, panicStyle bool) string {
+	modelName := generator.FirstUpperCamelCase(statement.TableName.Name)
+	keyArgs := make([]string, 0)
+	values := ""
+	for _, col := range keys {
+		if col.AutoIncrement {
+			keyArgs = append(keyArgs, "id")
+			for _, c := range auditedColumns(statement) {
+				if c == col {
+					values = fmt.Sprintf("\n        values[\"%s\"] = id", col.ColumnName.Name)
+				}
+			}
+			continue
+		}
+		keyArgs = append(keyArgs, "s."+generator.FirstUpperCamelCase(col.ColumnName.Name))
+	}
+	if panicStyle {
+		return fmt.Sprintf(`    id := int64(0)
+    audited(ctx, db, func(tx DataSource) {
+        id = %s
+        values := auditColumns%s(s)%s
+        auditLog(ctx, tx, "%s", []interface{}{%s}, "create", nil, values)
+    })
+    return id
+`, call, modelName, values, statement.TableName.Name, strings.Join(keyArgs, ", "))
+	}
+	return fmt.Sprintf(`    id := int64(0)
+    err := audited(ctx, db, func(tx DataSource) error {
+        var err error
+        id, err = %s
+        if err != nil {
+            return err
+        }
+        values := auditColumns%s(s)%s
+        return auditLog(ctx, tx, "%s", []interface{}{%s}, "create", nil, values)
+    })
+    return id, err
+`, call, modelName, values, statement.TableName.Name, strings.Join(keyArgs, ", "))
+}
+
+// auditWrite records the old row loaded by query before the write and, for an update or an undelete, the row loaded after it.
+func auditWrite(statement *parser.Statement, keys []*parser.ColumnDefinition, name string, call string, query string, panicStyle bool) string {
+	modelName := generator.FirstUpperCamelCase(statement.TableName.Name)
+	keyArgs := make([]string, 0)
+	for _, col := range keys {
+		keyArgs = append(keyArgs, "old."+generator.FirstUpperCamelCase(col.ColumnName.Name))
+	}
+	operation, after := "update", fmt.Sprintf("auditColumns%s(row)", modelName)
+	switch {
+	case strings.HasPrefix(name, "Delete"):
+		operation, after = "delete", "nil"
+	case strings.HasPrefix(name, "UnDelete"):
+		operation = "undelete"
+	}
+	if panicStyle {
+		row := ""
+		if operation != "delete" {
+			row = fmt.Sprintf("\n        row := %s(ctx, tx, s)", query)
+		}
+		return fmt.Sprintf(`    count := int64(0)
+    audited(ctx, db, func(tx DataSource) {
+        old := %s(ctx, tx, s)
+        count = %s
+        if count == 0 || old == nil {
+            return
+        }%s
+        auditLog(ctx, tx, "%s", []interface{}{%s}, "%s", auditColumns%s(old), %s)
+    })
+    return count
+`, query, call, row, statement.TableName.Name, strings.Join(keyArgs, ", "), operation, modelName, after)
+	}
+	row := ""
+	if operation != "delete" {
+		row = fmt.Sprintf(`
+        row, err := %s(ctx, tx, s)
+        if err != nil {
+            return err
+        }`, query)
+	}
+	return fmt.Sprintf(`    count := int64(0)
+    err := audited(ctx, db, func(tx DataSource) error {
+        old, err := %s(ctx, tx, s)
+        if err != nil {
+            return err
+        }
+        count, err = %s
+        if err != nil || count == 0 || old == nil {
+            return err
+        }%s
+        return auditLog(ctx, tx, "%s", []interface{}{%s}, "%s", auditColumns%s(old), %s)
+    })
+    return count, err
+`, query, call, row, statement.TableName.Name, strings.Join(keyArgs, ", "), operation, modelName, after)
+}
+
+// auditUpsert records the row loaded after the upsert as a create when query found no old row and as an update otherwise,
+// an upsert which changed nothing records nothing. A row inserted without its auto increment key is loaded by the generated id.
+func auditUpsert(statement *parser.Statement, g *gen, keys []*parser.ColumnDefinition, pair []*parser.ColumnDefinition, call string, query string, panicStyle bool) (string, []string) {
+	modelName := generator.FirstUpperCamelCase(statement.TableName.Name)
+	keyArgs := make([]string, 0)
+	for _, col := range keys {
+		keyArgs = append(keyArgs, "row."+generator.FirstUpperCamelCase(col.ColumnName.Name))
+	}
+	generated, imports := auditGeneratedKey(g, pair, panicStyle)
+	if panicStyle {
+		return fmt.Sprintf(`    result := UpsertUnchanged
+    audited(ctx, db, func(tx DataSource) {
+        old := %s(ctx, tx, s)
+        result = %s
+        if result == UpsertUnchanged {
+            return
+        }
+%s        row := %s(ctx, tx, key)
+        if row == nil {
+            return
+        }
+        operation := "update"
+        if old == nil {
+            operation = "create"
+        }
+        auditLog(ctx, tx, "%s", []interface{}{%s}, operation, auditColumns%s(old), auditColumns%s(row))
+    })
+    return result
+`, query, call, generated, query, statement.TableName.Name, strings.Join(keyArgs, ", "), modelName, modelName), imports
+	}
+	return fmt.Sprintf(`    result := UpsertUnchanged
+    err := audited(ctx, db, func(tx DataSource) error {
+        old, err := %s(ctx, tx, s)
+        if err != nil {
+            return err
+        }
+        result, err = %s
+        if err != nil || result == UpsertUnchanged {
+            return err
+        }
+%s        row, err := %s(ctx, tx, key)
+        if err != nil || row == nil {
+            return err
+        }
+        operation := "update"
+        if old == nil {
+            operation = "create"
+        }
+        return auditLog(ctx, tx, "%s", []interface{}{%s}, operation, auditColumns%s(old), auditColumns%s(row))
+    })
+    return result, err
+`, query, call, generated, query, statement.TableName.Name, strings.Join(keyArgs, ", "), modelName, modelName), imports
+}
+
+// auditGeneratedKey declares the key the row is loaded by after the upsert, s itself or, when the upsert inserted without
+// the auto increment column of pair, s with the id the connection of the transaction generated last.
+func auditGeneratedKey(g *gen, pair []*parser.ColumnDefinition, panicStyle bool) (string, []string) {
+	var auto *parser.ColumnDefinition
+	for _, col := range pair {
+		if col.AutoIncrement {
+			auto = col
+		}
+	}
+	if auto == nil {
+		return "        key := s\n", nil
+	}
+	fieldName := generator.FirstUpperCamelCase(auto.ColumnName.Name)
+	typ := "n.Int"
+	if auto.Type == "BIGINT" {
+		typ = "n.Int64"
+	}
+	SQL := "select last_insert_id()"
+	if g.dialect() == SQLite {
+		SQL = "select last_insert_rowid()"
+	}
+	scan := `            err = tx.QueryRowContext(ctx, "` + SQL + `").Scan(&id)
+            if err != nil {
+                return t.Error(err)
+            }`
+	if panicStyle {
+		scan = `            t.AssertErrorNil(tx.QueryRowContext(ctx, "` + SQL + `").Scan(&id))`
+	}
+	return fmt.Sprintf(`        key := s
+        if s.%[1]s == nil && result == UpsertInserted {
+            id := int64(0)
+%[2]s
+            generated := *s
+            v := %[3]s(id)
+            generated.%[1]s = &v
+            key = &generated
+        }
+`, fieldName, scan, typ), []string{"github.com/stella-go/siu/t/n"}
+}
@@ -25,59 +25,215 @@ import (
 	"github.com/stella-go/stella/version"
 )
 
-var (
-	unDeleteMap = map[interface{}]interface{}{
-		`\"0\"`: `\"1\"`,
-		`\"1\"`: `\"0\"`,
-		`1`:     `0`,
-		`0`:     `1`,
-	}
-)
-
-func Generate(pkg string, statements []*parser.Statement, banner bool, logic string, asc string, desc string, round string) string {
+func Generate(pkg string, statements []*parser.Statement, o *Options) string {
 	importsMap := make(map[string]common.Void)
 	importsMap["database/sql"] = common.Null
 	importsMap["fmt"] = common.Null
 	importsMap["strings"] = common.Null
 	importsMap["github.com/stella-go/siu/t"] = common.Null
 	functions := make([]string, 0)
-	switch round {
-	case "s":
-		round = "time.Second"
-	case "ms", "milli":
-		round = "time.Millisecond"
-	case "μs", "us", "micro":
-		round = "time.Microsecond"
-	default:
-		round = ""
-	}
-	if round != "" {
-		importsMap["time"] = common.Null
+	g := newGen(o)
+	if o.Ctx || o.Tx || o.Prepare || o.Audit {
+		importsMap["context"] = common.Null
+	}
+	if o.Audit {
+		importsMap["encoding/json"] = common.Null
+	}
+	for _, i := range dialectImports[o.Dialect] {
+		importsMap[i] = common.Null
+	}
+	if o.Keyset {
+		importsMap["bytes"] = common.Null
+		importsMap["encoding/base64"] = common.Null
+		importsMap["encoding/json"] = common.Null
+	}
+	versioned := false
+	for _, statement := range statements {
+		if generator.VersionColumn(statement) != nil {
+			versioned = true
+		}
+	}
+	if versioned {
+		importsMap["errors"] = common.Null
 	}
 	for _, statement := range statements {
 		functions = append(functions, "// ==================== "+generator.FirstUpperCamelCase(statement.TableName.Name)+" ====================")
-		function, imports := c(statement, round)
+		policy := generator.ParseLogic(statement, o.Logic)
+		function, imports := c(statement, g)
+		functions = append(functions, function)
+		for _, i := range imports {
+			importsMap[i] = common.Null
+		}
+
+		if !o.Audit {
+			function, imports = cm(statement, g)
+			functions = append(functions, function)
+			for _, i := range imports {
+				importsMap[i] = common.Null
+			}
+		}
+
+		function, imports = ups(statement, g)
 		functions = append(functions, function)
 		for _, i := range imports {
 			importsMap[i] = common.Null
 		}
 
-		function, imports = u(statement, round)
+		function, imports = u(statement, g)
 		functions = append(functions, function)
 		for _, i := range imports {
 			importsMap[i] = common.Null
 		}
 
-		function, imports = r(statement, asc, desc, round)
+		if o.Mask {
+			if !o.Projection {
+				functions = append(functions, columnsStruct(statement, g))
+			}
+			function, imports = uf(statement, g)
+			functions = append(functions, function)
+			for _, i := range imports {
+				importsMap[i] = common.Null
+			}
+		}
+
+		if !o.Audit {
+			function, imports = um(statement, g, policy)
+			functions = append(functions, function)
+			for _, i := range imports {
+				importsMap[i] = common.Null
+			}
+		}
+
+		if o.Sort {
+			functions = append(functions, sortableMap(statement, g))
+		}
+		function, imports = r(statement, g, policy, "")
+		functions = append(functions, function)
+		for _, i := range imports {
+			importsMap[i] = common.Null
+		}
+		if policy != nil {
+			function, imports = r(statement, g, nil, "WithDeleted")
+			functions = append(functions, function)
+			for _, i := range imports {
+				importsMap[i] = common.Null
+			}
+		}
+		if o.Projection {
+			functions = append(functions, columnsStruct(statement, g))
+			function, imports = rp(statement, g, policy, "")
+			functions = append(functions, function)
+			for _, i := range imports {
+				importsMap[i] = common.Null
+			}
+			if policy != nil {
+				function, imports = rp(statement, g, nil, "WithDeleted")
+				functions = append(functions, function)
+				for _, i := range imports {
+					importsMap[i] = common.Null
+				}
+			}
+		}
+		if o.Keyset {
+			function, imports = ra(statement, g, policy, "")
+			functions = append(functions, function)
+			for _, i := range imports {
+				importsMap[i] = common.Null
+			}
+			if policy != nil {
+				function, imports = ra(statement, g, nil, "WithDeleted")
+				functions = append(functions, function)
+				for _, i := range imports {
+					importsMap[i] = common.Null
+				}
+			}
+		}
+		if o.Filter {
+			function, imports = filterStruct(statement, g)
+			functions = append(functions, function)
+			for _, i := range imports {
+				importsMap[i] = common.Null
+			}
+			function, imports = rf(statement, g, policy, "")
+			functions = append(functions, function)
+			for _, i := range imports {
+				importsMap[i] = common.Null
+			}
+			if policy != nil {
+				function, imports = rf(statement, g, nil, "WithDeleted")
+				functions = append(functions, function)
+				for _, i := range imports {
+					importsMap[i] = common.Null
+				}
+			}
+		}
+		function, imports = cnt(statement, g, policy, "")
 		functions = append(functions, function)
 		for _, i := range imports {
 			importsMap[i] = common.Null
 		}
-		function, imports = d(statement, logic, round)
+		if policy != nil {
+			function, imports = cnt(statement, g, nil, "WithDeleted")
+			functions = append(functions, function)
+			for _, i := range imports {
+				importsMap[i] = common.Null
+			}
+		}
+		if o.Aggregate {
+			function, imports = agg(statement, g, policy, "")
+			functions = append(functions, function)
+			for _, i := range imports {
+				importsMap[i] = common.Null
+			}
+			if policy != nil {
+				function, imports = agg(statement, g, nil, "WithDeleted")
+				functions = append(functions, function)
+				for _, i := range imports {
+					importsMap[i] = common.Null
+				}
+			}
+		}
+		if o.Stream {
+			function, imports = each(statement, g, policy, "")
+			functions = append(functions, function)
+			for _, i := range imports {
+				importsMap[i] = common.Null
+			}
+			if policy != nil {
+				function, imports = each(statement, g, nil, "WithDeleted")
+				functions = append(functions, function)
+				for _, i := range imports {
+					importsMap[i] = common.Null
+				}
+			}
+		}
+		function, imports = d(statement, g, policy)
 		functions = append(functions, function)
 		for _, i := range imports {
 			importsMap[i] = common.Null
 		}
+		if o.Audit {
+			function, imports = audit(statement, g, policy, false)
+			functions = append(functions, function)
+			for _, i := range imports {
+				importsMap[i] = common.Null
+			}
+		} else {
+			function, imports = dm(statement, g, policy)
+			functions = append(functions, function)
+			for _, i := range imports {
+				importsMap[i] = common.Null
+			}
+		}
+	}
+
+	if strings.Contains(strings.Join(functions, "\n"), "time.") {
+		importsMap["time"] = common.Null
+	}
+	helpers := timeHelpers(strings.Join(functions, "\n"))
+	if helpers != "" {
+		importsMap["time"] = common.Null
+		importsMap["github.com/stella-go/siu/t/n"] = common.Null
 	}
 
 	importsLines := make([]string, 0)
@@ -93,16 +249,128 @@ func Generate(pkg string, statements []*parser.Statement, banner bool, logic str
     QueryRow(query string, args ...interface{}) *sql.Row
     Query(query string, args ...interface{}) (*sql.Rows, error)
 }`
+	if o.Ctx {
+		datasourceLines = `type DataSource interface {
+    ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+    QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+    QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+}`
+	}
+	datasourceLines += `
+
+type UpsertResult int
+
+const (
+    UpsertUnchanged UpsertResult = iota
+    UpsertInserted
+    UpsertUpdated
+)`
+	if o.Tx {
+		datasourceLines += `
+
+func WithTx(ctx context.Context, db *sql.DB, opts *sql.TxOptions, fn func(tx DataSource) error) error {
+    return WithTxRetry(ctx, db, opts, 0, fn)
+}
+
+func WithTxRetry(ctx context.Context, db *sql.DB, opts *sql.TxOptions, retries int, fn func(tx DataSource) error) error {
+    for i := 0; ; i++ {
+        err := withTx(ctx, db, opts, fn)
+        if err == nil || i >= retries || !isDeadlock(err) {
+            return err
+        }
+    }
+}
+
+func withTx(ctx context.Context, db *sql.DB, opts *sql.TxOptions, fn func(tx DataSource) error) error {
+    tx, err := db.BeginTx(ctx, opts)
+    if err != nil {
+        return t.Error(err)
+    }
+    defer func() {
+        if r := recover(); r != nil {
+            tx.Rollback()
+            panic(r)
+        }
+    }()
+    err = fn(tx)
+    if err != nil {
+        tx.Rollback()
+        return err
+    }
+    err = tx.Commit()
+    if err != nil {
+        return t.Error(err)
+    }
+    return nil
+}
+
+func isDeadlock(err error) bool {
+    message := err.Error()
+    return ` + g.deadlock() + `
+}`
+	}
+	if o.Keyset {
+		datasourceLines += `
+
+func encodeCursor(values ...interface{}) (string, error) {
+    bts, err := json.Marshal(values)
+    if err != nil {
+        return "", err
+    }
+    return base64.RawURLEncoding.EncodeToString(bts), nil
+}
+
+func decodeCursor(cursor string, n int) ([]interface{}, error) {
+    bts, err := base64.RawURLEncoding.DecodeString(cursor)
+    if err != nil {
+        return nil, &ArgumentError{Message: "invalid cursor"}
+    }
+    values := make([]interface{}, 0)
+    decoder := json.NewDecoder(bytes.NewReader(bts))
+    decoder.UseNumber()
+    err = decoder.Decode(&values)
+    if err != nil || len(values) != n {
+        return nil, &ArgumentError{Message: "invalid cursor"}
+    }
+    return values, nil
+}`
+	}
+	if versioned {
+		datasourceLines += `
+
+var ErrStaleObject = errors.New("stale object")`
+	}
+	if o.Sort || o.Filter || o.Keyset || o.Projection || o.Mask {
+		datasourceLines += argumentLines
+	}
+	if o.Filter {
+		datasourceLines += g.filterLines()
+	}
+	if o.Sort {
+		datasourceLines += g.orderLines()
+	}
+	if o.Projection || o.Mask {
+		datasourceLines += projectionLines
+	}
+	datasourceLines += helpers
+	datasourceLines += g.bindLines()
 	bannerS := ""
-	if banner {
+	if o.Banner {
 		bannerS = fmt.Sprintf("\n/**\n * Auto Generate by github.com/stella-go/stella %s on %s.\n */\n", version.VERSION, time.Now().Format("2006/01/02"))
 
 	}
+	if o.Prepare {
+		datasourceLines += g.queriesLines(false)
+	}
+	if o.Audit {
+		datasourceLines += g.auditLines(false)
+	}
 	return fmt.Sprintf("package %s\n%s\nimport (\n%s\n)\n\n%s\n\n%s", pkg, bannerS, strings.Join(importsLines, "\n"), datasourceLines, strings.Join(functions, "\n"))
 }
 
-func c(statement *parser.Statement, round string) (string, []string) {
+func c(statement *parser.Statement, g *gen) (string, []string) {
 	modelName := generator.FirstUpperCamelCase(statement.TableName.Name)
+	zero, imports := versionZero(statement, "    ")
 	columns := make([]string, 0)
 	values := make([]string, 0)
 	args := make([]string, 0)
@@ -111,12 +379,10 @@ func c(statement *parser.Statement, round string) (string, []string) {
 			continue
 		}
 		fieldName := generator.FirstUpperCamelCase(col.ColumnName.Name)
-		columns = append(columns, "\"`"+col.ColumnName.Name+"`\"")
+		columns = append(columns, "\""+g.ident(col.ColumnName.Name)+"\"")
 		values = append(values, "\"?\"")
 		arg := "s." + fieldName
-		if (col.Type == "DATE" || col.Type == "DATETIME" || col.Type == "TIMESTAMP") && round != "" {
-			arg = arg + ".Round(" + round + ")"
-		}
+		arg = timeArg(col, arg, g.times)
 		args = append(args, arg)
 	}
 	insert := fmt.Sprintf(`columns := []string{%s}
@@ -130,27 +396,19 @@ func c(statement *parser.Statement, round string) (string, []string) {
 		if col.DefaultValue != nil {
 			fieldName := generator.FirstUpperCamelCase(col.ColumnName.Name)
 			arg := "s." + fieldName
-			if (col.Type == "DATE" || col.Type == "DATETIME" || col.Type == "TIMESTAMP") && round != "" {
-				arg = arg + ".Round(" + round + ")"
-			}
+			arg = timeArg(col, arg, g.times)
 			insert += fmt.Sprintf(`    if s.%s != nil {
         columns = append(columns, "%s")
         values = append(values, "?")
         args = append(args, %s)
     }
-`, fieldName, "`"+col.ColumnName.Name+"`", arg)
+`, fieldName, g.ident(col.ColumnName.Name), arg)
 		}
 	}
 	insert += `    SQL = fmt.Sprintf(SQL, strings.Join(columns, ", "), strings.Join(values, ", "))`
 
-	SQL := fmt.Sprintf("insert into `%s` (%%s) values (%%s)", statement.TableName.Name)
-	funcLines := fmt.Sprintf(`func Create%s(db DataSource, s *%s) (int64, error) {
-    if s == nil {
-        return 0, t.Error(fmt.Errorf("pointer can not be nil"))
-    }
-    SQL := "%s"
-    %s
-    ret, err := db.Exec(SQL, args...)
+	SQL := fmt.Sprintf("insert into "+g.ident("%s")+" (%%s) values (%%s)", statement.TableName.Name)
+	exec := `    ret, err := ` + g.exec("SQL") + `, args...)
     if err != nil {
         return 0, t.Error(err)
     }
@@ -159,195 +417,606 @@ func c(statement *parser.Statement, round string) (string, []string) {
         return 0, t.Error(err)
     }
     return ret.LastInsertId()
-}
-`, modelName, modelName, SQL, insert)
-	return funcLines, nil
-}
-
-func u(statement *parser.Statement, round string) (string, []string) {
-	modelName := generator.FirstUpperCamelCase(statement.TableName.Name)
-	funcLines := ""
-	uniqKeyPairs := getUniqKeyPairs(statement)
-	for _, keys := range uniqKeyPairs {
-		args := make([]string, 0)
-		set := `set := ""
-    args := make([]interface{}, 0)
-    `
+}`
+	if g.Dialect == Postgres || g.Dialect == SQLServer {
+		exec = `    _, err := ` + g.exec("SQL") + `, args...)
+    if err != nil {
+        return 0, t.Error(err)
+    }
+    return 0, nil
+}`
+		var id *parser.ColumnDefinition
 		for _, col := range statement.Columns {
-			if col.AutoIncrement || col.CurrentTimestamp {
-				continue
-			}
-			if contains(keys, col) {
-				continue
-			}
-			fieldName := generator.FirstUpperCamelCase(col.ColumnName.Name)
-			arg := "s." + fieldName
-			if (col.Type == "DATE" || col.Type == "DATETIME" || col.Type == "TIMESTAMP") && round != "" {
-				arg = arg + ".Round(" + round + ")"
+			if col.AutoIncrement {
+				id = col
 			}
-			set += fmt.Sprintf(`if %s != nil {
-        set += ", `+"`%s`"+` = ? "
-        args = append(args, %s)
-    }
-    `, "s."+fieldName, col.ColumnName, arg)
 		}
-		set += `set = strings.TrimLeft(set, ",")
-    set = strings.TrimSpace(set)
-    if set == "" {
-        return 0, t.Error(fmt.Errorf("all field is nil"))
+		if id != nil {
+			exec = `    id := int64(0)
+    err := ` + g.queryRow("SQL") + `, args...).Scan(&id)
+    if err != nil {
+        return 0, t.Error(err)
     }
-    SQL = fmt.Sprintf(SQL, set)`
-		fields := make([]string, 0)
-		conditions := make([]string, 0)
-		for _, col := range keys {
-			conditions = append(conditions, "`"+col.ColumnName.Name+"` = ?")
-			arg := "s." + generator.FirstUpperCamelCase(col.ColumnName.Name)
-			if (col.Type == "DATE" || col.Type == "DATETIME" || col.Type == "TIMESTAMP") && round != "" {
-				arg = arg + ".Round(" + round + ")"
+    return id, nil
+}`
+			if g.Dialect == Postgres {
+				SQL = fmt.Sprintf("insert into "+g.ident("%s")+" (%%s) values (%%s) returning "+g.ident("%s"), statement.TableName.Name, id.ColumnName.Name)
+			} else {
+				SQL = fmt.Sprintf("insert into "+g.ident("%s")+" (%%s) output inserted."+g.ident("%s")+" values (%%s)", statement.TableName.Name, id.ColumnName.Name)
 			}
-			args = append(args, arg)
-			fields = append(fields, generator.FirstUpperCamelCase(col.ColumnName.Name))
 		}
-		SQL := fmt.Sprintf("update `%s` set %%s where %s", statement.TableName.Name, strings.Join(conditions, " and "))
-		funcLines += fmt.Sprintf(`func Update%sBy%s(db DataSource, s *%s) (int64, error) {
+	}
+	funcLines := fmt.Sprintf(`func `+g.audited("Create")+`%s(`+g.db()+`, s *%s) (int64, error) {
     if s == nil {
         return 0, t.Error(fmt.Errorf("pointer can not be nil"))
     }
-    SQL := "%s"
+%s    SQL := "%s"
     %s
-    args = append(args, %s)
-    ret, err := db.Exec(SQL, args...)
-    if err != nil {
-        return 0, t.Error(err)
-    }
-    count, err := ret.RowsAffected()
-    if err != nil {
-        return 0, t.Error(err)
-    }
-    return count, nil
-}
-`, modelName, strings.Join(fields, ""), modelName, SQL, set, strings.Join(args, ", "))
-	}
-	return funcLines, nil
+%s
+`, modelName, modelName, zero, g.prepared(SQL), insert, exec)
+	return funcLines, imports
 }
 
-func r(statement *parser.Statement, asc string, desc string, round string) (string, []string) {
+func cm(statement *parser.Statement, g *gen) (string, []string) {
 	modelName := generator.FirstUpperCamelCase(statement.TableName.Name)
-	funcLines := ""
-	names := make([]string, 0)
-	binds := make([]string, 0)
-
+	zero, imports := versionZero(statement, "        ")
+	columns := make([]string, 0)
+	values := make([]string, 0)
+	args := make([]string, 0)
 	for _, col := range statement.Columns {
-		names = append(names, "`"+col.ColumnName.Name+"`")
+		if col.AutoIncrement || col.CurrentTimestamp || col.DefaultValue != nil {
+			continue
+		}
 		fieldName := generator.FirstUpperCamelCase(col.ColumnName.Name)
-		binds = append(binds, "&ret."+fieldName)
+		columns = append(columns, "\""+g.ident(col.ColumnName.Name)+"\"")
+		values = append(values, "\"?\"")
+		arg := "s." + fieldName
+		arg = timeArg(col, arg, g.times)
+		args = append(args, arg)
+	}
+	present := ""
+	row := ""
+	for _, col := range statement.Columns {
+		if col.AutoIncrement || col.CurrentTimestamp {
+			continue
+		}
+		if col.DefaultValue != nil {
+			fieldName := generator.FirstUpperCamelCase(col.ColumnName.Name)
+			arg := "s." + fieldName
+			arg = timeArg(col, arg, g.times)
+			present += fmt.Sprintf(`        has%s := false
+        for _, s := range batch {
+            if s.%s != nil {
+                has%s = true
+                break
+            }
+        }
+        if has%s {
+            columns = append(columns, "%s")
+        }
+`, fieldName, fieldName, fieldName, fieldName, g.ident(col.ColumnName.Name))
+			row += fmt.Sprintf(`            if has%s {
+                if s.%s != nil {
+                    value = append(value, "?")
+                    args = append(args, %s)
+                } else {
+                    value = append(value, "default")
+                }
+            }
+`, fieldName, fieldName, arg)
+		}
+	}
+	insert := fmt.Sprintf(`        columns := []string{%s}
+%s        values := make([]string, 0, len(batch))
+        args := make([]interface{}, 0)
+        for _, s := range batch {
+            value := []string{%s}
+            args = append(args, %s)
+%s            values = append(values, "("+strings.Join(value, ", ")+")")
+        }
+        SQL = fmt.Sprintf(SQL, strings.Join(columns, ", "), strings.Join(values, ", "))`, strings.Join(columns, ", "), present, strings.Join(values, ", "), strings.Join(args, ", "), row)
+	if len(args) == 0 {
+		insert = strings.Replace(insert, "            args = append(args, )\n", "", 1)
 	}
 
-	uniqKeyPairs := getUniqKeyPairs(statement)
+	SQL := fmt.Sprintf("insert into "+g.ident("%s")+" (%%s) values %%s", statement.TableName.Name)
+	funcLines := fmt.Sprintf(`func CreateMany%s(`+g.db()+`, list []*%s, batchSize int) (int64, error) {
+    for _, s := range list {
+        if s == nil {
+            return 0, t.Error(fmt.Errorf("pointer can not be nil"))
+        }
+%s    }
+    if batchSize <= 0 {
+        batchSize = 500
+    }
+    total := int64(0)
+    for start := 0; start < len(list); start += batchSize {
+        end := start + batchSize
+        if end > len(list) {
+            end = len(list)
+        }
+        batch := list[start:end]
+        SQL := "%s"
+%s
+        ret, err := `+g.exec("SQL")+`, args...)
+        if err != nil {
+            return total, t.Error(err)
+        }
+        count, err := ret.RowsAffected()
+        if err != nil {
+            return total, t.Error(err)
+        }
+        total += count
+    }
+    return total, nil
+}
+`, modelName, modelName, zero, g.prepared(SQL), insert)
+	return funcLines, imports
+}
+
+func ups(statement *parser.Statement, g *gen) (string, []string) {
+	modelName := generator.FirstUpperCamelCase(statement.TableName.Name)
+	funcLines := ""
+	imports := make([]string, 0)
+	uniqKeyPairs := generator.UniqKeyPairs(statement)
 	for _, keys := range uniqKeyPairs {
+		if !g.upsertable(keys) {
+			continue
+		}
+		version := generator.VersionColumn(statement)
+		if version != nil && contains(keys, version) {
+			version = nil
+		}
+		zero := ""
+		if version != nil {
+			zero, imports = versionZero(statement, "    ")
+		}
+		begin := ""
 		fields := make([]string, 0)
-		conditions := make([]string, 0)
+		columns := make([]string, 0)
+		values := make([]string, 0)
 		args := make([]string, 0)
+		names := make([]string, 0)
+		updatable := make([]string, 0)
 		for _, col := range keys {
-			conditions = append(conditions, "`"+col.ColumnName.Name+"` = ?")
 			fieldName := generator.FirstUpperCamelCase(col.ColumnName.Name)
+			columns = append(columns, "\""+g.ident(col.ColumnName.Name)+"\"")
+			values = append(values, "\"?\"")
 			arg := "s." + fieldName
-			if (col.Type == "DATE" || col.Type == "DATETIME" || col.Type == "TIMESTAMP") && round != "" {
-				arg = arg + ".Round(" + round + ")"
-			}
+			arg = timeArg(col, arg, g.times)
 			args = append(args, arg)
 			fields = append(fields, fieldName)
 		}
-		SQL := fmt.Sprintf("select %s from `%s` where %s", strings.Join(names, ", "), statement.TableName, strings.Join(conditions, " and "))
-		funcLines += fmt.Sprintf(`func Query%sBy%s(db DataSource, s *%s) (*%s, error) {
-    if s == nil {
-        return nil, t.Error(fmt.Errorf("pointer can not be nil"))
+		optional := ""
+		for _, col := range statement.Columns {
+			if col.AutoIncrement || col.CurrentTimestamp {
+				continue
+			}
+			if contains(keys, col) {
+				continue
+			}
+			if col != version {
+				updatable = append(updatable, "\""+col.ColumnName.Name+"\"")
+			}
+			fieldName := generator.FirstUpperCamelCase(col.ColumnName.Name)
+			arg := "s." + fieldName
+			arg = timeArg(col, arg, g.times)
+			if col.DefaultValue != nil {
+				name := fmt.Sprintf(`
+        names = append(names, "%s")`, col.ColumnName.Name)
+				if col == version {
+					name = ""
+				}
+				optional += fmt.Sprintf(`    if s.%s != nil {
+        columns = append(columns, "%s")
+        values = append(values, "?")
+        args = append(args, %s)%s
     }
-    SQL := "%s"
-    ret := &%s{}
-    err := db.QueryRow(SQL, %s).Scan(%s)
+`, fieldName, g.ident(col.ColumnName.Name), arg, name)
+				continue
+			}
+			columns = append(columns, "\""+g.ident(col.ColumnName.Name)+"\"")
+			values = append(values, "\"?\"")
+			args = append(args, arg)
+			if col != version {
+				names = append(names, "\""+col.ColumnName.Name+"\"")
+			}
+		}
+		bump := ""
+		if version != nil {
+			current := ""
+			switch g.dialect() {
+			case Postgres, SQLite:
+				current = g.ident(statement.TableName.Name) + "."
+			case SQLServer:
+				current = "target."
+			}
+			bump = fmt.Sprintf(`    set = append(set, "%s = %s%s + 1")
+`, g.ident(version.ColumnName.Name), current, g.ident(version.ColumnName.Name))
+		}
+		conflict := make([]string, 0)
+		matched := make([]string, 0)
+		for _, col := range keys {
+			conflict = append(conflict, g.ident(col.ColumnName.Name))
+			matched = append(matched, "target."+g.ident(col.ColumnName.Name)+" = source."+g.ident(col.ColumnName.Name))
+		}
+		name := g.identOf("name")
+		assign := name + " = values(" + name + ")"
+		format := `    SQL = fmt.Sprintf(SQL, strings.Join(columns, ", "), strings.Join(values, ", "), strings.Join(set, ", "))`
+		SQL := fmt.Sprintf("insert into "+g.ident("%s")+" (%%s) values (%%s) on duplicate key update %%s", statement.TableName.Name)
+		exec := `    ret, err := ` + g.exec("SQL") + `, args...)
     if err != nil {
-        if err != sql.ErrNoRows {
-            return nil, t.Error(err)
-        }
-        return nil, nil
+        return UpsertUnchanged, t.Error(err)
     }
-    return ret, nil
-}
-`, modelName, strings.Join(fields, ""), modelName, modelName, SQL, modelName, strings.Join(args, ", "), strings.Join(binds, ", "))
-	}
-	type Order struct {
-		FuncSuffix string
-		Statement  string
-	}
-	orders := []*Order{{}}
-	if asc != "" {
-		columns := make([]string, 0)
-		if (strings.HasPrefix(asc, "\"") && strings.HasSuffix(asc, "\"")) || (strings.HasPrefix(asc, "'") && strings.HasSuffix(asc, "'")) {
-			asc = asc[1 : len(asc)-1]
-		}
-		columnNames := strings.Split(asc, ",")
-		for _, name := range columnNames {
-			for _, c := range statement.Columns {
-				if c.ColumnName.Name != name {
-					continue
-				}
-				columns = append(columns, c.ColumnName.Name)
-			}
+    count, err := ret.RowsAffected()
+    if err != nil {
+        return UpsertUnchanged, t.Error(err)
+    }
+    switch count {
+    case 1:
+        return UpsertInserted, nil
+    case 0:
+        return UpsertUnchanged, nil
+    default:
+        return UpsertUpdated, nil
+    }
+}`
+		switch g.dialect() {
+		case Postgres:
+			assign = name + " = excluded." + name
+			SQL = fmt.Sprintf("insert into "+g.ident("%s")+" (%%s) values (%%s) on conflict (%s) do update set %%s returning (xmax = 0)", statement.TableName.Name, strings.Join(conflict, ", "))
+			exec = `    inserted := false
+    err := ` + g.queryRow("SQL") + `, args...).Scan(&inserted)
+    if err != nil {
+        return UpsertUnchanged, t.Error(err)
+    }
+    if inserted {
+        return UpsertInserted, nil
+    }
+    return UpsertUpdated, nil
+}`
+		case SQLite:
+			begin = fmt.Sprintf(`    if sqlDB, ok := db.(*sql.DB); ok {
+        tx, err := sqlDB.%s
+        if err != nil {
+            return UpsertUnchanged, t.Error(err)
+        }
+        defer tx.Rollback()
+        result, err := `+g.audited("Upsert")+`%sBy%s(%s, s, update...)
+        if err != nil {
+            return UpsertUnchanged, err
+        }
+        err = tx.Commit()
+        if err != nil {
+            return UpsertUnchanged, t.Error(err)
+        }
+        return result, nil
+    }
+`, g.begin(), modelName, strings.Join(fields, ""), g.txArgs())
+			assign = name + " = excluded." + name
+			format = `    SQL1 := fmt.Sprintf(SQL, strings.Join(columns, ", "), strings.Join(values, ", "), "nothing")
+    SQL2 := fmt.Sprintf(SQL, strings.Join(columns, ", "), strings.Join(values, ", "), "update set "+strings.Join(set, ", "))`
+			SQL = fmt.Sprintf("insert into "+g.ident("%s")+" (%%s) values (%%s) on conflict (%s) do %%s", statement.TableName.Name, strings.Join(conflict, ", "))
+			exec = `    ret, err := ` + g.exec("SQL1") + `, args...)
+    if err != nil {
+        return UpsertUnchanged, t.Error(err)
+    }
+    count, err := ret.RowsAffected()
+    if err != nil {
+        return UpsertUnchanged, t.Error(err)
+    }
+    if count == 1 {
+        return UpsertInserted, nil
+    }
+    ret, err = ` + g.exec("SQL2") + `, args...)
+    if err != nil {
+        return UpsertUnchanged, t.Error(err)
+    }
+    count, err = ret.RowsAffected()
+    if err != nil {
+        return UpsertUnchanged, t.Error(err)
+    }
+    if count == 0 {
+        return UpsertUnchanged, nil
+    }
+    return UpsertUpdated, nil
+}`
+		case SQLServer:
+			assign = name + " = source." + name
+			format = `    sources := make([]string, 0, len(columns))
+    for _, column := range columns {
+        sources = append(sources, "source."+column)
+    }
+    SQL = fmt.Sprintf(SQL, strings.Join(values, ", "), strings.Join(columns, ", "), strings.Join(set, ", "), strings.Join(columns, ", "), strings.Join(sources, ", "))`
+			SQL = fmt.Sprintf("merge into "+g.ident("%s")+" with (holdlock) as target using (values (%%s)) as source (%%s) on %s when matched then update set %%s when not matched then insert (%%s) values (%%s) output $action;", statement.TableName.Name, strings.Join(matched, " and "))
+			exec = `    action := ""
+    err := ` + g.queryRow("SQL") + `, args...).Scan(&action)
+    if err != nil {
+        return UpsertUnchanged, t.Error(err)
+    }
+    if action == "INSERT" {
+        return UpsertInserted, nil
+    }
+    return UpsertUpdated, nil
+}`
 		}
-		if len(columns) != 0 {
-			s1 := make([]string, 0)
-			s2 := make([]string, 0)
-			for _, c := range columns {
-				s1 = append(s1, generator.FirstUpperCamelCase(c))
-				s2 = append(s2, "`"+c+"`")
+		insert := fmt.Sprintf(`columns := []string{%s}
+    values := []string{%s}
+    args := []interface{}{%s}
+    names := []string{%s}
+%s    if len(update) == 0 {
+        update = names
+    }
+    updatable := []string{%s}
+    set := make([]string, 0, len(update))
+    for _, name := range update {
+        found := false
+        for _, u := range updatable {
+            if u == name {
+                found = true
+                break
+            }
+        }
+        if !found {
+            return UpsertUnchanged, t.Error(fmt.Errorf("column %%s can not be updated", name))
+        }
+        set = append(set, "%s")
+    }
+    if len(set) == 0 {
+        return UpsertUnchanged, t.Error(fmt.Errorf("no column to update"))
+    }
+%s%s`, strings.Join(columns, ", "), strings.Join(values, ", "), strings.Join(args, ", "), strings.Join(names, ", "), optional, strings.Join(updatable, ", "), assign, bump, format)
+
+		funcLines += fmt.Sprintf(`func `+g.audited("Upsert")+`%sBy%s(`+g.db()+`, s *%s, update ...string) (UpsertResult, error) {
+    if s == nil {
+        return UpsertUnchanged, t.Error(fmt.Errorf("pointer can not be nil"))
+    }
+%s%s    SQL := "%s"
+    %s
+%s
+`, modelName, strings.Join(fields, ""), modelName, begin, zero, g.prepared(SQL), insert, exec)
+	}
+	return funcLines, imports
+}
+
+func u(statement *parser.Statement, g *gen) (string, []string) {
+	modelName := generator.FirstUpperCamelCase(statement.TableName.Name)
+	funcLines := ""
+	uniqKeyPairs := generator.UniqKeyPairs(statement)
+	for _, keys := range uniqKeyPairs {
+		version := generator.VersionColumn(statement)
+		if version != nil && contains(keys, version) {
+			version = nil
+		}
+		args := make([]string, 0)
+		set := `set := ""
+    args := make([]interface{}, 0)
+    `
+		for _, col := range statement.Columns {
+			if col.AutoIncrement || col.CurrentTimestamp {
+				continue
+			}
+			if contains(keys, col) || col == version {
+				continue
 			}
-			orders = append(orders, &Order{FuncSuffix: fmt.Sprintf("OrderBy%s", strings.Join(s1, "")), Statement: fmt.Sprintf("order by %s ", strings.Join(s2, ", "))})
+			fieldName := generator.FirstUpperCamelCase(col.ColumnName.Name)
+			arg := "s." + fieldName
+			arg = timeArg(col, arg, g.times)
+			set += fmt.Sprintf(`if %s != nil {
+        set += ", `+g.ident("%s")+` = ? "
+        args = append(args, %s)
+    }
+    `, "s."+fieldName, col.ColumnName, arg)
+		}
+		set += `set = strings.TrimLeft(set, ",")
+    set = strings.TrimSpace(set)
+    if set == "" {
+        return 0, t.Error(fmt.Errorf("all field is nil"))
+    }
+    SQL = fmt.Sprintf(SQL, set)`
+		fields := make([]string, 0)
+		conditions := make([]string, 0)
+		for _, col := range keys {
+			conditions = append(conditions, g.ident(col.ColumnName.Name)+" = ?")
+			arg := "s." + generator.FirstUpperCamelCase(col.ColumnName.Name)
+			arg = timeArg(col, arg, g.times)
+			args = append(args, arg)
+			fields = append(fields, generator.FirstUpperCamelCase(col.ColumnName.Name))
+		}
+		SQL := fmt.Sprintf("update "+g.ident("%s")+" set %%s where %s", statement.TableName.Name, strings.Join(conditions, " and "))
+		versionCheck := ""
+		staleCheck := ""
+		if version != nil {
+			versionName := generator.FirstUpperCamelCase(version.ColumnName.Name)
+			SQL = fmt.Sprintf("update "+g.ident("%s")+" set %%s, "+g.ident("%s")+" = "+g.ident("%s")+" + 1 where %s and "+g.ident("%s")+" = ?", statement.TableName.Name, version.ColumnName.Name, version.ColumnName.Name, strings.Join(conditions, " and "), version.ColumnName.Name)
+			args = append(args, "s."+versionName)
+			versionCheck = fmt.Sprintf(`    if s.%s == nil {
+        return 0, t.Error(fmt.Errorf("version can not be nil"))
+    }
+`, versionName)
+			staleCheck = `    if count == 0 {
+        return 0, ErrStaleObject
+    }
+`
 		}
+		funcLines += fmt.Sprintf(`func `+g.audited("Update")+`%sBy%s(`+g.db()+`, s *%s) (int64, error) {
+    if s == nil {
+        return 0, t.Error(fmt.Errorf("pointer can not be nil"))
+    }
+%s    SQL := "%s"
+    %s
+    args = append(args, %s)
+    ret, err := `+g.exec("SQL")+`, args...)
+    if err != nil {
+        return 0, t.Error(err)
+    }
+    count, err := ret.RowsAffected()
+    if err != nil {
+        return 0, t.Error(err)
+    }
+%s    return count, nil
+}
+`, modelName, strings.Join(fields, ""), modelName, versionCheck, SQL, set, strings.Join(args, ", "), staleCheck)
 	}
-	if desc != "" {
-		columns := make([]string, 0)
-		if (strings.HasPrefix(desc, "\"") && strings.HasSuffix(desc, "\"")) || (strings.HasPrefix(desc, "'") && strings.HasSuffix(desc, "'")) {
-			desc = desc[1 : len(desc)-1]
+	return funcLines, nil
+}
+
+// uf updates the columns of the field mask, a nil field in the mask sets its column to null.
+func uf(statement *parser.Statement, g *gen) (string, []string) {
+	modelName := generator.FirstUpperCamelCase(statement.TableName.Name)
+	funcLines := ""
+	uniqKeyPairs := generator.UniqKeyPairs(statement)
+	for _, keys := range uniqKeyPairs {
+		version := generator.VersionColumn(statement)
+		if version != nil && contains(keys, version) {
+			version = nil
 		}
-		columnNames := strings.Split(desc, ",")
-		for _, name := range columnNames {
-			for _, c := range statement.Columns {
-				if c.ColumnName.Name != name {
-					continue
-				}
-				columns = append(columns, c.ColumnName.Name)
+		cases := make([]string, 0)
+		for _, col := range statement.Columns {
+			if col.AutoIncrement || col.CurrentTimestamp {
+				continue
 			}
-		}
-		if len(columns) != 0 {
-			s1 := make([]string, 0)
-			s2 := make([]string, 0)
-			for _, c := range columns {
-				s1 = append(s1, generator.FirstUpperCamelCase(c))
-				s2 = append(s2, "`"+c+"`")
+			if contains(keys, col) || col == version {
+				continue
 			}
-			orders = append(orders, &Order{FuncSuffix: fmt.Sprintf("OrderBy%sDesc", strings.Join(s1, "")), Statement: fmt.Sprintf("order by %s desc ", strings.Join(s2, ", "))})
+			fieldName := generator.FirstUpperCamelCase(col.ColumnName.Name)
+			arg := "        args = append(args, s." + fieldName + ")"
+			if value := timeArg(col, "s."+fieldName, g.times); value != "s."+fieldName {
+				arg = fmt.Sprintf(`        if s.%s != nil {
+            args = append(args, %s)
+        } else {
+            args = append(args, nil)
+        }`, fieldName, value)
+			}
+			cases = append(cases, fmt.Sprintf(`        case %sColumn%s:
+            set = append(set, "`+g.ident("%s")+` = ?")
+    %s`, modelName, fieldName, col.ColumnName.Name, arg))
+		}
+		fields := make([]string, 0)
+		conditions := make([]string, 0)
+		args := make([]string, 0)
+		for _, col := range keys {
+			conditions = append(conditions, g.ident(col.ColumnName.Name)+" = ?")
+			arg := "s." + generator.FirstUpperCamelCase(col.ColumnName.Name)
+			arg = timeArg(col, arg, g.times)
+			args = append(args, arg)
+			fields = append(fields, generator.FirstUpperCamelCase(col.ColumnName.Name))
+		}
+		SQL := fmt.Sprintf("update "+g.ident("%s")+" set %%s where %s", statement.TableName.Name, strings.Join(conditions, " and "))
+		versionCheck := ""
+		staleCheck := ""
+		if version != nil {
+			versionName := generator.FirstUpperCamelCase(version.ColumnName.Name)
+			SQL = fmt.Sprintf("update "+g.ident("%s")+" set %%s, "+g.ident("%s")+" = "+g.ident("%s")+" + 1 where %s and "+g.ident("%s")+" = ?", statement.TableName.Name, version.ColumnName.Name, version.ColumnName.Name, strings.Join(conditions, " and "), version.ColumnName.Name)
+			args = append(args, "s."+versionName)
+			versionCheck = fmt.Sprintf(`    if s.%s == nil {
+        return 0, t.Error(fmt.Errorf("version can not be nil"))
+    }
+`, versionName)
+			staleCheck = `    if count == 0 {
+        return 0, ErrStaleObject
+    }
+`
+		}
+		switchLines := "    for _, field := range fields {\n        switch field {\n" + strings.Join(cases, "\n") + `
+        default:
+            return 0, t.Error(fmt.Errorf("column %s can not be updated", field))
+        }
+    }`
+		funcLines += fmt.Sprintf(`func `+g.audited("Update")+`%sBy%sFields(`+g.db()+`, s *%s, fields []%sColumn) (int64, error) {
+    if s == nil {
+        return 0, t.Error(fmt.Errorf("pointer can not be nil"))
+    }
+    if len(fields) == 0 {
+        return 0, t.Error(fmt.Errorf("no field selected"))
+    }
+%s    set := make([]string, 0, len(fields))
+    args := make([]interface{}, 0, len(fields))
+%s
+    SQL := fmt.Sprintf("%s", strings.Join(set, ", "))
+    args = append(args, %s)
+    ret, err := `+g.exec("SQL")+`, args...)
+    if err != nil {
+        return 0, t.Error(err)
+    }
+    count, err := ret.RowsAffected()
+    if err != nil {
+        return 0, t.Error(err)
+    }
+%s    return count, nil
+}
+`, modelName, strings.Join(fields, ""), modelName, modelName, versionCheck, switchLines, SQL, strings.Join(args, ", "), staleCheck)
+	}
+	return funcLines, nil
+}
+
+func r(statement *parser.Statement, g *gen, logic *generator.Logic, suffix string) (string, []string) {
+	modelName := generator.FirstUpperCamelCase(statement.TableName.Name)
+	funcLines := ""
+	names := make([]string, 0)
+	binds := make([]string, 0)
+
+	for _, col := range statement.Columns {
+		names = append(names, g.ident(col.ColumnName.Name))
+		fieldName := generator.FirstUpperCamelCase(col.ColumnName.Name)
+		binds = append(binds, "&ret."+fieldName)
+	}
+
+	uniqKeyPairs := generator.UniqKeyPairs(statement)
+	for _, keys := range uniqKeyPairs {
+		fields := make([]string, 0)
+		conditions := make([]string, 0)
+		args := make([]string, 0)
+		for _, col := range keys {
+			conditions = append(conditions, g.ident(col.ColumnName.Name)+" = ?")
+			fieldName := generator.FirstUpperCamelCase(col.ColumnName.Name)
+			arg := "s." + fieldName
+			arg = timeArg(col, arg, g.times)
+			args = append(args, arg)
+			fields = append(fields, fieldName)
+		}
+		if logic != nil {
+			conditions = append(conditions, quote(logic.Alive(g.name)))
 		}
+		SQL := fmt.Sprintf("select %s from "+g.ident("%s")+" where %s", strings.Join(names, ", "), statement.TableName, strings.Join(conditions, " and "))
+		funcLines += fmt.Sprintf(`func Query%sBy%s`+suffix+`(`+g.db()+`, s *%s) (*%s, error) {
+    if s == nil {
+        return nil, t.Error(fmt.Errorf("pointer can not be nil"))
+    }
+    SQL := "%s"
+    ret := &%s{}
+    err := `+g.queryRow("SQL")+`, %s).Scan(%s)
+    if err != nil {
+        if err != sql.ErrNoRows {
+            return nil, t.Error(err)
+        }
+        return nil, nil
+    }
+    return ret, nil
+}
+`, modelName, strings.Join(fields, ""), modelName, modelName, g.prepared(SQL), modelName, strings.Join(args, ", "), strings.Join(binds, ", "))
 	}
+	orders := getOrders(statement, g)
 	for _, order := range orders {
-		indexKeyPairs := getIndexKeyPairs(statement)
+		indexKeyPairs := generator.IndexKeyPairs(statement)
 		for _, keys := range indexKeyPairs {
 			fields := make([]string, 0)
 			conditions := make([]string, 0)
 			args := make([]string, 0)
 			for _, col := range keys {
-				conditions = append(conditions, "`"+col.ColumnName.Name+"` = ?")
+				conditions = append(conditions, g.ident(col.ColumnName.Name)+" = ?")
 				fieldName := generator.FirstUpperCamelCase(col.ColumnName.Name)
 				arg := "s." + fieldName
-				if (col.Type == "DATE" || col.Type == "DATETIME" || col.Type == "TIMESTAMP") && round != "" {
-					arg = arg + ".Round(" + round + ")"
-				}
+				arg = timeArg(col, arg, g.times)
 				args = append(args, arg)
 				fields = append(fields, fieldName)
 			}
-			SQL1 := fmt.Sprintf("select count(*) from `%s` where %s", statement.TableName.Name, strings.Join(conditions, " and "))
-			SQL2 := fmt.Sprintf("select %s from `%s` where %s %slimit ?, ?", strings.Join(names, ", "), statement.TableName.Name, strings.Join(conditions, " and "), order.Statement)
-			funcLines += fmt.Sprintf(`func QueryMany%sBy%s%s(db DataSource, s *%s, page int, size int) (int, []*%s, error) {
+			if logic != nil {
+				conditions = append(conditions, quote(logic.Alive(g.name)))
+			}
+			SQL1 := fmt.Sprintf("select count(*) from "+g.ident("%s")+" where %s", statement.TableName.Name, strings.Join(conditions, " and "))
+			SQL2 := fmt.Sprintf("select %s from "+g.ident("%s")+" where %s %s", strings.Join(names, ", "), statement.TableName.Name, strings.Join(conditions, " and "), g.page(order.Statement))
+			funcLines += fmt.Sprintf(`func QueryMany%sBy%s%s`+suffix+`(`+g.db()+`, s *%s, page int, size int) (int, []*%s, error) {
     if s == nil {
         return 0, nil, t.Error(fmt.Errorf("pointer can not be nil"))
     }
@@ -359,13 +1028,13 @@ func r(statement *parser.Statement, asc string, desc string, round string) (stri
     }
     SQL1 := "%s"
     count := 0
-    err := db.QueryRow(SQL1, %s).Scan(&count)
+    err := `+g.queryRow("SQL1")+`, %s).Scan(&count)
     if err != nil {
         return 0, nil, t.Error(err)
     }
 
     SQL2 := "%s"
-    rows, err := db.Query(SQL2, %s, (page-1)*size, size)
+    rows, err := `+g.query("SQL2")+`, %s, (page-1)*size, size)
     if err != nil {
         if err != sql.ErrNoRows {
             return 0, nil, t.Error(err)
@@ -385,7 +1054,7 @@ func r(statement *parser.Statement, asc string, desc string, round string) (stri
     }
     return count, results, nil
 }
-`, modelName, strings.Join(fields, ""), order.FuncSuffix, modelName, modelName, SQL1, strings.Join(args, ", "), SQL2, strings.Join(args, ", "), modelName, modelName, strings.Join(binds, ", "))
+`, modelName, strings.Join(fields, ""), order.FuncSuffix, modelName, modelName, g.prepared(SQL1), strings.Join(args, ", "), g.prepared(SQL2), strings.Join(args, ", "), modelName, modelName, strings.Join(binds, ", "))
 		}
 
 		where := `where := ""
@@ -395,11 +1064,9 @@ func r(statement *parser.Statement, asc string, desc string, round string) (stri
 		for _, col := range statement.Columns {
 			fieldName := generator.FirstUpperCamelCase(col.ColumnName.Name)
 			arg := "s." + fieldName
-			if (col.Type == "DATE" || col.Type == "DATETIME" || col.Type == "TIMESTAMP") && round != "" {
-				arg = arg + ".Round(" + round + ")"
-			}
+			arg = timeArg(col, arg, g.times)
 			where += fmt.Sprintf(`        if %s != nil {
-            where += "and `+"`%s`"+` = ? "
+            where += "and `+g.ident("%s")+` = ? "
             args = append(args, %s)
         }
 `, "s."+fieldName, col.ColumnName, arg)
@@ -413,26 +1080,49 @@ func r(statement *parser.Statement, asc string, desc string, round string) (stri
     }
     SQL1 = fmt.Sprintf(SQL1, where)
     SQL2 = fmt.Sprintf(SQL2, where)`
+		if logic != nil {
+			where = strings.Replace(where, `where := ""`, `where := "and `+quote(logic.Alive(g.name))+` "`, 1)
+			where = strings.Replace(where, `        where = strings.TrimLeft(where, "and")
+        where = strings.TrimSpace(where)
+        if where != "" {
+            where = "where " + where
+        }
+    }`, `    }
+    where = strings.TrimLeft(where, "and")
+    where = strings.TrimSpace(where)
+    where = "where " + where`, 1)
+		}
 
-		SQL1 := fmt.Sprintf("select count(*) from `%s` %%s", statement.TableName.Name)
-		SQL2 := fmt.Sprintf("select %s from `%s` %%s %slimit ?, ?", strings.Join(names, ", "), statement.TableName.Name, order.Statement)
-		funcLines += fmt.Sprintf(`func QueryMany%s%s(db DataSource, s *%s, page int, size int) (int, []*%s, error) {
+		SQL1 := fmt.Sprintf("select count(*) from "+g.ident("%s")+" %%s", statement.TableName.Name)
+		SQL2 := fmt.Sprintf("select %s from "+g.ident("%s")+" %%s %s", strings.Join(names, ", "), statement.TableName.Name, g.page(order.Statement))
+		params := "s *" + modelName + ", page int"
+		sortLines := ""
+		if g.Sort && order.FuncSuffix == "" {
+			params = "s *" + modelName + ", orders []Order, page int"
+			SQL2 = fmt.Sprintf("select %s from "+g.ident("%s")+" %%s "+g.page("\" + sort + \""), strings.Join(names, ", "), statement.TableName.Name)
+			sortLines = fmt.Sprintf(`
+    sort, sortErr := sortBy(orders, sortable%s)
+    if sortErr != nil {
+        return 0, nil, sortErr
+    }`, modelName)
+		}
+		funcLines += fmt.Sprintf(`func QueryMany%s%s`+suffix+`(`+g.db()+`, %s, size int) (int, []*%s, error) {
     if page <= 0 {
         page = 1
     }
     if size <= 0 {
         size = 10
-    }
+    }%s
     SQL1 := "%s"
     SQL2 := "%s"
     %s
     count := 0
-    err := db.QueryRow(SQL1, args...).Scan(&count)
+    err := `+g.queryRow("SQL1")+`, args...).Scan(&count)
     if err != nil {
         return 0, nil, t.Error(err)
     }
     args = append(args, (page-1)*size, size)
-    rows, err := db.Query(SQL2, args...)
+    rows, err := `+g.query("SQL2")+`, args...)
     if err != nil {
         if err != sql.ErrNoRows {
             return 0, nil, t.Error(err)
@@ -452,125 +1142,1219 @@ func r(statement *parser.Statement, asc string, desc string, round string) (stri
     }
     return count, results, nil
 }
-`, modelName, order.FuncSuffix, modelName, modelName, SQL1, SQL2, where, modelName, modelName, strings.Join(binds, ", "))
+`, modelName, order.FuncSuffix, params, modelName, sortLines, g.prepared(SQL1), g.prepared(SQL2), where, modelName, modelName, strings.Join(binds, ", "))
 	}
 	return funcLines, nil
 }
 
-func d(statement *parser.Statement, logic string, round string) (string, []string) {
-	var logicDelete bool
-	var logicCol string
-	var logicValue string
-	if logic != "" {
-		if index := strings.Index(logic, "="); index != -1 {
-			logicCol, logicValue = logic[:index], logic[index+1:]
-			if (strings.HasPrefix(logicValue, "\"") && strings.HasSuffix(logicValue, "\"")) || (strings.HasPrefix(logicValue, "'") && strings.HasSuffix(logicValue, "'")) {
-				logicValue = logicValue[1 : len(logicValue)-1]
-			}
-			for _, c := range statement.Columns {
-				if c.ColumnName.Name != logicCol {
-					continue
-				}
-				if c.Type != "TINYINT" && c.Type != "INT" && c.Type != "BIGINT" && c.Type != "FLOAT" {
-					logicValue = `\"` + logicValue + `\"`
-				}
-				logicDelete = true
-			}
-		}
-	}
+func ra(statement *parser.Statement, g *gen, logic *generator.Logic, suffix string) (string, []string) {
 	modelName := generator.FirstUpperCamelCase(statement.TableName.Name)
 	funcLines := ""
-	uniqKeyPairs := getUniqKeyPairs(statement)
-	for _, keys := range uniqKeyPairs {
-		fields := make([]string, 0)
-		conditions := make([]string, 0)
-		args := make([]string, 0)
+	primaryKeys := getPrimaryKeys(statement)
+	if len(primaryKeys) == 0 {
+		return funcLines, nil
+	}
+	names := make([]string, 0)
+	binds := make([]string, 0)
+	for _, col := range statement.Columns {
+		names = append(names, g.ident(col.ColumnName.Name))
+		fieldName := generator.FirstUpperCamelCase(col.ColumnName.Name)
+		binds = append(binds, "&ret."+fieldName)
+	}
+	where := `where := ""
+    args := make([]interface{}, 0)
+    if s != nil {
+`
+	for _, col := range statement.Columns {
+		fieldName := generator.FirstUpperCamelCase(col.ColumnName.Name)
+		arg := "s." + fieldName
+		arg = timeArg(col, arg, g.times)
+		where += fmt.Sprintf(`        if %s != nil {
+            where += "and `+g.ident("%s")+` = ? "
+            args = append(args, %s)
+        }
+`, "s."+fieldName, col.ColumnName, arg)
+	}
+	where += `    }
+`
+	if logic != nil {
+		where = strings.Replace(where, `where := ""`, `where := "and `+quote(logic.Alive(g.name))+` "`, 1)
+	}
+	orders := getOrders(statement, g)
+	for _, order := range orders {
+		keys := make([]*parser.ColumnDefinition, 0)
+		keys = append(keys, order.Columns...)
+		for _, col := range primaryKeys {
+			if !contains(keys, col) {
+				keys = append(keys, col)
+			}
+		}
+		columns := make([]string, 0)
+		sorts := make([]string, 0)
+		marks := make([]string, 0)
+		values := make([]string, 0)
 		for _, col := range keys {
-			fieldName := generator.FirstUpperCamelCase(col.ColumnName.Name)
-			conditions = append(conditions, "`"+col.ColumnName.Name+"` = ?")
-			arg := "s." + fieldName
-			if col.Type == "DATE" || col.Type == "DATETIME" || col.Type == "TIMESTAMP" {
-				arg = "time.Time(" + arg + ")"
-				if round != "" {
-					arg = arg + ".Round(" + round + ")"
-				}
+			columns = append(columns, g.ident(col.ColumnName.Name))
+			if order.Desc {
+				sorts = append(sorts, g.ident(col.ColumnName.Name)+" desc")
+			} else {
+				sorts = append(sorts, g.ident(col.ColumnName.Name))
 			}
-			args = append(args, arg)
-			fields = append(fields, fieldName)
+			marks = append(marks, "?")
+			values = append(values, "last."+generator.FirstUpperCamelCase(col.ColumnName.Name))
 		}
-		SQL := ""
-		if logicDelete {
-			SQL = fmt.Sprintf("update `%s` set `%s` = %s where %s", statement.TableName, logicCol, logicValue, strings.Join(conditions, " and "))
-		} else {
-			SQL = fmt.Sprintf("delete from `%s` where %s", statement.TableName, strings.Join(conditions, " and "))
+		operator := ">"
+		if order.Desc {
+			operator = "<"
 		}
-		funcTemplate := `func %sDelete%sBy%s(db DataSource, s *%s) (int64, error) {
-    if s == nil {
-        return 0, t.Error(fmt.Errorf("pointer can not be nil"))
-    }
-    SQL := "%s"
-    ret, err := db.Exec(SQL, %s)
-    if err != nil {
+		condition := fmt.Sprintf("and (%s) %s (%s) ", strings.Join(columns, ", "), operator, strings.Join(marks, ", "))
+		cursorArgs := "values..."
+		if g.Dialect == SQLServer {
+			ors := make([]string, 0)
+			indexes := make([]string, 0)
+			for i := range keys {
+				ands := make([]string, 0)
+				for j := 0; j < i; j++ {
+					ands = append(ands, columns[j]+" = ?")
+					indexes = append(indexes, fmt.Sprintf("values[%d]", j))
+				}
+				ands = append(ands, columns[i]+" "+operator+" ?")
+				indexes = append(indexes, fmt.Sprintf("values[%d]", i))
+				ors = append(ors, "("+strings.Join(ands, " and ")+")")
+			}
+			condition = fmt.Sprintf("and (%s) ", strings.Join(ors, " or "))
+			cursorArgs = strings.Join(indexes, ", ")
+		}
+		SQL := fmt.Sprintf("select %s from "+g.ident("%s")+" %%s order by %s "+g.limit(), strings.Join(names, ", "), statement.TableName.Name, strings.Join(sorts, ", "))
+		funcLines += fmt.Sprintf(`func QueryMany%s%sAfter`+suffix+`(`+g.db()+`, s *%s, cursor string, size int) (string, []*%s, error) {
+    if size <= 0 {
+        size = 10
+    }
+    SQL := "%s"
+    %s    if cursor != "" {
+        values, err := decodeCursor(cursor, %d)
+        if err != nil {
+            return "", nil, err
+        }
+        where += "%s"
+        args = append(args, %s)
+    }
+    where = strings.TrimLeft(where, "and")
+    where = strings.TrimSpace(where)
+    if where != "" {
+        where = "where " + where
+    }
+    SQL = fmt.Sprintf(SQL, where)
+    args = append(args, size)
+    rows, err := `+g.query("SQL")+`, args...)
+    if err != nil {
+        return "", nil, t.Error(err)
+    }
+    defer rows.Close()
+
+    results := make([]*%s, 0)
+    for rows.Next() {
+        ret := &%s{}
+        err = rows.Scan(%s)
+        if err != nil {
+            return "", nil, t.Error(err)
+        }
+        results = append(results, ret)
+    }
+    next := ""
+    if len(results) == size {
+        last := results[len(results)-1]
+        next, err = encodeCursor(%s)
+        if err != nil {
+            return "", nil, t.Error(err)
+        }
+    }
+    return next, results, nil
+}
+`, modelName, order.FuncSuffix, modelName, modelName, g.prepared(SQL), where, len(keys), condition, cursorArgs, modelName, modelName, strings.Join(binds, ", "), strings.Join(values, ", "))
+	}
+	return funcLines, nil
+}
+
+func rf(statement *parser.Statement, g *gen, logic *generator.Logic, suffix string) (string, []string) {
+	modelName := generator.FirstUpperCamelCase(statement.TableName.Name)
+	funcLines := ""
+	names := make([]string, 0)
+	binds := make([]string, 0)
+	for _, col := range statement.Columns {
+		names = append(names, g.ident(col.ColumnName.Name))
+		fieldName := generator.FirstUpperCamelCase(col.ColumnName.Name)
+		binds = append(binds, "&ret."+fieldName)
+	}
+	alive, conditions := "", `where = "where " + conditions`
+	if logic != nil {
+		alive, conditions = "where "+quote(logic.Alive(g.name)), `where += " and " + conditions`
+	}
+	orders := getOrders(statement, g)
+	for _, order := range orders {
+		SQL1 := fmt.Sprintf("select count(*) from "+g.ident("%s")+" %%s", statement.TableName.Name)
+		SQL2 := fmt.Sprintf("select %s from "+g.ident("%s")+" %%s %s", strings.Join(names, ", "), statement.TableName.Name, g.page(order.Statement))
+		params := "f *" + modelName + "Filter, page int"
+		sortLines := ""
+		if g.Sort && order.FuncSuffix == "" {
+			params = "f *" + modelName + "Filter, orders []Order, page int"
+			SQL2 = fmt.Sprintf("select %s from "+g.ident("%s")+" %%s "+g.page("\" + sort + \""), strings.Join(names, ", "), statement.TableName.Name)
+			sortLines = fmt.Sprintf(`
+    sort, sortErr := sortBy(orders, sortable%s)
+    if sortErr != nil {
+        return 0, nil, sortErr
+    }`, modelName)
+		}
+		funcLines += fmt.Sprintf(`func QueryMany%sByFilter%s`+suffix+`(`+g.db()+`, %s, size int) (int, []*%s, error) {
+    if page <= 0 {
+        page = 1
+    }
+    if size <= 0 {
+        size = 10
+    }%s
+    SQL1 := "%s"
+    SQL2 := "%s"
+    where := "%s"
+    args := make([]interface{}, 0)
+    if f != nil {
+        conditions, values, err := f.where()
+        if err != nil {
+            return 0, nil, err
+        }
+        if conditions != "" {
+            %s
+        }
+        args = values
+    }
+    SQL1 = fmt.Sprintf(SQL1, where)
+    SQL2 = fmt.Sprintf(SQL2, where)
+    count := 0
+    err := `+g.queryRow("SQL1")+`, args...).Scan(&count)
+    if err != nil {
+        return 0, nil, t.Error(err)
+    }
+    args = append(args, (page-1)*size, size)
+    rows, err := `+g.query("SQL2")+`, args...)
+    if err != nil {
+        return 0, nil, t.Error(err)
+    }
+    defer rows.Close()
+
+    results := make([]*%s, 0)
+    for rows.Next() {
+        ret := &%s{}
+        err = rows.Scan(%s)
+        if err != nil {
+            return 0, nil, t.Error(err)
+        }
+        results = append(results, ret)
+    }
+    return count, results, nil
+}
+`, modelName, order.FuncSuffix, params, modelName, sortLines, g.prepared(SQL1), g.prepared(SQL2), alive, conditions, modelName, modelName, strings.Join(binds, ", "))
+	}
+	if g.Projection {
+		SQL1 := fmt.Sprintf("select count(*) from "+g.ident("%s")+" %%s", statement.TableName.Name)
+		SQL2 := fmt.Sprintf(" from "+g.ident("%s")+" %%s "+g.page(""), statement.TableName.Name)
+		params := "f *" + modelName + "Filter, columns []" + modelName + "Column, page int"
+		sortLines := ""
+		if g.Sort {
+			params = "f *" + modelName + "Filter, orders []Order, columns []" + modelName + "Column, page int"
+			SQL2 = fmt.Sprintf(" from "+g.ident("%s")+" %%s "+g.page("\" + sort + \""), statement.TableName.Name)
+			sortLines = fmt.Sprintf(`
+    sort, sortErr := sortBy(orders, sortable%s)
+    if sortErr != nil {
+        return 0, nil, sortErr
+    }`, modelName)
+		}
+		funcLines += fmt.Sprintf(`func QueryMany%sByFilterColumns`+suffix+`(`+g.db()+`, %s, size int) (int, []*%s, error) {
+    if page <= 0 {
+        page = 1
+    }
+    if size <= 0 {
+        size = 10
+    }%s
+    names, _, err := project%s(&%s{}, columns)
+    if err != nil {
+        return 0, nil, err
+    }
+    SQL1 := "%s"
+    SQL2 := "select " + names + "%s"
+    where := "%s"
+    args := make([]interface{}, 0)
+    if f != nil {
+        conditions, values, err := f.where()
+        if err != nil {
+            return 0, nil, err
+        }
+        if conditions != "" {
+            %s
+        }
+        args = values
+    }
+    SQL1 = fmt.Sprintf(SQL1, where)
+    SQL2 = fmt.Sprintf(SQL2, where)
+    count := 0
+    err = `+g.queryRow("SQL1")+`, args...).Scan(&count)
+    if err != nil {
+        return 0, nil, t.Error(err)
+    }
+    args = append(args, (page-1)*size, size)
+    rows, err := `+g.query("SQL2")+`, args...)
+    if err != nil {
+        return 0, nil, t.Error(err)
+    }
+    defer rows.Close()
+
+    results := make([]*%s, 0)
+    for rows.Next() {
+        ret := &%s{}
+        _, binds, _ := project%s(ret, columns)
+        err = rows.Scan(binds...)
+        if err != nil {
+            return 0, nil, t.Error(err)
+        }
+        results = append(results, ret)
+    }
+    return count, results, nil
+}
+`, modelName, params, modelName, sortLines, modelName, modelName, g.prepared(SQL1), SQL2, alive, conditions, modelName, modelName, modelName)
+	}
+	return funcLines, nil
+}
+
+func rp(statement *parser.Statement, g *gen, logic *generator.Logic, suffix string) (string, []string) {
+	modelName := generator.FirstUpperCamelCase(statement.TableName.Name)
+	funcLines := ""
+	uniqKeyPairs := generator.UniqKeyPairs(statement)
+	for _, keys := range uniqKeyPairs {
+		fields := make([]string, 0)
+		conditions := make([]string, 0)
+		args := make([]string, 0)
+		for _, col := range keys {
+			conditions = append(conditions, g.ident(col.ColumnName.Name)+" = ?")
+			fieldName := generator.FirstUpperCamelCase(col.ColumnName.Name)
+			arg := "s." + fieldName
+			arg = timeArg(col, arg, g.times)
+			args = append(args, arg)
+			fields = append(fields, fieldName)
+		}
+		if logic != nil {
+			conditions = append(conditions, quote(logic.Alive(g.name)))
+		}
+		SQL := fmt.Sprintf(" from "+g.ident("%s")+" where %s", statement.TableName, strings.Join(conditions, " and "))
+		funcLines += fmt.Sprintf(`func Query%sBy%sColumns`+suffix+`(`+g.db()+`, s *%s, columns []%sColumn) (*%s, error) {
+    if s == nil {
+        return nil, t.Error(fmt.Errorf("pointer can not be nil"))
+    }
+    ret := &%s{}
+    names, binds, err := project%s(ret, columns)
+    if err != nil {
+        return nil, err
+    }
+    SQL := "select " + names + "%s"
+    err = `+g.queryRow("SQL")+`, %s).Scan(binds...)
+    if err != nil {
+        if err != sql.ErrNoRows {
+            return nil, t.Error(err)
+        }
+        return nil, nil
+    }
+    return ret, nil
+}
+`, modelName, strings.Join(fields, ""), modelName, modelName, modelName, modelName, modelName, SQL, strings.Join(args, ", "))
+	}
+
+	where := `where := ""
+    args := make([]interface{}, 0)
+    if s != nil {
+`
+	for _, col := range statement.Columns {
+		fieldName := generator.FirstUpperCamelCase(col.ColumnName.Name)
+		arg := "s." + fieldName
+		arg = timeArg(col, arg, g.times)
+		where += fmt.Sprintf(`        if %s != nil {
+            where += "and `+g.ident("%s")+` = ? "
+            args = append(args, %s)
+        }
+`, "s."+fieldName, col.ColumnName, arg)
+	}
+
+	where += `        where = strings.TrimLeft(where, "and")
+        where = strings.TrimSpace(where)
+        if where != "" {
+            where = "where " + where
+        }
+    }
+    SQL1 = fmt.Sprintf(SQL1, where)
+    SQL2 = fmt.Sprintf(SQL2, where)`
+	if logic != nil {
+		where = strings.Replace(where, `where := ""`, `where := "and `+quote(logic.Alive(g.name))+` "`, 1)
+		where = strings.Replace(where, `        where = strings.TrimLeft(where, "and")
+        where = strings.TrimSpace(where)
+        if where != "" {
+            where = "where " + where
+        }
+    }`, `    }
+    where = strings.TrimLeft(where, "and")
+    where = strings.TrimSpace(where)
+    where = "where " + where`, 1)
+	}
+
+	SQL1 := fmt.Sprintf("select count(*) from "+g.ident("%s")+" %%s", statement.TableName.Name)
+	SQL2 := fmt.Sprintf(" from "+g.ident("%s")+" %%s "+g.page(""), statement.TableName.Name)
+	params := "s *" + modelName + ", columns []" + modelName + "Column, page int"
+	sortLines := ""
+	if g.Sort {
+		params = "s *" + modelName + ", orders []Order, columns []" + modelName + "Column, page int"
+		SQL2 = fmt.Sprintf(" from "+g.ident("%s")+" %%s "+g.page("\" + sort + \""), statement.TableName.Name)
+		sortLines = fmt.Sprintf(`
+    sort, sortErr := sortBy(orders, sortable%s)
+    if sortErr != nil {
+        return 0, nil, sortErr
+    }`, modelName)
+	}
+	funcLines += fmt.Sprintf(`func QueryMany%sColumns`+suffix+`(`+g.db()+`, %s, size int) (int, []*%s, error) {
+    if page <= 0 {
+        page = 1
+    }
+    if size <= 0 {
+        size = 10
+    }%s
+    names, _, err := project%s(&%s{}, columns)
+    if err != nil {
+        return 0, nil, err
+    }
+    SQL1 := "%s"
+    SQL2 := "select " + names + "%s"
+    %s
+    count := 0
+    err = `+g.queryRow("SQL1")+`, args...).Scan(&count)
+    if err != nil {
+        return 0, nil, t.Error(err)
+    }
+    args = append(args, (page-1)*size, size)
+    rows, err := `+g.query("SQL2")+`, args...)
+    if err != nil {
+        if err != sql.ErrNoRows {
+            return 0, nil, t.Error(err)
+        }
+		return 0, nil, nil
+    }
+    defer rows.Close()
+
+    results := make([]*%s, 0)
+    for rows.Next() {
+        ret := &%s{}
+        _, binds, _ := project%s(ret, columns)
+        err = rows.Scan(binds...)
+        if err != nil {
+            return 0, nil, t.Error(err)
+        }
+        results = append(results, ret)
+    }
+    return count, results, nil
+}
+`, modelName, params, modelName, sortLines, modelName, modelName, g.prepared(SQL1), SQL2, where, modelName, modelName, modelName)
+	return funcLines, nil
+}
+
+func cnt(statement *parser.Statement, g *gen, logic *generator.Logic, suffix string) (string, []string) {
+	modelName := generator.FirstUpperCamelCase(statement.TableName.Name)
+	funcLines := fmt.Sprintf(`func Count%s`+suffix+`(`+g.db()+`, s *%s) (int, error) {
+    SQL := "select count(*) from `+g.ident("%s")+` %%s"
+    %s
+    count := 0
+    err := `+g.queryRow("SQL")+`, args...).Scan(&count)
+    if err != nil {
+        return 0, t.Error(err)
+    }
+    return count, nil
+}
+`, modelName, modelName, statement.TableName.Name, modelWhere(statement, g, logic))
+	for _, exists := range []bool{false, true} {
+		keyPairs := generator.IndexKeyPairs(statement)
+		if exists {
+			keyPairs = generator.UniqKeyPairs(statement)
+		}
+		for _, keys := range keyPairs {
+			fields := make([]string, 0)
+			conditions := make([]string, 0)
+			args := make([]string, 0)
+			for _, col := range keys {
+				conditions = append(conditions, g.ident(col.ColumnName.Name)+" = ?")
+				fieldName := generator.FirstUpperCamelCase(col.ColumnName.Name)
+				arg := "s." + fieldName
+				arg = timeArg(col, arg, g.times)
+				args = append(args, arg)
+				fields = append(fields, fieldName)
+			}
+			if logic != nil {
+				conditions = append(conditions, quote(logic.Alive(g.name)))
+			}
+			SQL := fmt.Sprintf("select count(*) from "+g.ident("%s")+" where %s", statement.TableName.Name, strings.Join(conditions, " and "))
+			name, typ, zero, ret := "Count", "int", "0", "count"
+			if exists {
+				name, typ, zero, ret = "Exists", "bool", "false", "count > 0"
+			}
+			funcLines += fmt.Sprintf(`func %s%sBy%s`+suffix+`(`+g.db()+`, s *%s) (%s, error) {
+    if s == nil {
+        return %s, t.Error(fmt.Errorf("pointer can not be nil"))
+    }
+    SQL := "%s"
+    count := 0
+    err := `+g.queryRow("SQL")+`, %s).Scan(&count)
+    if err != nil {
+        return %s, t.Error(err)
+    }
+    return %s, nil
+}
+`, name, modelName, strings.Join(fields, ""), modelName, typ, zero, g.prepared(SQL), strings.Join(args, ", "), zero, ret)
+		}
+	}
+	return funcLines, nil
+}
+
+func agg(statement *parser.Statement, g *gen, logic *generator.Logic, suffix string) (string, []string) {
+	modelName := generator.FirstUpperCamelCase(statement.TableName.Name)
+	funcLines := ""
+	for _, col := range statement.Columns {
+		typ, null, field := "int64", "sql.NullInt64", "Int64"
+		switch col.Type {
+		case "TINYINT", "INT", "BIGINT":
+		case "FLOAT":
+			typ, null, field = "float64", "sql.NullFloat64", "Float64"
+		default:
+			continue
+		}
+		fieldName := generator.FirstUpperCamelCase(col.ColumnName.Name)
+		for _, fn := range []string{"Sum", "Max", "Min"} {
+			funcLines += fmt.Sprintf(`func %s%s%s`+suffix+`(`+g.db()+`, s *%s) (%s, error) {
+    SQL := "select %s(`+g.ident("%s")+`) from `+g.ident("%s")+` %%s"
+    %s
+    ret := %s{}
+    err := `+g.queryRow("SQL")+`, args...).Scan(&ret)
+    if err != nil {
+        return 0, t.Error(err)
+    }
+    return ret.%s, nil
+}
+`, fn, modelName, fieldName, modelName, typ, strings.ToLower(fn), col.ColumnName.Name, statement.TableName.Name, modelWhere(statement, g, logic), null, field)
+		}
+	}
+	return funcLines, nil
+}
+
+func each(statement *parser.Statement, g *gen, logic *generator.Logic, suffix string) (string, []string) {
+	modelName := generator.FirstUpperCamelCase(statement.TableName.Name)
+	names := make([]string, 0)
+	binds := make([]string, 0)
+	for _, col := range statement.Columns {
+		names = append(names, g.ident(col.ColumnName.Name))
+		binds = append(binds, "&ret."+generator.FirstUpperCamelCase(col.ColumnName.Name))
+	}
+	SQL := fmt.Sprintf("select %s from "+g.ident("%s")+" %%s", strings.Join(names, ", "), statement.TableName.Name)
+	where := modelWhere(statement, g, logic)
+	funcLines := fmt.Sprintf(`func Each%s`+suffix+`(`+g.db()+`, s *%s, fn func(*%s) error) error {
+    SQL := "%s"
+    %s
+    rows, err := `+g.query("SQL")+`, args...)
+    if err != nil {
+        return t.Error(err)
+    }
+    defer rows.Close()
+    for rows.Next() {
+        ret := &%s{}
+        err = rows.Scan(%s)
+        if err != nil {
+            return t.Error(err)
+        }
+        err = fn(ret)
+        if err != nil {
+            return err
+        }
+    }
+    err = rows.Err()
+    if err != nil {
+        return t.Error(err)
+    }
+    return nil
+}
+func Iter%s`+suffix+`(`+g.db()+`, s *%s) iter.Seq2[*%s, error] {
+    return func(yield func(*%s, error) bool) {
+        SQL := "%s"
+        %s
+        rows, err := `+g.query("SQL")+`, args...)
+        if err != nil {
+            yield(nil, t.Error(err))
+            return
+        }
+        defer rows.Close()
+        for rows.Next() {
+            ret := &%s{}
+            err = rows.Scan(%s)
+            if err != nil {
+                yield(nil, t.Error(err))
+                return
+            }
+            if !yield(ret, nil) {
+                return
+            }
+        }
+        err = rows.Err()
+        if err != nil {
+            yield(nil, t.Error(err))
+        }
+    }
+}
+`, modelName, modelName, modelName, g.prepared(SQL), where, modelName, strings.Join(binds, ", "),
+		modelName, modelName, modelName, modelName, g.prepared(SQL), where, modelName, strings.Join(binds, ", "))
+	return funcLines, []string{"iter"}
+}
+
+// modelWhere generates the where clause of the non-nil fields of s, the same as the one of QueryManyX.
+func modelWhere(statement *parser.Statement, g *gen, logic *generator.Logic) string {
+	where := `where := ""
+    args := make([]interface{}, 0)
+    if s != nil {
+`
+	for _, col := range statement.Columns {
+		fieldName := generator.FirstUpperCamelCase(col.ColumnName.Name)
+		arg := "s." + fieldName
+		arg = timeArg(col, arg, g.times)
+		where += fmt.Sprintf(`        if %s != nil {
+            where += "and `+g.ident("%s")+` = ? "
+            args = append(args, %s)
+        }
+`, "s."+fieldName, col.ColumnName, arg)
+	}
+	where += `        where = strings.TrimLeft(where, "and")
+        where = strings.TrimSpace(where)
+        if where != "" {
+            where = "where " + where
+        }
+    }
+    SQL = fmt.Sprintf(SQL, where)`
+	if logic != nil {
+		where = strings.Replace(where, `where := ""`, `where := "and `+quote(logic.Alive(g.name))+` "`, 1)
+		where = strings.Replace(where, `        where = strings.TrimLeft(where, "and")
+        where = strings.TrimSpace(where)
+        if where != "" {
+            where = "where " + where
+        }
+    }`, `    }
+    where = strings.TrimLeft(where, "and")
+    where = strings.TrimSpace(where)
+    where = "where " + where`, 1)
+	}
+	return where
+}
+
+func d(statement *parser.Statement, g *gen, logic *generator.Logic) (string, []string) {
+	modelName := generator.FirstUpperCamelCase(statement.TableName.Name)
+	funcLines := ""
+	uniqKeyPairs := generator.UniqKeyPairs(statement)
+	for _, keys := range uniqKeyPairs {
+		fields := make([]string, 0)
+		conditions := make([]string, 0)
+		args := make([]string, 0)
+		for _, col := range keys {
+			fieldName := generator.FirstUpperCamelCase(col.ColumnName.Name)
+			conditions = append(conditions, g.ident(col.ColumnName.Name)+" = ?")
+			arg := "s." + fieldName
+			arg = timeArg(col, arg, g.times)
+			args = append(args, arg)
+			fields = append(fields, fieldName)
+		}
+		SQL := ""
+		if logic != nil {
+			SQL = fmt.Sprintf("update "+g.ident("%s")+" set "+g.ident("%s")+" = %s where %s", statement.TableName, logic.Column, quote(logic.Deleted), strings.Join(conditions, " and "))
+		} else {
+			SQL = fmt.Sprintf("delete from "+g.ident("%s")+" where %s", statement.TableName, strings.Join(conditions, " and "))
+		}
+		funcTemplate := `func %s%sBy%s(` + g.db() + `, s *%s) (int64, error) {
+    if s == nil {
+        return 0, t.Error(fmt.Errorf("pointer can not be nil"))
+    }
+    SQL := "%s"
+    ret, err := ` + g.exec("SQL") + `, %s)
+    if err != nil {
+        return 0, t.Error(err)
+    }
+    count, err := ret.RowsAffected()
+    if err != nil {
+        return 0, t.Error(err)
+    }
+    return count, nil
+}
+`
+		funcLines += fmt.Sprintf(funcTemplate, g.audited("Delete"), modelName, strings.Join(fields, ""), modelName, g.prepared(SQL), strings.Join(args, ", "))
+		if logic != nil {
+			if logic.Undeleted != "" {
+				UNSQL := fmt.Sprintf("update "+g.ident("%s")+" set "+g.ident("%s")+" = %s where %s", statement.TableName, logic.Column, quote(logic.Undeleted), strings.Join(conditions, " and "))
+				funcLines += fmt.Sprintf(funcTemplate, g.audited("UnDelete"), modelName, strings.Join(fields, ""), modelName, g.prepared(UNSQL), strings.Join(args, ", "))
+			}
+		}
+
+	}
+	return funcLines, nil
+}
+
+func um(statement *parser.Statement, g *gen, logic *generator.Logic) (string, []string) {
+	modelName := generator.FirstUpperCamelCase(statement.TableName.Name)
+	funcLines := ""
+	for _, keys := range generator.IndexKeyPairs(statement) {
+		version := generator.VersionColumn(statement)
+		if version != nil && contains(keys, version) {
+			version = nil
+		}
+		set := `set := ""
+    args := make([]interface{}, 0)
+    `
+		for _, col := range statement.Columns {
+			if col.AutoIncrement || col.CurrentTimestamp {
+				continue
+			}
+			if contains(keys, col) || col == version {
+				continue
+			}
+			fieldName := generator.FirstUpperCamelCase(col.ColumnName.Name)
+			arg := "s." + fieldName
+			arg = timeArg(col, arg, g.times)
+			set += fmt.Sprintf(`if %s != nil {
+        set += ", `+g.ident("%s")+` = ? "
+        args = append(args, %s)
+    }
+    `, "s."+fieldName, col.ColumnName, arg)
+		}
+		set += `set = strings.TrimLeft(set, ",")
+    set = strings.TrimSpace(set)
+    if set == "" {
+        return 0, t.Error(fmt.Errorf("all field is nil"))
+    }
+    SQL = fmt.Sprintf(SQL, set)`
+		fields, conditions, args, guard := indexConditions(keys, g)
+		if logic != nil {
+			conditions = append(conditions, quote(logic.Alive(g.name)))
+		}
+		SQL := fmt.Sprintf("update "+g.ident("%s")+" set %%s where %s", statement.TableName.Name, strings.Join(conditions, " and "))
+		if version != nil {
+			SQL = fmt.Sprintf("update "+g.ident("%s")+" set %%s, "+g.ident("%s")+" = "+g.ident("%s")+" + 1 where %s", statement.TableName.Name, version.ColumnName.Name, version.ColumnName.Name, strings.Join(conditions, " and "))
+		}
+		funcLines += fmt.Sprintf(`func UpdateMany%sBy%s(`+g.db()+`, s *%s, limit int) (int64, error) {
+    if s == nil {
+        return 0, t.Error(fmt.Errorf("pointer can not be nil"))
+    }
+    if %s {
+        return 0, t.Error(fmt.Errorf("index field can not be nil"))
+    }
+    SQL := "%s"
+    %s
+    args = append(args, %s)
+%s    ret, err := `+g.exec("SQL")+`, args...)
+    if err != nil {
         return 0, t.Error(err)
     }
-    count, err := ret.RowsAffected()
+    return ret.RowsAffected()
+}
+`, modelName, strings.Join(fields, ""), modelName, guard, g.prepared(SQL), set, strings.Join(args, ", "), limitLines(statement, g, conditions, args))
+	}
+	return funcLines, nil
+}
+
+func dm(statement *parser.Statement, g *gen, logic *generator.Logic) (string, []string) {
+	modelName := generator.FirstUpperCamelCase(statement.TableName.Name)
+	funcLines := ""
+	for _, keys := range generator.IndexKeyPairs(statement) {
+		fields, conditions, args, guard := indexConditions(keys, g)
+		if logic != nil {
+			conditions = append(conditions, quote(logic.Alive(g.name)))
+		}
+		SQL := fmt.Sprintf("delete from "+g.ident("%s")+" where %s", statement.TableName, strings.Join(conditions, " and "))
+		if logic != nil {
+			SQL = fmt.Sprintf("update "+g.ident("%s")+" set "+g.ident("%s")+" = %s where %s", statement.TableName, logic.Column, quote(logic.Deleted), strings.Join(conditions, " and "))
+		}
+		funcLines += fmt.Sprintf(`func DeleteMany%sBy%s(`+g.db()+`, s *%s, limit int) (int64, error) {
+    if s == nil {
+        return 0, t.Error(fmt.Errorf("pointer can not be nil"))
+    }
+    if %s {
+        return 0, t.Error(fmt.Errorf("index field can not be nil"))
+    }
+    SQL := "%s"
+    args := []interface{}{%s}
+%s    ret, err := `+g.exec("SQL")+`, args...)
     if err != nil {
         return 0, t.Error(err)
     }
-    return count, nil
+    return ret.RowsAffected()
+}
+`, modelName, strings.Join(fields, ""), modelName, guard, g.prepared(SQL), strings.Join(args, ", "), limitLines(statement, g, conditions, args))
+	}
+	return funcLines, nil
 }
+
+// indexConditions returns the conditions of an index with their arguments, and the guard which refuses
+// a bulk statement when a field of the index is nil.
+func indexConditions(keys []*parser.ColumnDefinition, g *gen) ([]string, []string, []string, string) {
+	fields := make([]string, 0)
+	conditions := make([]string, 0)
+	args := make([]string, 0)
+	guards := make([]string, 0)
+	for _, col := range keys {
+		fieldName := generator.FirstUpperCamelCase(col.ColumnName.Name)
+		conditions = append(conditions, g.ident(col.ColumnName.Name)+" = ?")
+		arg := "s." + fieldName
+		arg = timeArg(col, arg, g.times)
+		args = append(args, arg)
+		fields = append(fields, fieldName)
+		guards = append(guards, "s."+fieldName+" == nil")
+	}
+	return fields, conditions, args, strings.Join(guards, " || ")
+}
+
+// limitLines caps the rows of a bulk statement, mysql has update and delete with limit, the other dialects
+// limit a subquery on the primary key and need a table with a single column primary key for it.
+func limitLines(statement *parser.Statement, g *gen, conditions []string, args []string) string {
+	if g.dialect() == MySQL {
+		return `    if limit > 0 {
+        SQL += " limit ?"
+        args = append(args, limit)
+    }
 `
-		funcLines += fmt.Sprintf(funcTemplate, "", modelName, strings.Join(fields, ""), modelName, SQL, strings.Join(args, ", "))
-		if logicDelete {
-			if unDeleteValue, ok := unDeleteMap[logicValue]; ok {
-				UNSQL := fmt.Sprintf("update `%s` set `%s` = %s where %s", statement.TableName, logicCol, unDeleteValue, strings.Join(conditions, " and "))
-				funcLines += fmt.Sprintf(funcTemplate, "Un", modelName, strings.Join(fields, ""), modelName, UNSQL, strings.Join(args, ", "))
-			}
+	}
+	primaryKeys := getPrimaryKeys(statement)
+	if len(primaryKeys) != 1 {
+		return `    if limit > 0 {
+        return 0, t.Error(fmt.Errorf("limit needs a single column primary key"))
+    }
+`
+	}
+	key := g.ident(primaryKeys[0].ColumnName.Name)
+	return fmt.Sprintf(`    if limit > 0 {
+        SQL += " and %s in (select %s from `+g.ident("%s")+` where %s order by %s `+g.limit()+`)"
+        args = append(args, %s, limit)
+    }
+`, key, key, statement.TableName.Name, strings.Join(conditions, " and "), key, strings.Join(args, ", "))
+}
+
+var filterTypes = map[string]string{
+	"TINYINT":   "OrderedFieldFilter[int64]",
+	"INT":       "OrderedFieldFilter[int64]",
+	"BIGINT":    "OrderedFieldFilter[int64]",
+	"FLOAT":     "OrderedFieldFilter[float64]",
+	"CHAR":      "StringFieldFilter",
+	"VARCHAR":   "StringFieldFilter",
+	"TEXT":      "StringFieldFilter",
+	"DATE":      "OrderedFieldFilter[n.Time]",
+	"DATETIME":  "OrderedFieldFilter[n.Time]",
+	"TIMESTAMP": "OrderedFieldFilter[n.Time]",
+	"default":   "FieldFilter[interface{}]",
+}
+
+// filterLines are the operator types shared by every generated XFilter, column names are
+// written by the generator and every value is bound as an argument.
+func (g *gen) filterLines() string {
+	return `
+
+type FieldFilter[T any] struct {
+    Eq     *T    ` + "`json:\"eq,omitempty\"`" + `
+    Ne     *T    ` + "`json:\"ne,omitempty\"`" + `
+    In     []T   ` + "`json:\"in,omitempty\"`" + `
+    NotIn  []T   ` + "`json:\"not_in,omitempty\"`" + `
+    IsNull *bool ` + "`json:\"is_null,omitempty\"`" + `
+}
+
+func (f *FieldFilter[T]) where(column string) ([]string, []interface{}, error) {
+    conditions := make([]string, 0)
+    args := make([]interface{}, 0)
+    if f.Eq != nil {
+        conditions = append(conditions, column+" = ?")
+        args = append(args, *f.Eq)
+    }
+    if f.Ne != nil {
+        conditions = append(conditions, column+" <> ?")
+        args = append(args, *f.Ne)
+    }
+    if f.In != nil {
+        if len(f.In) == 0 {
+            conditions = append(conditions, "1 = 0")
+        } else {
+            conditions = append(conditions, column+" in (?"+strings.Repeat(", ?", len(f.In)-1)+")")
+            for _, v := range f.In {
+                args = append(args, v)
+            }
+        }
+    }
+    if len(f.NotIn) != 0 {
+        conditions = append(conditions, column+" not in (?"+strings.Repeat(", ?", len(f.NotIn)-1)+")")
+        for _, v := range f.NotIn {
+            args = append(args, v)
+        }
+    }
+    if f.IsNull != nil {
+        if *f.IsNull {
+            conditions = append(conditions, column+" is null")
+        } else {
+            conditions = append(conditions, column+" is not null")
+        }
+    }
+    return conditions, args, nil
+}
+
+type OrderedFieldFilter[T any] struct {
+    FieldFilter[T]
+    Gt      *T  ` + "`json:\"gt,omitempty\"`" + `
+    Gte     *T  ` + "`json:\"gte,omitempty\"`" + `
+    Lt      *T  ` + "`json:\"lt,omitempty\"`" + `
+    Lte     *T  ` + "`json:\"lte,omitempty\"`" + `
+    Between []T ` + "`json:\"between,omitempty\"`" + `
+}
+
+func (f *OrderedFieldFilter[T]) where(column string) ([]string, []interface{}, error) {
+    conditions, args, err := f.FieldFilter.where(column)
+    if err != nil {
+        return nil, nil, err
+    }
+    if f.Gt != nil {
+        conditions = append(conditions, column+" > ?")
+        args = append(args, *f.Gt)
+    }
+    if f.Gte != nil {
+        conditions = append(conditions, column+" >= ?")
+        args = append(args, *f.Gte)
+    }
+    if f.Lt != nil {
+        conditions = append(conditions, column+" < ?")
+        args = append(args, *f.Lt)
+    }
+    if f.Lte != nil {
+        conditions = append(conditions, column+" <= ?")
+        args = append(args, *f.Lte)
+    }
+    if f.Between != nil {
+        if len(f.Between) != 2 {
+            return nil, nil, &ArgumentError{Message: fmt.Sprintf("between of %s requires 2 values", column)}
+        }
+        conditions = append(conditions, column+" between ? and ?")
+        args = append(args, f.Between[0], f.Between[1])
+    }
+    return conditions, args, nil
+}
+
+type StringFieldFilter struct {
+    FieldFilter[string]
+    Like   *string ` + "`json:\"like,omitempty\"`" + `
+    Prefix *string ` + "`json:\"prefix,omitempty\"`" + `
+}
+
+var likeEscaper = strings.NewReplacer(` + g.likeEscapes() + `)
+
+func (f *StringFieldFilter) where(column string) ([]string, []interface{}, error) {
+    conditions, args, err := f.FieldFilter.where(column)
+    if err != nil {
+        return nil, nil, err
+    }
+    if f.Like != nil {
+        conditions = append(conditions, column+` + g.like() + `)
+        args = append(args, *f.Like)
+    }
+    if f.Prefix != nil {
+        conditions = append(conditions, column+` + g.like() + `)
+        args = append(args, likeEscaper.Replace(*f.Prefix)+"%")
+    }
+    return conditions, args, nil
+}
+
+type filterBuilder struct {
+    conditions []string
+    groups     []string
+    args       []interface{}
+    err        error
+}
+
+func (b *filterBuilder) add(column string, f interface {
+    where(column string) ([]string, []interface{}, error)
+}) {
+    if b.err != nil {
+        return
+    }
+    conditions, args, err := f.where(column)
+    if err != nil {
+        b.err = err
+        return
+    }
+    b.conditions = append(b.conditions, conditions...)
+    b.args = append(b.args, args...)
+}
+
+func (b *filterBuilder) or(where string, args []interface{}, err error) {
+    if b.err != nil {
+        return
+    }
+    if err != nil {
+        b.err = err
+        return
+    }
+    if where == "" {
+        where = "1 = 1"
+    }
+    b.groups = append(b.groups, "("+where+")")
+    b.args = append(b.args, args...)
+}
+
+func (b *filterBuilder) build() (string, []interface{}, error) {
+    if b.err != nil {
+        return "", nil, b.err
+    }
+    conditions := b.conditions
+    if len(b.groups) != 0 {
+        conditions = append(conditions, "("+strings.Join(b.groups, " or ")+")")
+    }
+    return strings.Join(conditions, " and "), b.args, nil
+}`
+}
+
+// filterStruct generates the XFilter type of a table and its where builder.
+func filterStruct(statement *parser.Statement, g *gen) (string, []string) {
+	modelName := generator.FirstUpperCamelCase(statement.TableName.Name)
+	fields := make([]string, 0)
+	adds := make([]string, 0)
+	imports := make([]string, 0)
+	for _, col := range statement.Columns {
+		typ, ok := filterTypes[col.Type]
+		if !ok {
+			typ = filterTypes["default"]
 		}
+		if strings.Contains(typ, "n.Time") {
+			imports = append(imports, "github.com/stella-go/siu/t/n")
+		}
+		fieldName := generator.FirstUpperCamelCase(col.ColumnName.Name)
+		fields = append(fields, fmt.Sprintf("    %s *%s `json:\"%s,omitempty\"`", fieldName, typ, generator.ToSnakeCase(col.ColumnName.Name)))
+		adds = append(adds, fmt.Sprintf(`    if f.%s != nil {
+        b.add("`+g.ident("%s")+`", f.%s)
+    }
+`, fieldName, col.ColumnName.Name, fieldName))
+	}
+	funcLines := fmt.Sprintf(`type %sFilter struct {
+%s
+    Or []*%sFilter `+"`json:\"or,omitempty\"`"+`
+}
+
+func (f *%sFilter) where() (string, []interface{}, error) {
+    b := &filterBuilder{}
+%s    for _, or := range f.Or {
+        if or != nil {
+            b.or(or.where())
+        }
+    }
+    return b.build()
+}
+`, modelName, strings.Join(fields, "\n"), modelName, modelName, strings.Join(adds, ""))
+	return funcLines, imports
+}
 
+// orderLines are the sort types shared by every generated QueryManyX, columns are checked against
+// the sortable map of the table so the order by never carries user input.
+func (g *gen) orderLines() string {
+	unsorted := ""
+	if g.dialect() == SQLServer {
+		unsorted = "order by (select null) "
 	}
-	return funcLines, nil
+	return `
+
+type Order struct {
+    Column string ` + "`json:\"column\"`" + `
+    Desc   bool   ` + "`json:\"desc\"`" + `
 }
 
-func getIndexKeyPairs(statement *parser.Statement) [][]*parser.ColumnDefinition {
-	keyPairs := make([][]*parser.ColumnDefinition, 0)
-	for _, pair := range statement.IndexKeyPairs {
-		p := make([]*parser.ColumnDefinition, 0)
-		for _, k := range pair {
-			for _, c := range statement.Columns {
-				if c.ColumnName.Name == k.Name {
-					p = append(p, c)
-					break
-				}
-			}
-		}
-		keyPairs = append(keyPairs, p)
+// ParseOrders parses a comma separated list of columns, a column prefixed with - is sorted descending.
+func ParseOrders(sort string) []Order {
+    orders := make([]Order, 0)
+    for _, column := range strings.Split(sort, ",") {
+        column = strings.TrimSpace(column)
+        desc := strings.HasPrefix(column, "-")
+        column = strings.TrimLeft(column, "+-")
+        if column == "" {
+            continue
+        }
+        orders = append(orders, Order{Column: column, Desc: desc})
+    }
+    return orders
+}
+
+func sortBy(orders []Order, sortable map[string]string) (string, error) {
+    if len(orders) == 0 {
+        return "` + unsorted + `", nil
+    }
+    columns := make([]string, 0, len(orders))
+    for _, order := range orders {
+        column, ok := sortable[order.Column]
+        if !ok {
+            return "", &ArgumentError{Message: fmt.Sprintf("column %s can not be sorted", order.Column)}
+        }
+        if order.Desc {
+            column += " desc"
+        }
+        columns = append(columns, column)
+    }
+    return "order by " + strings.Join(columns, ", ") + " ", nil
+}`
+}
+
+// argumentLines are the error of the orders, columns, cursors and filters the queries refuse, the routers
+// answer it with 400 and its message.
+const argumentLines = `
+
+type ArgumentError struct {
+    Message string
+}
+
+func (e *ArgumentError) Error() string {
+    return e.Message
+}`
+
+// sortableMap generates the whitelist of the columns a table may be sorted by.
+func sortableMap(statement *parser.Statement, g *gen) string {
+	modelName := generator.FirstUpperCamelCase(statement.TableName.Name)
+	entries := make([]string, 0)
+	for _, col := range generator.SortableColumns(statement) {
+		entries = append(entries, fmt.Sprintf("    \"%s\": \""+g.ident("%s")+"\",", generator.ToSnakeCase(col.ColumnName.Name), col.ColumnName.Name))
 	}
-	return keyPairs
+	return fmt.Sprintf("var sortable%s = map[string]string{\n%s\n}\n", modelName, strings.Join(entries, "\n"))
 }
 
-func getUniqKeyPairs(statement *parser.Statement) [][]*parser.ColumnDefinition {
-	keyPairs := make([][]*parser.ColumnDefinition, 0)
+// projectionLines parse the columns asked by the callers of the projected queries.
+const projectionLines = `
+
+// ParseColumns parses a comma separated list of columns.
+func ParseColumns[C ~string](columns string) []C {
+    ret := make([]C, 0)
+    for _, column := range strings.Split(columns, ",") {
+        column = strings.TrimSpace(column)
+        if column != "" {
+            ret = append(ret, C(column))
+        }
+    }
+    return ret
+}`
+
+// columnsStruct generates the column constants of a table, the columns listed by default
+// which leave out the large text and blob columns, and the projection of the selected columns onto a model.
+func columnsStruct(statement *parser.Statement, g *gen) string {
+	modelName := generator.FirstUpperCamelCase(statement.TableName.Name)
+	constants := make([]string, 0)
+	list := make([]string, 0)
+	cases := make([]string, 0)
+	for _, col := range generator.ListColumns(statement) {
+		list = append(list, modelName+"Column"+generator.FirstUpperCamelCase(col.ColumnName.Name))
+	}
 	for _, col := range statement.Columns {
-		if col.PrimaryKey || col.UniqueKey {
-			keyPairs = append(keyPairs, []*parser.ColumnDefinition{col})
+		fieldName := generator.FirstUpperCamelCase(col.ColumnName.Name)
+		constants = append(constants, fmt.Sprintf("    %sColumn%s %sColumn = \"%s\"", modelName, fieldName, modelName, generator.ToSnakeCase(col.ColumnName.Name)))
+		cases = append(cases, fmt.Sprintf(`        case %sColumn%s:
+            names = append(names, "`+g.ident("%s")+`")
+            binds = append(binds, &ret.%s)`, modelName, fieldName, col.ColumnName.Name, fieldName))
+	}
+	return fmt.Sprintf(`type %sColumn string
+
+const (
+%s
+)
+
+var %sListColumns = []%sColumn{%s}
+
+func project%s(ret *%s, columns []%sColumn) (string, []interface{}, error) {
+    if len(columns) == 0 {
+        return "", nil, &ArgumentError{Message: "no column selected"}
+    }
+    names := make([]string, 0, len(columns))
+    binds := make([]interface{}, 0, len(columns))
+    for _, column := range columns {
+        switch column {
+%s
+        default:
+            return "", nil, &ArgumentError{Message: fmt.Sprintf("unknown column %%s", column)}
+        }
+    }
+    return strings.Join(names, ", "), binds, nil
+}
+`, modelName, strings.Join(constants, "\n"), modelName, modelName, strings.Join(list, ", "), modelName, modelName, modelName, strings.Join(cases, "\n"))
+}
+
+// quote escapes a SQL fragment for a Go string literal.
+func quote(s string) string {
+	return strings.ReplaceAll(s, `"`, `\"`)
+}
+
+type Order struct {
+	FuncSuffix string
+	Statement  string
+	Columns    []*parser.ColumnDefinition
+	Desc       bool
+}
+
+func getOrders(statement *parser.Statement, g *gen) []*Order {
+	asc, desc := g.Asc, g.Desc
+	orders := []*Order{{}}
+	if asc != "" {
+		columns := make([]*parser.ColumnDefinition, 0)
+		if (strings.HasPrefix(asc, "\"") && strings.HasSuffix(asc, "\"")) || (strings.HasPrefix(asc, "'") && strings.HasSuffix(asc, "'")) {
+			asc = asc[1 : len(asc)-1]
+		}
+		columnNames := strings.Split(asc, ",")
+		for _, name := range columnNames {
+			for _, c := range statement.Columns {
+				if c.ColumnName.Name != name {
+					continue
+				}
+				columns = append(columns, c)
+			}
+		}
+		if len(columns) != 0 {
+			s1 := make([]string, 0)
+			s2 := make([]string, 0)
+			for _, c := range columns {
+				s1 = append(s1, generator.FirstUpperCamelCase(c.ColumnName.Name))
+				s2 = append(s2, g.ident(c.ColumnName.Name))
+			}
+			orders = append(orders, &Order{FuncSuffix: fmt.Sprintf("OrderBy%s", strings.Join(s1, "")), Statement: fmt.Sprintf("order by %s ", strings.Join(s2, ", ")), Columns: columns})
 		}
 	}
-	for _, pair := range statement.PrimaryKeyPairs {
-		p := make([]*parser.ColumnDefinition, 0)
-		for _, k := range pair {
+	if desc != "" {
+		columns := make([]*parser.ColumnDefinition, 0)
+		if (strings.HasPrefix(desc, "\"") && strings.HasSuffix(desc, "\"")) || (strings.HasPrefix(desc, "'") && strings.HasSuffix(desc, "'")) {
+			desc = desc[1 : len(desc)-1]
+		}
+		columnNames := strings.Split(desc, ",")
+		for _, name := range columnNames {
 			for _, c := range statement.Columns {
-				if strings.EqualFold(c.ColumnName.Name, k.Name) {
-					p = append(p, c)
-					break
+				if c.ColumnName.Name != name {
+					continue
 				}
+				columns = append(columns, c)
 			}
 		}
-		if len(p) != 0 {
-			keyPairs = append(keyPairs, p)
+		if len(columns) != 0 {
+			s1 := make([]string, 0)
+			s2 := make([]string, 0)
+			for _, c := range columns {
+				s1 = append(s1, generator.FirstUpperCamelCase(c.ColumnName.Name))
+				s2 = append(s2, g.ident(c.ColumnName.Name))
+			}
+			orders = append(orders, &Order{FuncSuffix: fmt.Sprintf("OrderBy%sDesc", strings.Join(s1, "")), Statement: fmt.Sprintf("order by %s desc ", strings.Join(s2, ", ")), Columns: columns, Desc: true})
+		}
+	}
+	return orders
+}
+
+func getPrimaryKeys(statement *parser.Statement) []*parser.ColumnDefinition {
+	for _, col := range statement.Columns {
+		if col.PrimaryKey {
+			return []*parser.ColumnDefinition{col}
 		}
 	}
-	for _, pair := range statement.UniqKeyPairs {
+	for _, pair := range statement.PrimaryKeyPairs {
 		p := make([]*parser.ColumnDefinition, 0)
 		for _, k := range pair {
 			for _, c := range statement.Columns {
@@ -581,10 +2365,10 @@ func getUniqKeyPairs(statement *parser.Statement) [][]*parser.ColumnDefinition {
 			}
 		}
 		if len(p) != 0 {
-			keyPairs = append(keyPairs, p)
+			return p
 		}
 	}
-	return keyPairs
+	return nil
 }
 
 func contains(arr []*parser.ColumnDefinition, s *parser.ColumnDefinition) bool {
@@ -595,3 +2379,74 @@ func contains(arr []*parser.ColumnDefinition, s *parser.ColumnDefinition) bool {
 	}
 	return false
 }
+
+// timeArg adapts the argument of a time column to the precision and the time zone of the column.
+func timeArg(col *parser.ColumnDefinition, arg string, times *generator.TimePolicy) string {
+	if !generator.IsTime(col) {
+		return arg
+	}
+	if loc := times.Location(col); loc != "" {
+		arg = "inZone(" + arg + ", " + loc + ")"
+	}
+	if times.Precision(col) == "d" {
+		return "truncateDay(" + arg + ")"
+	}
+	if duration := times.Duration(col); duration != "" {
+		arg = arg + ".Round(" + duration + ")"
+	}
+	return arg
+}
+
+const inZoneLines = `
+
+// inZone moves a time into the time zone of its column.
+func inZone(v *n.Time, loc *time.Location) *n.Time {
+    if v == nil {
+        return nil
+    }
+    ret := n.Time(time.Time(*v).In(loc))
+    return &ret
+}`
+
+const truncateDayLines = `
+
+// truncateDay drops the time of day of a DATE value.
+func truncateDay(v *n.Time) *n.Time {
+    if v == nil {
+        return nil
+    }
+    t := time.Time(*v)
+    ret := n.Time(time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location()))
+    return &ret
+}`
+
+// timeHelpers returns the helpers of timeArg the generated functions call.
+func timeHelpers(funcLines string) string {
+	helpers := ""
+	if strings.Contains(funcLines, "inZone(") {
+		helpers += inZoneLines
+	}
+	if strings.Contains(funcLines, "truncateDay(") {
+		helpers += truncateDayLines
+	}
+	return helpers
+}
+
+// versionZero sets a nil version of s to 0 before it is inserted, a null version never matches the
+// version the updates check.
+func versionZero(statement *parser.Statement, indent string) (string, []string) {
+	version := generator.VersionColumn(statement)
+	if version == nil {
+		return "", nil
+	}
+	typ := "n.Int"
+	if version.Type == "BIGINT" {
+		typ = "n.Int64"
+	}
+	fieldName := generator.FirstUpperCamelCase(version.ColumnName.Name)
+	return fmt.Sprintf(`%[1]sif s.%[2]s == nil {
+%[1]s    version := %[3]s(0)
+%[1]s    s.%[2]s = &version
+%[1]s}
+`, indent, fieldName, typ), []string{"github.com/stella-go/siu/t/n"}
+}
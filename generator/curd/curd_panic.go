@@ -25,50 +25,215 @@ import (
 	"github.com/stella-go/stella/version"
 )
 
-func GeneratePanic(pkg string, statements []*parser.Statement, banner bool, logic string, asc string, desc string, round string) string {
+func GeneratePanic(pkg string, statements []*parser.Statement, o *Options) string {
 	importsMap := make(map[string]common.Void)
 	importsMap["database/sql"] = common.Null
 	importsMap["fmt"] = common.Null
 	importsMap["strings"] = common.Null
 	importsMap["github.com/stella-go/siu/t"] = common.Null
 	functions := make([]string, 0)
-	switch round {
-	case "s":
-		round = "time.Second"
-	case "ms", "milli":
-		round = "time.Millisecond"
-	case "μs", "us", "micro":
-		round = "time.Microsecond"
-	default:
-		round = ""
-	}
-	if round != "" {
-		importsMap["time"] = common.Null
+	g := newGen(o)
+	if o.Ctx || o.Tx || o.Prepare || o.Audit {
+		importsMap["context"] = common.Null
+	}
+	if o.Audit {
+		importsMap["encoding/json"] = common.Null
+	}
+	for _, i := range dialectImports[o.Dialect] {
+		importsMap[i] = common.Null
+	}
+	if o.Keyset {
+		importsMap["bytes"] = common.Null
+		importsMap["encoding/base64"] = common.Null
+		importsMap["encoding/json"] = common.Null
+	}
+	versioned := false
+	for _, statement := range statements {
+		if generator.VersionColumn(statement) != nil {
+			versioned = true
+		}
+	}
+	if versioned {
+		importsMap["errors"] = common.Null
 	}
 	for _, statement := range statements {
 		functions = append(functions, "// ==================== "+generator.FirstUpperCamelCase(statement.TableName.Name)+" ====================")
-		function, imports := c_panic(statement, round)
+		policy := generator.ParseLogic(statement, o.Logic)
+		function, imports := c_panic(statement, g)
+		functions = append(functions, function)
+		for _, i := range imports {
+			importsMap[i] = common.Null
+		}
+
+		if !o.Audit {
+			function, imports = cm_panic(statement, g)
+			functions = append(functions, function)
+			for _, i := range imports {
+				importsMap[i] = common.Null
+			}
+		}
+
+		function, imports = ups_panic(statement, g)
 		functions = append(functions, function)
 		for _, i := range imports {
 			importsMap[i] = common.Null
 		}
 
-		function, imports = u_panic(statement, round)
+		function, imports = u_panic(statement, g)
 		functions = append(functions, function)
 		for _, i := range imports {
 			importsMap[i] = common.Null
 		}
 
-		function, imports = r_panic(statement, asc, desc, round)
+		if o.Mask {
+			if !o.Projection {
+				functions = append(functions, columnsStruct(statement, g))
+			}
+			function, imports = uf_panic(statement, g)
+			functions = append(functions, function)
+			for _, i := range imports {
+				importsMap[i] = common.Null
+			}
+		}
+
+		if !o.Audit {
+			function, imports = um_panic(statement, g, policy)
+			functions = append(functions, function)
+			for _, i := range imports {
+				importsMap[i] = common.Null
+			}
+		}
+
+		if o.Sort {
+			functions = append(functions, sortableMap(statement, g))
+		}
+		function, imports = r_panic(statement, g, policy, "")
+		functions = append(functions, function)
+		for _, i := range imports {
+			importsMap[i] = common.Null
+		}
+		if policy != nil {
+			function, imports = r_panic(statement, g, nil, "WithDeleted")
+			functions = append(functions, function)
+			for _, i := range imports {
+				importsMap[i] = common.Null
+			}
+		}
+		if o.Projection {
+			functions = append(functions, columnsStruct(statement, g))
+			function, imports = rp_panic(statement, g, policy, "")
+			functions = append(functions, function)
+			for _, i := range imports {
+				importsMap[i] = common.Null
+			}
+			if policy != nil {
+				function, imports = rp_panic(statement, g, nil, "WithDeleted")
+				functions = append(functions, function)
+				for _, i := range imports {
+					importsMap[i] = common.Null
+				}
+			}
+		}
+		if o.Keyset {
+			function, imports = ra_panic(statement, g, policy, "")
+			functions = append(functions, function)
+			for _, i := range imports {
+				importsMap[i] = common.Null
+			}
+			if policy != nil {
+				function, imports = ra_panic(statement, g, nil, "WithDeleted")
+				functions = append(functions, function)
+				for _, i := range imports {
+					importsMap[i] = common.Null
+				}
+			}
+		}
+		if o.Filter {
+			function, imports = filterStruct(statement, g)
+			functions = append(functions, function)
+			for _, i := range imports {
+				importsMap[i] = common.Null
+			}
+			function, imports = rf_panic(statement, g, policy, "")
+			functions = append(functions, function)
+			for _, i := range imports {
+				importsMap[i] = common.Null
+			}
+			if policy != nil {
+				function, imports = rf_panic(statement, g, nil, "WithDeleted")
+				functions = append(functions, function)
+				for _, i := range imports {
+					importsMap[i] = common.Null
+				}
+			}
+		}
+		function, imports = cnt_panic(statement, g, policy, "")
 		functions = append(functions, function)
 		for _, i := range imports {
 			importsMap[i] = common.Null
 		}
-		function, imports = d_panic(statement, logic, round)
+		if policy != nil {
+			function, imports = cnt_panic(statement, g, nil, "WithDeleted")
+			functions = append(functions, function)
+			for _, i := range imports {
+				importsMap[i] = common.Null
+			}
+		}
+		if o.Aggregate {
+			function, imports = agg_panic(statement, g, policy, "")
+			functions = append(functions, function)
+			for _, i := range imports {
+				importsMap[i] = common.Null
+			}
+			if policy != nil {
+				function, imports = agg_panic(statement, g, nil, "WithDeleted")
+				functions = append(functions, function)
+				for _, i := range imports {
+					importsMap[i] = common.Null
+				}
+			}
+		}
+		if o.Stream {
+			function, imports = each_panic(statement, g, policy, "")
+			functions = append(functions, function)
+			for _, i := range imports {
+				importsMap[i] = common.Null
+			}
+			if policy != nil {
+				function, imports = each_panic(statement, g, nil, "WithDeleted")
+				functions = append(functions, function)
+				for _, i := range imports {
+					importsMap[i] = common.Null
+				}
+			}
+		}
+		function, imports = d_panic(statement, g, policy)
 		functions = append(functions, function)
 		for _, i := range imports {
 			importsMap[i] = common.Null
 		}
+		if o.Audit {
+			function, imports = audit(statement, g, policy, true)
+			functions = append(functions, function)
+			for _, i := range imports {
+				importsMap[i] = common.Null
+			}
+		} else {
+			function, imports = dm_panic(statement, g, policy)
+			functions = append(functions, function)
+			for _, i := range imports {
+				importsMap[i] = common.Null
+			}
+		}
+	}
+
+	if strings.Contains(strings.Join(functions, "\n"), "time.") {
+		importsMap["time"] = common.Null
+	}
+	helpers := timeHelpers(strings.Join(functions, "\n"))
+	if helpers != "" {
+		importsMap["time"] = common.Null
+		importsMap["github.com/stella-go/siu/t/n"] = common.Null
 	}
 
 	importsLines := make([]string, 0)
@@ -84,16 +249,126 @@ func GeneratePanic(pkg string, statements []*parser.Statement, banner bool, logi
     QueryRow(query string, args ...interface{}) *sql.Row
     Query(query string, args ...interface{}) (*sql.Rows, error)
 }`
+	if o.Ctx {
+		datasourceLines = `type DataSource interface {
+    ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+    QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+    QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+}`
+	}
+	datasourceLines += `
+
+type UpsertResult int
+
+const (
+    UpsertUnchanged UpsertResult = iota
+    UpsertInserted
+    UpsertUpdated
+)`
+	if o.Tx {
+		datasourceLines += `
+
+func WithTx(ctx context.Context, db *sql.DB, opts *sql.TxOptions, fn func(tx DataSource)) {
+    WithTxRetry(ctx, db, opts, 0, fn)
+}
+
+func WithTxRetry(ctx context.Context, db *sql.DB, opts *sql.TxOptions, retries int, fn func(tx DataSource)) {
+    for i := 0; ; i++ {
+        err := withTx(ctx, db, opts, fn)
+        if err == nil {
+            return
+        }
+        if i >= retries {
+            t.AssertErrorNil(err)
+        }
+    }
+}
+
+func withTx(ctx context.Context, db *sql.DB, opts *sql.TxOptions, fn func(tx DataSource)) (err error) {
+    tx, err := db.BeginTx(ctx, opts)
+    t.AssertErrorNil(err)
+    defer func() {
+        if r := recover(); r != nil {
+            tx.Rollback()
+            if e, ok := r.(error); ok && isDeadlock(e) {
+                err = e
+                return
+            }
+            panic(r)
+        }
+    }()
+    fn(tx)
+    t.AssertErrorNil(tx.Commit())
+    return nil
+}
+
+func isDeadlock(err error) bool {
+    message := err.Error()
+    return ` + g.deadlock() + `
+}`
+	}
+	if o.Keyset {
+		datasourceLines += `
+
+func encodeCursor(values ...interface{}) (string, error) {
+    bts, err := json.Marshal(values)
+    if err != nil {
+        return "", err
+    }
+    return base64.RawURLEncoding.EncodeToString(bts), nil
+}
+
+func decodeCursor(cursor string, n int) ([]interface{}, error) {
+    bts, err := base64.RawURLEncoding.DecodeString(cursor)
+    if err != nil {
+        return nil, &ArgumentError{Message: "invalid cursor"}
+    }
+    values := make([]interface{}, 0)
+    decoder := json.NewDecoder(bytes.NewReader(bts))
+    decoder.UseNumber()
+    err = decoder.Decode(&values)
+    if err != nil || len(values) != n {
+        return nil, &ArgumentError{Message: "invalid cursor"}
+    }
+    return values, nil
+}`
+	}
+	if versioned {
+		datasourceLines += `
+
+var ErrStaleObject = errors.New("stale object")`
+	}
+	if o.Sort || o.Filter || o.Keyset || o.Projection || o.Mask {
+		datasourceLines += argumentLines
+	}
+	if o.Filter {
+		datasourceLines += g.filterLines()
+	}
+	if o.Sort {
+		datasourceLines += g.orderLines()
+	}
+	if o.Projection || o.Mask {
+		datasourceLines += projectionLines
+	}
+	datasourceLines += helpers
+	datasourceLines += g.bindLines()
 	bannerS := ""
-	if banner {
+	if o.Banner {
 		bannerS = fmt.Sprintf("\n/**\n * Auto Generate by github.com/stella-go/stella %s on %s.\n */\n", version.VERSION, time.Now().Format("2006/01/02"))
 
 	}
+	if o.Prepare {
+		datasourceLines += g.queriesLines(true)
+	}
+	if o.Audit {
+		datasourceLines += g.auditLines(true)
+	}
 	return fmt.Sprintf("package %s\n%s\nimport (\n%s\n)\n\n%s\n\n%s", pkg, bannerS, strings.Join(importsLines, "\n"), datasourceLines, strings.Join(functions, "\n"))
 }
 
-func c_panic(statement *parser.Statement, round string) (string, []string) {
+func c_panic(statement *parser.Statement, g *gen) (string, []string) {
 	modelName := generator.FirstUpperCamelCase(statement.TableName.Name)
+	zero, imports := versionZero(statement, "    ")
 	columns := make([]string, 0)
 	values := make([]string, 0)
 	args := make([]string, 0)
@@ -102,12 +377,10 @@ func c_panic(statement *parser.Statement, round string) (string, []string) {
 			continue
 		}
 		fieldName := generator.FirstUpperCamelCase(col.ColumnName.Name)
-		columns = append(columns, "\"`"+col.ColumnName.Name+"`\"")
+		columns = append(columns, "\""+g.ident(col.ColumnName.Name)+"\"")
 		values = append(values, "\"?\"")
 		arg := "s." + fieldName
-		if (col.Type == "DATE" || col.Type == "DATETIME" || col.Type == "TIMESTAMP") && round != "" {
-			arg = arg + ".Round(" + round + ")"
-		}
+		arg = timeArg(col, arg, g.times)
 		args = append(args, arg)
 	}
 	insert := fmt.Sprintf(`columns := []string{%s}
@@ -121,43 +394,364 @@ func c_panic(statement *parser.Statement, round string) (string, []string) {
 		if col.DefaultValue != nil {
 			fieldName := generator.FirstUpperCamelCase(col.ColumnName.Name)
 			arg := "s." + fieldName
-			if (col.Type == "DATE" || col.Type == "DATETIME" || col.Type == "TIMESTAMP") && round != "" {
-				arg = arg + ".Round(" + round + ")"
-			}
+			arg = timeArg(col, arg, g.times)
 			insert += fmt.Sprintf(`    if s.%s != nil {
         columns = append(columns, "%s")
         values = append(values, "?")
         args = append(args, %s)
     }
-`, fieldName, "`"+col.ColumnName.Name+"`", arg)
+`, fieldName, g.ident(col.ColumnName.Name), arg)
 		}
 	}
 	insert += `    SQL = fmt.Sprintf(SQL, strings.Join(columns, ", "), strings.Join(values, ", "))`
 
-	SQL := fmt.Sprintf("insert into `%s` (%%s) values (%%s)", statement.TableName.Name)
-	funcLines := fmt.Sprintf(`func Create%s(db DataSource, s *%s) int64 {
-    if s == nil {
-        t.AssertErrorNil(fmt.Errorf("pointer can not be nil"))
-    }
-    SQL := "%s"
-    %s
-    ret, err := db.Exec(SQL, args...)
+	SQL := fmt.Sprintf("insert into "+g.ident("%s")+" (%%s) values (%%s)", statement.TableName.Name)
+	exec := `    ret, err := ` + g.exec("SQL") + `, args...)
     t.AssertErrorNil(err)
     _, err = ret.RowsAffected()
     t.AssertErrorNil(err)
     id, err := ret.LastInsertId()
 	t.AssertErrorNil(err)
 	return id
+}`
+	if g.Dialect == Postgres || g.Dialect == SQLServer {
+		exec = `    _, err := ` + g.exec("SQL") + `, args...)
+    t.AssertErrorNil(err)
+    return 0
+}`
+		var id *parser.ColumnDefinition
+		for _, col := range statement.Columns {
+			if col.AutoIncrement {
+				id = col
+			}
+		}
+		if id != nil {
+			exec = `    id := int64(0)
+    err := ` + g.queryRow("SQL") + `, args...).Scan(&id)
+    t.AssertErrorNil(err)
+    return id
+}`
+			if g.Dialect == Postgres {
+				SQL = fmt.Sprintf("insert into "+g.ident("%s")+" (%%s) values (%%s) returning "+g.ident("%s"), statement.TableName.Name, id.ColumnName.Name)
+			} else {
+				SQL = fmt.Sprintf("insert into "+g.ident("%s")+" (%%s) output inserted."+g.ident("%s")+" values (%%s)", statement.TableName.Name, id.ColumnName.Name)
+			}
+		}
+	}
+	funcLines := fmt.Sprintf(`func `+g.audited("Create")+`%s(`+g.db()+`, s *%s) int64 {
+    if s == nil {
+        t.AssertErrorNil(fmt.Errorf("pointer can not be nil"))
+    }
+%s    SQL := "%s"
+    %s
+%s
+`, modelName, modelName, zero, g.prepared(SQL), insert, exec)
+	return funcLines, imports
 }
-`, modelName, modelName, SQL, insert)
-	return funcLines, nil
+
+func cm_panic(statement *parser.Statement, g *gen) (string, []string) {
+	modelName := generator.FirstUpperCamelCase(statement.TableName.Name)
+	zero, imports := versionZero(statement, "        ")
+	columns := make([]string, 0)
+	values := make([]string, 0)
+	args := make([]string, 0)
+	for _, col := range statement.Columns {
+		if col.AutoIncrement || col.CurrentTimestamp || col.DefaultValue != nil {
+			continue
+		}
+		fieldName := generator.FirstUpperCamelCase(col.ColumnName.Name)
+		columns = append(columns, "\""+g.ident(col.ColumnName.Name)+"\"")
+		values = append(values, "\"?\"")
+		arg := "s." + fieldName
+		arg = timeArg(col, arg, g.times)
+		args = append(args, arg)
+	}
+	present := ""
+	row := ""
+	for _, col := range statement.Columns {
+		if col.AutoIncrement || col.CurrentTimestamp {
+			continue
+		}
+		if col.DefaultValue != nil {
+			fieldName := generator.FirstUpperCamelCase(col.ColumnName.Name)
+			arg := "s." + fieldName
+			arg = timeArg(col, arg, g.times)
+			present += fmt.Sprintf(`        has%s := false
+        for _, s := range batch {
+            if s.%s != nil {
+                has%s = true
+                break
+            }
+        }
+        if has%s {
+            columns = append(columns, "%s")
+        }
+`, fieldName, fieldName, fieldName, fieldName, g.ident(col.ColumnName.Name))
+			row += fmt.Sprintf(`            if has%s {
+                if s.%s != nil {
+                    value = append(value, "?")
+                    args = append(args, %s)
+                } else {
+                    value = append(value, "default")
+                }
+            }
+`, fieldName, fieldName, arg)
+		}
+	}
+	insert := fmt.Sprintf(`        columns := []string{%s}
+%s        values := make([]string, 0, len(batch))
+        args := make([]interface{}, 0)
+        for _, s := range batch {
+            value := []string{%s}
+            args = append(args, %s)
+%s            values = append(values, "("+strings.Join(value, ", ")+")")
+        }
+        SQL = fmt.Sprintf(SQL, strings.Join(columns, ", "), strings.Join(values, ", "))`, strings.Join(columns, ", "), present, strings.Join(values, ", "), strings.Join(args, ", "), row)
+	if len(args) == 0 {
+		insert = strings.Replace(insert, "            args = append(args, )\n", "", 1)
+	}
+
+	SQL := fmt.Sprintf("insert into "+g.ident("%s")+" (%%s) values %%s", statement.TableName.Name)
+	funcLines := fmt.Sprintf(`func CreateMany%s(`+g.db()+`, list []*%s, batchSize int) int64 {
+    for _, s := range list {
+        if s == nil {
+            t.AssertErrorNil(fmt.Errorf("pointer can not be nil"))
+        }
+%s    }
+    if batchSize <= 0 {
+        batchSize = 500
+    }
+    total := int64(0)
+    for start := 0; start < len(list); start += batchSize {
+        end := start + batchSize
+        if end > len(list) {
+            end = len(list)
+        }
+        batch := list[start:end]
+        SQL := "%s"
+%s
+        ret, err := `+g.exec("SQL")+`, args...)
+        t.AssertErrorNil(err)
+        count, err := ret.RowsAffected()
+        t.AssertErrorNil(err)
+        total += count
+    }
+    return total
+}
+`, modelName, modelName, zero, g.prepared(SQL), insert)
+	return funcLines, imports
+}
+
+func ups_panic(statement *parser.Statement, g *gen) (string, []string) {
+	modelName := generator.FirstUpperCamelCase(statement.TableName.Name)
+	funcLines := ""
+	imports := make([]string, 0)
+	uniqKeyPairs := generator.UniqKeyPairs(statement)
+	for _, keys := range uniqKeyPairs {
+		if !g.upsertable(keys) {
+			continue
+		}
+		version := generator.VersionColumn(statement)
+		if version != nil && contains(keys, version) {
+			version = nil
+		}
+		zero := ""
+		if version != nil {
+			zero, imports = versionZero(statement, "    ")
+		}
+		begin := ""
+		fields := make([]string, 0)
+		columns := make([]string, 0)
+		values := make([]string, 0)
+		args := make([]string, 0)
+		names := make([]string, 0)
+		updatable := make([]string, 0)
+		for _, col := range keys {
+			fieldName := generator.FirstUpperCamelCase(col.ColumnName.Name)
+			columns = append(columns, "\""+g.ident(col.ColumnName.Name)+"\"")
+			values = append(values, "\"?\"")
+			arg := "s." + fieldName
+			arg = timeArg(col, arg, g.times)
+			args = append(args, arg)
+			fields = append(fields, fieldName)
+		}
+		optional := ""
+		for _, col := range statement.Columns {
+			if col.AutoIncrement || col.CurrentTimestamp {
+				continue
+			}
+			if contains(keys, col) {
+				continue
+			}
+			if col != version {
+				updatable = append(updatable, "\""+col.ColumnName.Name+"\"")
+			}
+			fieldName := generator.FirstUpperCamelCase(col.ColumnName.Name)
+			arg := "s." + fieldName
+			arg = timeArg(col, arg, g.times)
+			if col.DefaultValue != nil {
+				name := fmt.Sprintf(`
+        names = append(names, "%s")`, col.ColumnName.Name)
+				if col == version {
+					name = ""
+				}
+				optional += fmt.Sprintf(`    if s.%s != nil {
+        columns = append(columns, "%s")
+        values = append(values, "?")
+        args = append(args, %s)%s
+    }
+`, fieldName, g.ident(col.ColumnName.Name), arg, name)
+				continue
+			}
+			columns = append(columns, "\""+g.ident(col.ColumnName.Name)+"\"")
+			values = append(values, "\"?\"")
+			args = append(args, arg)
+			if col != version {
+				names = append(names, "\""+col.ColumnName.Name+"\"")
+			}
+		}
+		bump := ""
+		if version != nil {
+			current := ""
+			switch g.dialect() {
+			case Postgres, SQLite:
+				current = g.ident(statement.TableName.Name) + "."
+			case SQLServer:
+				current = "target."
+			}
+			bump = fmt.Sprintf(`    set = append(set, "%s = %s%s + 1")
+`, g.ident(version.ColumnName.Name), current, g.ident(version.ColumnName.Name))
+		}
+		conflict := make([]string, 0)
+		matched := make([]string, 0)
+		for _, col := range keys {
+			conflict = append(conflict, g.ident(col.ColumnName.Name))
+			matched = append(matched, "target."+g.ident(col.ColumnName.Name)+" = source."+g.ident(col.ColumnName.Name))
+		}
+		name := g.identOf("name")
+		assign := name + " = values(" + name + ")"
+		format := `    SQL = fmt.Sprintf(SQL, strings.Join(columns, ", "), strings.Join(values, ", "), strings.Join(set, ", "))`
+		SQL := fmt.Sprintf("insert into "+g.ident("%s")+" (%%s) values (%%s) on duplicate key update %%s", statement.TableName.Name)
+		exec := `    ret, err := ` + g.exec("SQL") + `, args...)
+    t.AssertErrorNil(err)
+    count, err := ret.RowsAffected()
+    t.AssertErrorNil(err)
+    switch count {
+    case 1:
+        return UpsertInserted
+    case 0:
+        return UpsertUnchanged
+    default:
+        return UpsertUpdated
+    }
+}`
+		switch g.dialect() {
+		case Postgres:
+			assign = name + " = excluded." + name
+			SQL = fmt.Sprintf("insert into "+g.ident("%s")+" (%%s) values (%%s) on conflict (%s) do update set %%s returning (xmax = 0)", statement.TableName.Name, strings.Join(conflict, ", "))
+			exec = `    inserted := false
+    err := ` + g.queryRow("SQL") + `, args...).Scan(&inserted)
+    t.AssertErrorNil(err)
+    if inserted {
+        return UpsertInserted
+    }
+    return UpsertUpdated
+}`
+		case SQLite:
+			begin = fmt.Sprintf(`    if sqlDB, ok := db.(*sql.DB); ok {
+        tx, err := sqlDB.%s
+        t.AssertErrorNil(err)
+        defer tx.Rollback()
+        result := `+g.audited("Upsert")+`%sBy%s(%s, s, update...)
+        t.AssertErrorNil(tx.Commit())
+        return result
+    }
+`, g.begin(), modelName, strings.Join(fields, ""), g.txArgs())
+			assign = name + " = excluded." + name
+			format = `    SQL1 := fmt.Sprintf(SQL, strings.Join(columns, ", "), strings.Join(values, ", "), "nothing")
+    SQL2 := fmt.Sprintf(SQL, strings.Join(columns, ", "), strings.Join(values, ", "), "update set "+strings.Join(set, ", "))`
+			SQL = fmt.Sprintf("insert into "+g.ident("%s")+" (%%s) values (%%s) on conflict (%s) do %%s", statement.TableName.Name, strings.Join(conflict, ", "))
+			exec = `    ret, err := ` + g.exec("SQL1") + `, args...)
+    t.AssertErrorNil(err)
+    count, err := ret.RowsAffected()
+    t.AssertErrorNil(err)
+    if count == 1 {
+        return UpsertInserted
+    }
+    ret, err = ` + g.exec("SQL2") + `, args...)
+    t.AssertErrorNil(err)
+    count, err = ret.RowsAffected()
+    t.AssertErrorNil(err)
+    if count == 0 {
+        return UpsertUnchanged
+    }
+    return UpsertUpdated
+}`
+		case SQLServer:
+			assign = name + " = source." + name
+			format = `    sources := make([]string, 0, len(columns))
+    for _, column := range columns {
+        sources = append(sources, "source."+column)
+    }
+    SQL = fmt.Sprintf(SQL, strings.Join(values, ", "), strings.Join(columns, ", "), strings.Join(set, ", "), strings.Join(columns, ", "), strings.Join(sources, ", "))`
+			SQL = fmt.Sprintf("merge into "+g.ident("%s")+" with (holdlock) as target using (values (%%s)) as source (%%s) on %s when matched then update set %%s when not matched then insert (%%s) values (%%s) output $action;", statement.TableName.Name, strings.Join(matched, " and "))
+			exec = `    action := ""
+    err := ` + g.queryRow("SQL") + `, args...).Scan(&action)
+    t.AssertErrorNil(err)
+    if action == "INSERT" {
+        return UpsertInserted
+    }
+    return UpsertUpdated
+}`
+		}
+		insert := fmt.Sprintf(`columns := []string{%s}
+    values := []string{%s}
+    args := []interface{}{%s}
+    names := []string{%s}
+%s    if len(update) == 0 {
+        update = names
+    }
+    updatable := []string{%s}
+    set := make([]string, 0, len(update))
+    for _, name := range update {
+        found := false
+        for _, u := range updatable {
+            if u == name {
+                found = true
+                break
+            }
+        }
+        if !found {
+            t.AssertErrorNil(fmt.Errorf("column %%s can not be updated", name))
+        }
+        set = append(set, "%s")
+    }
+    if len(set) == 0 {
+        t.AssertErrorNil(fmt.Errorf("no column to update"))
+    }
+%s%s`, strings.Join(columns, ", "), strings.Join(values, ", "), strings.Join(args, ", "), strings.Join(names, ", "), optional, strings.Join(updatable, ", "), assign, bump, format)
+
+		funcLines += fmt.Sprintf(`func `+g.audited("Upsert")+`%sBy%s(`+g.db()+`, s *%s, update ...string) UpsertResult {
+    if s == nil {
+        t.AssertErrorNil(fmt.Errorf("pointer can not be nil"))
+    }
+%s%s    SQL := "%s"
+    %s
+%s
+`, modelName, strings.Join(fields, ""), modelName, begin, zero, g.prepared(SQL), insert, exec)
+	}
+	return funcLines, imports
 }
 
-func u_panic(statement *parser.Statement, round string) (string, []string) {
+func u_panic(statement *parser.Statement, g *gen) (string, []string) {
 	modelName := generator.FirstUpperCamelCase(statement.TableName.Name)
 	funcLines := ""
-	uniqKeyPairs := getUniqKeyPairs(statement)
+	uniqKeyPairs := generator.UniqKeyPairs(statement)
 	for _, keys := range uniqKeyPairs {
+		version := generator.VersionColumn(statement)
+		if version != nil && contains(keys, version) {
+			version = nil
+		}
 		args := make([]string, 0)
 		set := `set := ""
     args := make([]interface{}, 0)
@@ -166,16 +760,14 @@ func u_panic(statement *parser.Statement, round string) (string, []string) {
 			if col.AutoIncrement || col.CurrentTimestamp {
 				continue
 			}
-			if contains(keys, col) {
+			if contains(keys, col) || col == version {
 				continue
 			}
 			fieldName := generator.FirstUpperCamelCase(col.ColumnName.Name)
 			arg := "s." + fieldName
-			if (col.Type == "DATE" || col.Type == "DATETIME" || col.Type == "TIMESTAMP") && round != "" {
-				arg = arg + ".Round(" + round + ")"
-			}
+			arg = timeArg(col, arg, g.times)
 			set += fmt.Sprintf(`if %s != nil {
-        set += ", `+"`%s`"+` = ? "
+        set += ", `+g.ident("%s")+` = ? "
         args = append(args, %s)
     }
     `, "s."+fieldName, col.ColumnName, arg)
@@ -189,68 +781,166 @@ func u_panic(statement *parser.Statement, round string) (string, []string) {
 		fields := make([]string, 0)
 		conditions := make([]string, 0)
 		for _, col := range keys {
-			conditions = append(conditions, "`"+col.ColumnName.Name+"` = ?")
+			conditions = append(conditions, g.ident(col.ColumnName.Name)+" = ?")
 			arg := "s." + generator.FirstUpperCamelCase(col.ColumnName.Name)
-			if (col.Type == "DATE" || col.Type == "DATETIME" || col.Type == "TIMESTAMP") && round != "" {
-				arg = arg + ".Round(" + round + ")"
-			}
+			arg = timeArg(col, arg, g.times)
 			args = append(args, arg)
 			fields = append(fields, generator.FirstUpperCamelCase(col.ColumnName.Name))
 		}
-		SQL := fmt.Sprintf("update `%s` set %%s where %s", statement.TableName.Name, strings.Join(conditions, " and "))
-		funcLines += fmt.Sprintf(`func Update%sBy%s(db DataSource, s *%s) int64{
+		SQL := fmt.Sprintf("update "+g.ident("%s")+" set %%s where %s", statement.TableName.Name, strings.Join(conditions, " and "))
+		versionCheck := ""
+		staleCheck := ""
+		if version != nil {
+			versionName := generator.FirstUpperCamelCase(version.ColumnName.Name)
+			SQL = fmt.Sprintf("update "+g.ident("%s")+" set %%s, "+g.ident("%s")+" = "+g.ident("%s")+" + 1 where %s and "+g.ident("%s")+" = ?", statement.TableName.Name, version.ColumnName.Name, version.ColumnName.Name, strings.Join(conditions, " and "), version.ColumnName.Name)
+			args = append(args, "s."+versionName)
+			versionCheck = fmt.Sprintf(`    if s.%s == nil {
+        t.AssertErrorNil(fmt.Errorf("version can not be nil"))
+    }
+`, versionName)
+			staleCheck = `    if count == 0 {
+        panic(ErrStaleObject)
+    }
+`
+		}
+		funcLines += fmt.Sprintf(`func `+g.audited("Update")+`%sBy%s(`+g.db()+`, s *%s) int64{
     if s == nil {
         t.AssertErrorNil(fmt.Errorf("pointer can not be nil"))
     }
-    SQL := "%s"
+%s    SQL := "%s"
     %s
     args = append(args, %s)
-    ret, err := db.Exec(SQL, args...)
+    ret, err := `+g.exec("SQL")+`, args...)
     t.AssertErrorNil(err)
     count, err := ret.RowsAffected()
     t.AssertErrorNil(err)
-	return count
+%s	return count
 }
-`, modelName, strings.Join(fields, ""), modelName, SQL, set, strings.Join(args, ", "))
+`, modelName, strings.Join(fields, ""), modelName, versionCheck, SQL, set, strings.Join(args, ", "), staleCheck)
 	}
 	return funcLines, nil
 }
 
-func r_panic(statement *parser.Statement, asc string, desc string, round string) (string, []string) {
+func uf_panic(statement *parser.Statement, g *gen) (string, []string) {
 	modelName := generator.FirstUpperCamelCase(statement.TableName.Name)
 	funcLines := ""
-	names := make([]string, 0)
-	binds := make([]string, 0)
-
-	for _, col := range statement.Columns {
-		names = append(names, "`"+col.ColumnName.Name+"`")
-		fieldName := generator.FirstUpperCamelCase(col.ColumnName.Name)
-		binds = append(binds, "&ret."+fieldName)
-	}
-
-	uniqKeyPairs := getUniqKeyPairs(statement)
+	uniqKeyPairs := generator.UniqKeyPairs(statement)
 	for _, keys := range uniqKeyPairs {
-		fields := make([]string, 0)
-		conditions := make([]string, 0)
-		args := make([]string, 0)
+		version := generator.VersionColumn(statement)
+		if version != nil && contains(keys, version) {
+			version = nil
+		}
+		cases := make([]string, 0)
+		for _, col := range statement.Columns {
+			if col.AutoIncrement || col.CurrentTimestamp {
+				continue
+			}
+			if contains(keys, col) || col == version {
+				continue
+			}
+			fieldName := generator.FirstUpperCamelCase(col.ColumnName.Name)
+			arg := "        args = append(args, s." + fieldName + ")"
+			if value := timeArg(col, "s."+fieldName, g.times); value != "s."+fieldName {
+				arg = fmt.Sprintf(`        if s.%s != nil {
+            args = append(args, %s)
+        } else {
+            args = append(args, nil)
+        }`, fieldName, value)
+			}
+			cases = append(cases, fmt.Sprintf(`        case %sColumn%s:
+            set = append(set, "`+g.ident("%s")+` = ?")
+    %s`, modelName, fieldName, col.ColumnName.Name, arg))
+		}
+		fields := make([]string, 0)
+		conditions := make([]string, 0)
+		args := make([]string, 0)
 		for _, col := range keys {
-			conditions = append(conditions, "`"+col.ColumnName.Name+"` = ?")
+			conditions = append(conditions, g.ident(col.ColumnName.Name)+" = ?")
+			arg := "s." + generator.FirstUpperCamelCase(col.ColumnName.Name)
+			arg = timeArg(col, arg, g.times)
+			args = append(args, arg)
+			fields = append(fields, generator.FirstUpperCamelCase(col.ColumnName.Name))
+		}
+		SQL := fmt.Sprintf("update "+g.ident("%s")+" set %%s where %s", statement.TableName.Name, strings.Join(conditions, " and "))
+		versionCheck := ""
+		staleCheck := ""
+		if version != nil {
+			versionName := generator.FirstUpperCamelCase(version.ColumnName.Name)
+			SQL = fmt.Sprintf("update "+g.ident("%s")+" set %%s, "+g.ident("%s")+" = "+g.ident("%s")+" + 1 where %s and "+g.ident("%s")+" = ?", statement.TableName.Name, version.ColumnName.Name, version.ColumnName.Name, strings.Join(conditions, " and "), version.ColumnName.Name)
+			args = append(args, "s."+versionName)
+			versionCheck = fmt.Sprintf(`    if s.%s == nil {
+        t.AssertErrorNil(fmt.Errorf("version can not be nil"))
+    }
+`, versionName)
+			staleCheck = `    if count == 0 {
+        panic(ErrStaleObject)
+    }
+`
+		}
+		switchLines := "    for _, field := range fields {\n        switch field {\n" + strings.Join(cases, "\n") + `
+        default:
+            t.AssertErrorNil(fmt.Errorf("column %s can not be updated", field))
+        }
+    }`
+		funcLines += fmt.Sprintf(`func `+g.audited("Update")+`%sBy%sFields(`+g.db()+`, s *%s, fields []%sColumn) int64 {
+    if s == nil {
+        t.AssertErrorNil(fmt.Errorf("pointer can not be nil"))
+    }
+    if len(fields) == 0 {
+        t.AssertErrorNil(fmt.Errorf("no field selected"))
+    }
+%s    set := make([]string, 0, len(fields))
+    args := make([]interface{}, 0, len(fields))
+%s
+    SQL := fmt.Sprintf("%s", strings.Join(set, ", "))
+    args = append(args, %s)
+    ret, err := `+g.exec("SQL")+`, args...)
+    t.AssertErrorNil(err)
+    count, err := ret.RowsAffected()
+    t.AssertErrorNil(err)
+%s    return count
+}
+`, modelName, strings.Join(fields, ""), modelName, modelName, versionCheck, switchLines, SQL, strings.Join(args, ", "), staleCheck)
+	}
+	return funcLines, nil
+}
+
+func r_panic(statement *parser.Statement, g *gen, logic *generator.Logic, suffix string) (string, []string) {
+	modelName := generator.FirstUpperCamelCase(statement.TableName.Name)
+	funcLines := ""
+	names := make([]string, 0)
+	binds := make([]string, 0)
+
+	for _, col := range statement.Columns {
+		names = append(names, g.ident(col.ColumnName.Name))
+		fieldName := generator.FirstUpperCamelCase(col.ColumnName.Name)
+		binds = append(binds, "&ret."+fieldName)
+	}
+
+	uniqKeyPairs := generator.UniqKeyPairs(statement)
+	for _, keys := range uniqKeyPairs {
+		fields := make([]string, 0)
+		conditions := make([]string, 0)
+		args := make([]string, 0)
+		for _, col := range keys {
+			conditions = append(conditions, g.ident(col.ColumnName.Name)+" = ?")
 			fieldName := generator.FirstUpperCamelCase(col.ColumnName.Name)
 			arg := "s." + fieldName
-			if (col.Type == "DATE" || col.Type == "DATETIME" || col.Type == "TIMESTAMP") && round != "" {
-				arg = arg + ".Round(" + round + ")"
-			}
+			arg = timeArg(col, arg, g.times)
 			args = append(args, arg)
 			fields = append(fields, fieldName)
 		}
-		SQL := fmt.Sprintf("select %s from `%s` where %s", strings.Join(names, ", "), statement.TableName, strings.Join(conditions, " and "))
-		funcLines += fmt.Sprintf(`func Query%sBy%s(db DataSource, s *%s) *%s {
+		if logic != nil {
+			conditions = append(conditions, quote(logic.Alive(g.name)))
+		}
+		SQL := fmt.Sprintf("select %s from "+g.ident("%s")+" where %s", strings.Join(names, ", "), statement.TableName, strings.Join(conditions, " and "))
+		funcLines += fmt.Sprintf(`func Query%sBy%s`+suffix+`(`+g.db()+`, s *%s) *%s {
     if s == nil {
         t.AssertErrorNil(fmt.Errorf("pointer can not be nil"))
     }
     SQL := "%s"
     ret := &%s{}
-    err := db.QueryRow(SQL, %s).Scan(%s)
+    err := `+g.queryRow("SQL")+`, %s).Scan(%s)
     if err != nil {
         if err != sql.ErrNoRows {
             t.AssertErrorNil(err)
@@ -259,80 +949,29 @@ func r_panic(statement *parser.Statement, asc string, desc string, round string)
     }
     return ret
 }
-`, modelName, strings.Join(fields, ""), modelName, modelName, SQL, modelName, strings.Join(args, ", "), strings.Join(binds, ", "))
-	}
-	type Order struct {
-		FuncSuffix string
-		Statement  string
-	}
-	orders := []*Order{{}}
-	if asc != "" {
-		columns := make([]string, 0)
-		if (strings.HasPrefix(asc, "\"") && strings.HasSuffix(asc, "\"")) || (strings.HasPrefix(asc, "'") && strings.HasSuffix(asc, "'")) {
-			asc = asc[1 : len(asc)-1]
-		}
-		columnNames := strings.Split(asc, ",")
-		for _, name := range columnNames {
-			for _, c := range statement.Columns {
-				if c.ColumnName.Name != name {
-					continue
-				}
-				columns = append(columns, c.ColumnName.Name)
-			}
-		}
-		if len(columns) != 0 {
-			s1 := make([]string, 0)
-			s2 := make([]string, 0)
-			for _, c := range columns {
-				s1 = append(s1, generator.FirstUpperCamelCase(c))
-				s2 = append(s2, "`"+c+"`")
-			}
-			orders = append(orders, &Order{FuncSuffix: fmt.Sprintf("OrderBy%s", strings.Join(s1, "")), Statement: fmt.Sprintf("order by %s ", strings.Join(s2, ", "))})
-		}
-	}
-	if desc != "" {
-		columns := make([]string, 0)
-		if (strings.HasPrefix(desc, "\"") && strings.HasSuffix(desc, "\"")) || (strings.HasPrefix(desc, "'") && strings.HasSuffix(desc, "'")) {
-			desc = desc[1 : len(desc)-1]
-		}
-		columnNames := strings.Split(desc, ",")
-		for _, name := range columnNames {
-			for _, c := range statement.Columns {
-				if c.ColumnName.Name != name {
-					continue
-				}
-				columns = append(columns, c.ColumnName.Name)
-			}
-		}
-		if len(columns) != 0 {
-			s1 := make([]string, 0)
-			s2 := make([]string, 0)
-			for _, c := range columns {
-				s1 = append(s1, generator.FirstUpperCamelCase(c))
-				s2 = append(s2, "`"+c+"`")
-			}
-			orders = append(orders, &Order{FuncSuffix: fmt.Sprintf("OrderBy%sDesc", strings.Join(s1, "")), Statement: fmt.Sprintf("order by %s desc ", strings.Join(s2, ", "))})
-		}
+`, modelName, strings.Join(fields, ""), modelName, modelName, g.prepared(SQL), modelName, strings.Join(args, ", "), strings.Join(binds, ", "))
 	}
+	orders := getOrders(statement, g)
 	for _, order := range orders {
-		indexKeyPairs := getIndexKeyPairs(statement)
+		indexKeyPairs := generator.IndexKeyPairs(statement)
 		for _, keys := range indexKeyPairs {
 			fields := make([]string, 0)
 			conditions := make([]string, 0)
 			args := make([]string, 0)
 			for _, col := range keys {
-				conditions = append(conditions, "`"+col.ColumnName.Name+"` = ?")
+				conditions = append(conditions, g.ident(col.ColumnName.Name)+" = ?")
 				fieldName := generator.FirstUpperCamelCase(col.ColumnName.Name)
 				arg := "s." + fieldName
-				if (col.Type == "DATE" || col.Type == "DATETIME" || col.Type == "TIMESTAMP") && round != "" {
-					arg = arg + ".Round(" + round + ")"
-				}
+				arg = timeArg(col, arg, g.times)
 				args = append(args, arg)
 				fields = append(fields, fieldName)
 			}
-			SQL1 := fmt.Sprintf("select count(*) from `%s` where %s", statement.TableName.Name, strings.Join(conditions, " and "))
-			SQL2 := fmt.Sprintf("select %s from `%s` where %s %slimit ?, ?", strings.Join(names, ", "), statement.TableName.Name, strings.Join(conditions, " and "), order.Statement)
-			funcLines += fmt.Sprintf(`func QueryMany%sBy%s%s(db DataSource, s *%s, page int, size int) (int, []*%s) {
+			if logic != nil {
+				conditions = append(conditions, quote(logic.Alive(g.name)))
+			}
+			SQL1 := fmt.Sprintf("select count(*) from "+g.ident("%s")+" where %s", statement.TableName.Name, strings.Join(conditions, " and "))
+			SQL2 := fmt.Sprintf("select %s from "+g.ident("%s")+" where %s %s", strings.Join(names, ", "), statement.TableName.Name, strings.Join(conditions, " and "), g.page(order.Statement))
+			funcLines += fmt.Sprintf(`func QueryMany%sBy%s%s`+suffix+`(`+g.db()+`, s *%s, page int, size int) (int, []*%s) {
     if s == nil {
         t.AssertErrorNil(fmt.Errorf("pointer can not be nil"))
     }
@@ -344,11 +983,11 @@ func r_panic(statement *parser.Statement, asc string, desc string, round string)
     }
     SQL1 := "%s"
     count := 0
-    err := db.QueryRow(SQL1, %s).Scan(&count)
+    err := `+g.queryRow("SQL1")+`, %s).Scan(&count)
     t.AssertErrorNil(err)
 
     SQL2 := "%s"
-    rows, err := db.Query(SQL2, %s, (page-1)*size, size)
+    rows, err := `+g.query("SQL2")+`, %s, (page-1)*size, size)
     if err != nil {
         if err != sql.ErrNoRows {
             t.AssertErrorNil(err)
@@ -366,7 +1005,7 @@ func r_panic(statement *parser.Statement, asc string, desc string, round string)
     }
     return count, results
 }
-`, modelName, strings.Join(fields, ""), order.FuncSuffix, modelName, modelName, SQL1, strings.Join(args, ", "), SQL2, strings.Join(args, ", "), modelName, modelName, strings.Join(binds, ", "))
+`, modelName, strings.Join(fields, ""), order.FuncSuffix, modelName, modelName, g.prepared(SQL1), strings.Join(args, ", "), g.prepared(SQL2), strings.Join(args, ", "), modelName, modelName, strings.Join(binds, ", "))
 		}
 
 		where := `where := ""
@@ -376,11 +1015,9 @@ func r_panic(statement *parser.Statement, asc string, desc string, round string)
 		for _, col := range statement.Columns {
 			fieldName := generator.FirstUpperCamelCase(col.ColumnName.Name)
 			arg := "s." + fieldName
-			if (col.Type == "DATE" || col.Type == "DATETIME" || col.Type == "TIMESTAMP") && round != "" {
-				arg = arg + ".Round(" + round + ")"
-			}
+			arg = timeArg(col, arg, g.times)
 			where += fmt.Sprintf(`        if %s != nil {
-            where += "and `+"`%s`"+` = ? "
+            where += "and `+g.ident("%s")+` = ? "
             args = append(args, %s)
         }
 `, "s."+fieldName, col.ColumnName, arg)
@@ -394,24 +1031,47 @@ func r_panic(statement *parser.Statement, asc string, desc string, round string)
     }
     SQL1 = fmt.Sprintf(SQL1, where)
     SQL2 = fmt.Sprintf(SQL2, where)`
+		if logic != nil {
+			where = strings.Replace(where, `where := ""`, `where := "and `+quote(logic.Alive(g.name))+` "`, 1)
+			where = strings.Replace(where, `        where = strings.TrimLeft(where, "and")
+        where = strings.TrimSpace(where)
+        if where != "" {
+            where = "where " + where
+        }
+    }`, `    }
+    where = strings.TrimLeft(where, "and")
+    where = strings.TrimSpace(where)
+    where = "where " + where`, 1)
+		}
 
-		SQL1 := fmt.Sprintf("select count(*) from `%s` %%s", statement.TableName.Name)
-		SQL2 := fmt.Sprintf("select %s from `%s` %%s %slimit ?, ?", strings.Join(names, ", "), statement.TableName.Name, order.Statement)
-		funcLines += fmt.Sprintf(`func QueryMany%s%s(db DataSource, s *%s, page int, size int) (int, []*%s) {
+		SQL1 := fmt.Sprintf("select count(*) from "+g.ident("%s")+" %%s", statement.TableName.Name)
+		SQL2 := fmt.Sprintf("select %s from "+g.ident("%s")+" %%s %s", strings.Join(names, ", "), statement.TableName.Name, g.page(order.Statement))
+		params := "s *" + modelName + ", page int"
+		sortLines := ""
+		if g.Sort && order.FuncSuffix == "" {
+			params = "s *" + modelName + ", orders []Order, page int"
+			SQL2 = fmt.Sprintf("select %s from "+g.ident("%s")+" %%s "+g.page("\" + sort + \""), strings.Join(names, ", "), statement.TableName.Name)
+			sortLines = fmt.Sprintf(`
+    sort, sortErr := sortBy(orders, sortable%s)
+    if sortErr != nil {
+        panic(sortErr)
+    }`, modelName)
+		}
+		funcLines += fmt.Sprintf(`func QueryMany%s%s`+suffix+`(`+g.db()+`, %s, size int) (int, []*%s) {
     if page <= 0 {
         page = 1
     }
     if size <= 0 {
         size = 10
-    }
+    }%s
     SQL1 := "%s"
     SQL2 := "%s"
     %s
     count := 0
-    err := db.QueryRow(SQL1, args...).Scan(&count)
+    err := `+g.queryRow("SQL1")+`, args...).Scan(&count)
     t.AssertErrorNil(err)
     args = append(args, (page-1)*size, size)
-    rows, err := db.Query(SQL2, args...)
+    rows, err := `+g.query("SQL2")+`, args...)
     if err != nil {
         if err != sql.ErrNoRows {
             t.AssertErrorNil(err)
@@ -429,75 +1089,686 @@ func r_panic(statement *parser.Statement, asc string, desc string, round string)
     }
     return count, results
 }
-`, modelName, order.FuncSuffix, modelName, modelName, SQL1, SQL2, where, modelName, modelName, strings.Join(binds, ", "))
+`, modelName, order.FuncSuffix, params, modelName, sortLines, g.prepared(SQL1), g.prepared(SQL2), where, modelName, modelName, strings.Join(binds, ", "))
 	}
 	return funcLines, nil
 }
 
-func d_panic(statement *parser.Statement, logic string, round string) (string, []string) {
-	var logicDelete bool
-	var logicCol string
-	var logicValue string
-	if logic != "" {
-		if index := strings.Index(logic, "="); index != -1 {
-			logicCol, logicValue = logic[:index], logic[index+1:]
-			if (strings.HasPrefix(logicValue, "\"") && strings.HasSuffix(logicValue, "\"")) || (strings.HasPrefix(logicValue, "'") && strings.HasSuffix(logicValue, "'")) {
-				logicValue = logicValue[1 : len(logicValue)-1]
+func ra_panic(statement *parser.Statement, g *gen, logic *generator.Logic, suffix string) (string, []string) {
+	modelName := generator.FirstUpperCamelCase(statement.TableName.Name)
+	funcLines := ""
+	primaryKeys := getPrimaryKeys(statement)
+	if len(primaryKeys) == 0 {
+		return funcLines, nil
+	}
+	names := make([]string, 0)
+	binds := make([]string, 0)
+	for _, col := range statement.Columns {
+		names = append(names, g.ident(col.ColumnName.Name))
+		fieldName := generator.FirstUpperCamelCase(col.ColumnName.Name)
+		binds = append(binds, "&ret."+fieldName)
+	}
+	where := `where := ""
+    args := make([]interface{}, 0)
+    if s != nil {
+`
+	for _, col := range statement.Columns {
+		fieldName := generator.FirstUpperCamelCase(col.ColumnName.Name)
+		arg := "s." + fieldName
+		arg = timeArg(col, arg, g.times)
+		where += fmt.Sprintf(`        if %s != nil {
+            where += "and `+g.ident("%s")+` = ? "
+            args = append(args, %s)
+        }
+`, "s."+fieldName, col.ColumnName, arg)
+	}
+	where += `    }
+`
+	if logic != nil {
+		where = strings.Replace(where, `where := ""`, `where := "and `+quote(logic.Alive(g.name))+` "`, 1)
+	}
+	orders := getOrders(statement, g)
+	for _, order := range orders {
+		keys := make([]*parser.ColumnDefinition, 0)
+		keys = append(keys, order.Columns...)
+		for _, col := range primaryKeys {
+			if !contains(keys, col) {
+				keys = append(keys, col)
 			}
-			for _, c := range statement.Columns {
-				if c.ColumnName.Name != logicCol {
-					continue
-				}
-				if c.Type != "TINYINT" && c.Type != "INT" && c.Type != "BIGINT" && c.Type != "FLOAT" {
-					logicValue = `\"` + logicValue + `\"`
+		}
+		columns := make([]string, 0)
+		sorts := make([]string, 0)
+		marks := make([]string, 0)
+		values := make([]string, 0)
+		for _, col := range keys {
+			columns = append(columns, g.ident(col.ColumnName.Name))
+			if order.Desc {
+				sorts = append(sorts, g.ident(col.ColumnName.Name)+" desc")
+			} else {
+				sorts = append(sorts, g.ident(col.ColumnName.Name))
+			}
+			marks = append(marks, "?")
+			values = append(values, "last."+generator.FirstUpperCamelCase(col.ColumnName.Name))
+		}
+		operator := ">"
+		if order.Desc {
+			operator = "<"
+		}
+		condition := fmt.Sprintf("and (%s) %s (%s) ", strings.Join(columns, ", "), operator, strings.Join(marks, ", "))
+		cursorArgs := "values..."
+		if g.Dialect == SQLServer {
+			ors := make([]string, 0)
+			indexes := make([]string, 0)
+			for i := range keys {
+				ands := make([]string, 0)
+				for j := 0; j < i; j++ {
+					ands = append(ands, columns[j]+" = ?")
+					indexes = append(indexes, fmt.Sprintf("values[%d]", j))
 				}
-				logicDelete = true
+				ands = append(ands, columns[i]+" "+operator+" ?")
+				indexes = append(indexes, fmt.Sprintf("values[%d]", i))
+				ors = append(ors, "("+strings.Join(ands, " and ")+")")
 			}
+			condition = fmt.Sprintf("and (%s) ", strings.Join(ors, " or "))
+			cursorArgs = strings.Join(indexes, ", ")
 		}
+		SQL := fmt.Sprintf("select %s from "+g.ident("%s")+" %%s order by %s "+g.limit(), strings.Join(names, ", "), statement.TableName.Name, strings.Join(sorts, ", "))
+		funcLines += fmt.Sprintf(`func QueryMany%s%sAfter`+suffix+`(`+g.db()+`, s *%s, cursor string, size int) (string, []*%s) {
+    if size <= 0 {
+        size = 10
+    }
+    SQL := "%s"
+    %s    if cursor != "" {
+        values, err := decodeCursor(cursor, %d)
+        if err != nil {
+            panic(err)
+        }
+        where += "%s"
+        args = append(args, %s)
+    }
+    where = strings.TrimLeft(where, "and")
+    where = strings.TrimSpace(where)
+    if where != "" {
+        where = "where " + where
+    }
+    SQL = fmt.Sprintf(SQL, where)
+    args = append(args, size)
+    rows, err := `+g.query("SQL")+`, args...)
+    t.AssertErrorNil(err)
+    defer rows.Close()
+
+    results := make([]*%s, 0)
+    for rows.Next() {
+        ret := &%s{}
+        err = rows.Scan(%s)
+        t.AssertErrorNil(err)
+        results = append(results, ret)
+    }
+    next := ""
+    if len(results) == size {
+        last := results[len(results)-1]
+        next, err = encodeCursor(%s)
+        t.AssertErrorNil(err)
+    }
+    return next, results
+}
+`, modelName, order.FuncSuffix, modelName, modelName, g.prepared(SQL), where, len(keys), condition, cursorArgs, modelName, modelName, strings.Join(binds, ", "), strings.Join(values, ", "))
 	}
+	return funcLines, nil
+}
+
+func rf_panic(statement *parser.Statement, g *gen, logic *generator.Logic, suffix string) (string, []string) {
 	modelName := generator.FirstUpperCamelCase(statement.TableName.Name)
 	funcLines := ""
-	uniqKeyPairs := getUniqKeyPairs(statement)
+	names := make([]string, 0)
+	binds := make([]string, 0)
+	for _, col := range statement.Columns {
+		names = append(names, g.ident(col.ColumnName.Name))
+		fieldName := generator.FirstUpperCamelCase(col.ColumnName.Name)
+		binds = append(binds, "&ret."+fieldName)
+	}
+	alive, conditions := "", `where = "where " + conditions`
+	if logic != nil {
+		alive, conditions = "where "+quote(logic.Alive(g.name)), `where += " and " + conditions`
+	}
+	orders := getOrders(statement, g)
+	for _, order := range orders {
+		SQL1 := fmt.Sprintf("select count(*) from "+g.ident("%s")+" %%s", statement.TableName.Name)
+		SQL2 := fmt.Sprintf("select %s from "+g.ident("%s")+" %%s %s", strings.Join(names, ", "), statement.TableName.Name, g.page(order.Statement))
+		params := "f *" + modelName + "Filter, page int"
+		sortLines := ""
+		if g.Sort && order.FuncSuffix == "" {
+			params = "f *" + modelName + "Filter, orders []Order, page int"
+			SQL2 = fmt.Sprintf("select %s from "+g.ident("%s")+" %%s "+g.page("\" + sort + \""), strings.Join(names, ", "), statement.TableName.Name)
+			sortLines = fmt.Sprintf(`
+    sort, sortErr := sortBy(orders, sortable%s)
+    if sortErr != nil {
+        panic(sortErr)
+    }`, modelName)
+		}
+		funcLines += fmt.Sprintf(`func QueryMany%sByFilter%s`+suffix+`(`+g.db()+`, %s, size int) (int, []*%s) {
+    if page <= 0 {
+        page = 1
+    }
+    if size <= 0 {
+        size = 10
+    }%s
+    SQL1 := "%s"
+    SQL2 := "%s"
+    where := "%s"
+    args := make([]interface{}, 0)
+    if f != nil {
+        conditions, values, err := f.where()
+        if err != nil {
+            panic(err)
+        }
+        if conditions != "" {
+            %s
+        }
+        args = values
+    }
+    SQL1 = fmt.Sprintf(SQL1, where)
+    SQL2 = fmt.Sprintf(SQL2, where)
+    count := 0
+    err := `+g.queryRow("SQL1")+`, args...).Scan(&count)
+    t.AssertErrorNil(err)
+    args = append(args, (page-1)*size, size)
+    rows, err := `+g.query("SQL2")+`, args...)
+    t.AssertErrorNil(err)
+    defer rows.Close()
+
+    results := make([]*%s, 0)
+    for rows.Next() {
+        ret := &%s{}
+        err = rows.Scan(%s)
+        t.AssertErrorNil(err)
+        results = append(results, ret)
+    }
+    return count, results
+}
+`, modelName, order.FuncSuffix, params, modelName, sortLines, g.prepared(SQL1), g.prepared(SQL2), alive, conditions, modelName, modelName, strings.Join(binds, ", "))
+	}
+	if g.Projection {
+		SQL1 := fmt.Sprintf("select count(*) from "+g.ident("%s")+" %%s", statement.TableName.Name)
+		SQL2 := fmt.Sprintf(" from "+g.ident("%s")+" %%s "+g.page(""), statement.TableName.Name)
+		params := "f *" + modelName + "Filter, columns []" + modelName + "Column, page int"
+		sortLines := ""
+		if g.Sort {
+			params = "f *" + modelName + "Filter, orders []Order, columns []" + modelName + "Column, page int"
+			SQL2 = fmt.Sprintf(" from "+g.ident("%s")+" %%s "+g.page("\" + sort + \""), statement.TableName.Name)
+			sortLines = fmt.Sprintf(`
+    sort, sortErr := sortBy(orders, sortable%s)
+    if sortErr != nil {
+        panic(sortErr)
+    }`, modelName)
+		}
+		funcLines += fmt.Sprintf(`func QueryMany%sByFilterColumns`+suffix+`(`+g.db()+`, %s, size int) (int, []*%s) {
+    if page <= 0 {
+        page = 1
+    }
+    if size <= 0 {
+        size = 10
+    }%s
+    names, _, err := project%s(&%s{}, columns)
+    if err != nil {
+        panic(err)
+    }
+    SQL1 := "%s"
+    SQL2 := "select " + names + "%s"
+    where := "%s"
+    args := make([]interface{}, 0)
+    if f != nil {
+        conditions, values, err := f.where()
+        if err != nil {
+            panic(err)
+        }
+        if conditions != "" {
+            %s
+        }
+        args = values
+    }
+    SQL1 = fmt.Sprintf(SQL1, where)
+    SQL2 = fmt.Sprintf(SQL2, where)
+    count := 0
+    err = `+g.queryRow("SQL1")+`, args...).Scan(&count)
+    t.AssertErrorNil(err)
+    args = append(args, (page-1)*size, size)
+    rows, err := `+g.query("SQL2")+`, args...)
+    t.AssertErrorNil(err)
+    defer rows.Close()
+
+    results := make([]*%s, 0)
+    for rows.Next() {
+        ret := &%s{}
+        _, binds, _ := project%s(ret, columns)
+        err = rows.Scan(binds...)
+        t.AssertErrorNil(err)
+        results = append(results, ret)
+    }
+    return count, results
+}
+`, modelName, params, modelName, sortLines, modelName, modelName, g.prepared(SQL1), SQL2, alive, conditions, modelName, modelName, modelName)
+	}
+	return funcLines, nil
+}
+
+func rp_panic(statement *parser.Statement, g *gen, logic *generator.Logic, suffix string) (string, []string) {
+	modelName := generator.FirstUpperCamelCase(statement.TableName.Name)
+	funcLines := ""
+	uniqKeyPairs := generator.UniqKeyPairs(statement)
 	for _, keys := range uniqKeyPairs {
 		fields := make([]string, 0)
 		conditions := make([]string, 0)
 		args := make([]string, 0)
 		for _, col := range keys {
+			conditions = append(conditions, g.ident(col.ColumnName.Name)+" = ?")
 			fieldName := generator.FirstUpperCamelCase(col.ColumnName.Name)
-			conditions = append(conditions, "`"+col.ColumnName.Name+"` = ?")
 			arg := "s." + fieldName
-			if col.Type == "DATE" || col.Type == "DATETIME" || col.Type == "TIMESTAMP" {
-				arg = "time.Time(" + arg + ")"
-				if round != "" {
-					arg = arg + ".Round(" + round + ")"
-				}
+			arg = timeArg(col, arg, g.times)
+			args = append(args, arg)
+			fields = append(fields, fieldName)
+		}
+		if logic != nil {
+			conditions = append(conditions, quote(logic.Alive(g.name)))
+		}
+		SQL := fmt.Sprintf(" from "+g.ident("%s")+" where %s", statement.TableName, strings.Join(conditions, " and "))
+		funcLines += fmt.Sprintf(`func Query%sBy%sColumns`+suffix+`(`+g.db()+`, s *%s, columns []%sColumn) *%s {
+    if s == nil {
+        t.AssertErrorNil(fmt.Errorf("pointer can not be nil"))
+    }
+    ret := &%s{}
+    names, binds, err := project%s(ret, columns)
+    if err != nil {
+        panic(err)
+    }
+    SQL := "select " + names + "%s"
+    err = `+g.queryRow("SQL")+`, %s).Scan(binds...)
+    if err != nil {
+        if err != sql.ErrNoRows {
+            t.AssertErrorNil(err)
+        }
+        return nil
+    }
+    return ret
+}
+`, modelName, strings.Join(fields, ""), modelName, modelName, modelName, modelName, modelName, SQL, strings.Join(args, ", "))
+	}
+
+	where := `where := ""
+    args := make([]interface{}, 0)
+    if s != nil {
+`
+	for _, col := range statement.Columns {
+		fieldName := generator.FirstUpperCamelCase(col.ColumnName.Name)
+		arg := "s." + fieldName
+		arg = timeArg(col, arg, g.times)
+		where += fmt.Sprintf(`        if %s != nil {
+            where += "and `+g.ident("%s")+` = ? "
+            args = append(args, %s)
+        }
+`, "s."+fieldName, col.ColumnName, arg)
+	}
+
+	where += `        where = strings.TrimLeft(where, "and")
+        where = strings.TrimSpace(where)
+        if where != "" {
+            where = "where " + where
+        }
+    }
+    SQL1 = fmt.Sprintf(SQL1, where)
+    SQL2 = fmt.Sprintf(SQL2, where)`
+	if logic != nil {
+		where = strings.Replace(where, `where := ""`, `where := "and `+quote(logic.Alive(g.name))+` "`, 1)
+		where = strings.Replace(where, `        where = strings.TrimLeft(where, "and")
+        where = strings.TrimSpace(where)
+        if where != "" {
+            where = "where " + where
+        }
+    }`, `    }
+    where = strings.TrimLeft(where, "and")
+    where = strings.TrimSpace(where)
+    where = "where " + where`, 1)
+	}
+
+	SQL1 := fmt.Sprintf("select count(*) from "+g.ident("%s")+" %%s", statement.TableName.Name)
+	SQL2 := fmt.Sprintf(" from "+g.ident("%s")+" %%s "+g.page(""), statement.TableName.Name)
+	params := "s *" + modelName + ", columns []" + modelName + "Column, page int"
+	sortLines := ""
+	if g.Sort {
+		params = "s *" + modelName + ", orders []Order, columns []" + modelName + "Column, page int"
+		SQL2 = fmt.Sprintf(" from "+g.ident("%s")+" %%s "+g.page("\" + sort + \""), statement.TableName.Name)
+		sortLines = fmt.Sprintf(`
+    sort, sortErr := sortBy(orders, sortable%s)
+    if sortErr != nil {
+        panic(sortErr)
+    }`, modelName)
+	}
+	funcLines += fmt.Sprintf(`func QueryMany%sColumns`+suffix+`(`+g.db()+`, %s, size int) (int, []*%s) {
+    if page <= 0 {
+        page = 1
+    }
+    if size <= 0 {
+        size = 10
+    }%s
+    names, _, err := project%s(&%s{}, columns)
+    if err != nil {
+        panic(err)
+    }
+    SQL1 := "%s"
+    SQL2 := "select " + names + "%s"
+    %s
+    count := 0
+    err = `+g.queryRow("SQL1")+`, args...).Scan(&count)
+    t.AssertErrorNil(err)
+    args = append(args, (page-1)*size, size)
+    rows, err := `+g.query("SQL2")+`, args...)
+    if err != nil {
+        if err != sql.ErrNoRows {
+            t.AssertErrorNil(err)
+        }
+        return 0, nil
+    }
+    defer rows.Close()
+
+    results := make([]*%s, 0)
+    for rows.Next() {
+        ret := &%s{}
+        _, binds, _ := project%s(ret, columns)
+        err = rows.Scan(binds...)
+        t.AssertErrorNil(err)
+        results = append(results, ret)
+    }
+    return count, results
+}
+`, modelName, params, modelName, sortLines, modelName, modelName, g.prepared(SQL1), SQL2, where, modelName, modelName, modelName)
+	return funcLines, nil
+}
+
+func cnt_panic(statement *parser.Statement, g *gen, logic *generator.Logic, suffix string) (string, []string) {
+	modelName := generator.FirstUpperCamelCase(statement.TableName.Name)
+	funcLines := fmt.Sprintf(`func Count%s`+suffix+`(`+g.db()+`, s *%s) int {
+    SQL := "select count(*) from `+g.ident("%s")+` %%s"
+    %s
+    count := 0
+    err := `+g.queryRow("SQL")+`, args...).Scan(&count)
+    t.AssertErrorNil(err)
+    return count
+}
+`, modelName, modelName, statement.TableName.Name, modelWhere(statement, g, logic))
+	for _, exists := range []bool{false, true} {
+		keyPairs := generator.IndexKeyPairs(statement)
+		if exists {
+			keyPairs = generator.UniqKeyPairs(statement)
+		}
+		for _, keys := range keyPairs {
+			fields := make([]string, 0)
+			conditions := make([]string, 0)
+			args := make([]string, 0)
+			for _, col := range keys {
+				conditions = append(conditions, g.ident(col.ColumnName.Name)+" = ?")
+				fieldName := generator.FirstUpperCamelCase(col.ColumnName.Name)
+				arg := "s." + fieldName
+				arg = timeArg(col, arg, g.times)
+				args = append(args, arg)
+				fields = append(fields, fieldName)
+			}
+			if logic != nil {
+				conditions = append(conditions, quote(logic.Alive(g.name)))
+			}
+			SQL := fmt.Sprintf("select count(*) from "+g.ident("%s")+" where %s", statement.TableName.Name, strings.Join(conditions, " and "))
+			name, typ, ret := "Count", "int", "count"
+			if exists {
+				name, typ, ret = "Exists", "bool", "count > 0"
 			}
+			funcLines += fmt.Sprintf(`func %s%sBy%s`+suffix+`(`+g.db()+`, s *%s) %s {
+    if s == nil {
+        t.AssertErrorNil(fmt.Errorf("pointer can not be nil"))
+    }
+    SQL := "%s"
+    count := 0
+    err := `+g.queryRow("SQL")+`, %s).Scan(&count)
+    t.AssertErrorNil(err)
+    return %s
+}
+`, name, modelName, strings.Join(fields, ""), modelName, typ, g.prepared(SQL), strings.Join(args, ", "), ret)
+		}
+	}
+	return funcLines, nil
+}
+
+func agg_panic(statement *parser.Statement, g *gen, logic *generator.Logic, suffix string) (string, []string) {
+	modelName := generator.FirstUpperCamelCase(statement.TableName.Name)
+	funcLines := ""
+	for _, col := range statement.Columns {
+		typ, null, field := "int64", "sql.NullInt64", "Int64"
+		switch col.Type {
+		case "TINYINT", "INT", "BIGINT":
+		case "FLOAT":
+			typ, null, field = "float64", "sql.NullFloat64", "Float64"
+		default:
+			continue
+		}
+		fieldName := generator.FirstUpperCamelCase(col.ColumnName.Name)
+		for _, fn := range []string{"Sum", "Max", "Min"} {
+			funcLines += fmt.Sprintf(`func %s%s%s`+suffix+`(`+g.db()+`, s *%s) %s {
+    SQL := "select %s(`+g.ident("%s")+`) from `+g.ident("%s")+` %%s"
+    %s
+    ret := %s{}
+    err := `+g.queryRow("SQL")+`, args...).Scan(&ret)
+    t.AssertErrorNil(err)
+    return ret.%s
+}
+`, fn, modelName, fieldName, modelName, typ, strings.ToLower(fn), col.ColumnName.Name, statement.TableName.Name, modelWhere(statement, g, logic), null, field)
+		}
+	}
+	return funcLines, nil
+}
+
+func each_panic(statement *parser.Statement, g *gen, logic *generator.Logic, suffix string) (string, []string) {
+	modelName := generator.FirstUpperCamelCase(statement.TableName.Name)
+	names := make([]string, 0)
+	binds := make([]string, 0)
+	for _, col := range statement.Columns {
+		names = append(names, g.ident(col.ColumnName.Name))
+		binds = append(binds, "&ret."+generator.FirstUpperCamelCase(col.ColumnName.Name))
+	}
+	SQL := fmt.Sprintf("select %s from "+g.ident("%s")+" %%s", strings.Join(names, ", "), statement.TableName.Name)
+	where := modelWhere(statement, g, logic)
+	funcLines := fmt.Sprintf(`func Each%s`+suffix+`(`+g.db()+`, s *%s, fn func(*%s) bool) {
+    SQL := "%s"
+    %s
+    rows, err := `+g.query("SQL")+`, args...)
+    t.AssertErrorNil(err)
+    defer rows.Close()
+    for rows.Next() {
+        ret := &%s{}
+        err = rows.Scan(%s)
+        t.AssertErrorNil(err)
+        if !fn(ret) {
+            return
+        }
+    }
+    t.AssertErrorNil(rows.Err())
+}
+func Iter%s`+suffix+`(`+g.db()+`, s *%s) iter.Seq[*%s] {
+    return func(yield func(*%s) bool) {
+        SQL := "%s"
+        %s
+        rows, err := `+g.query("SQL")+`, args...)
+        t.AssertErrorNil(err)
+        defer rows.Close()
+        for rows.Next() {
+            ret := &%s{}
+            err = rows.Scan(%s)
+            t.AssertErrorNil(err)
+            if !yield(ret) {
+                return
+            }
+        }
+        t.AssertErrorNil(rows.Err())
+    }
+}
+`, modelName, modelName, modelName, g.prepared(SQL), where, modelName, strings.Join(binds, ", "),
+		modelName, modelName, modelName, modelName, g.prepared(SQL), where, modelName, strings.Join(binds, ", "))
+	return funcLines, []string{"iter"}
+}
+
+func um_panic(statement *parser.Statement, g *gen, logic *generator.Logic) (string, []string) {
+	modelName := generator.FirstUpperCamelCase(statement.TableName.Name)
+	funcLines := ""
+	for _, keys := range generator.IndexKeyPairs(statement) {
+		version := generator.VersionColumn(statement)
+		if version != nil && contains(keys, version) {
+			version = nil
+		}
+		set := `set := ""
+    args := make([]interface{}, 0)
+    `
+		for _, col := range statement.Columns {
+			if col.AutoIncrement || col.CurrentTimestamp {
+				continue
+			}
+			if contains(keys, col) || col == version {
+				continue
+			}
+			fieldName := generator.FirstUpperCamelCase(col.ColumnName.Name)
+			arg := "s." + fieldName
+			arg = timeArg(col, arg, g.times)
+			set += fmt.Sprintf(`if %s != nil {
+        set += ", `+g.ident("%s")+` = ? "
+        args = append(args, %s)
+    }
+    `, "s."+fieldName, col.ColumnName, arg)
+		}
+		set += `set = strings.TrimLeft(set, ",")
+    set = strings.TrimSpace(set)
+    if set == "" {
+        t.AssertErrorNil(fmt.Errorf("all field is nil"))
+    }
+    SQL = fmt.Sprintf(SQL, set)`
+		fields, conditions, args, guard := indexConditions(keys, g)
+		if logic != nil {
+			conditions = append(conditions, quote(logic.Alive(g.name)))
+		}
+		SQL := fmt.Sprintf("update "+g.ident("%s")+" set %%s where %s", statement.TableName.Name, strings.Join(conditions, " and "))
+		if version != nil {
+			SQL = fmt.Sprintf("update "+g.ident("%s")+" set %%s, "+g.ident("%s")+" = "+g.ident("%s")+" + 1 where %s", statement.TableName.Name, version.ColumnName.Name, version.ColumnName.Name, strings.Join(conditions, " and "))
+		}
+		funcLines += fmt.Sprintf(`func UpdateMany%sBy%s(`+g.db()+`, s *%s, limit int) int64 {
+    if s == nil {
+        t.AssertErrorNil(fmt.Errorf("pointer can not be nil"))
+    }
+    if %s {
+        t.AssertErrorNil(fmt.Errorf("index field can not be nil"))
+    }
+    SQL := "%s"
+    %s
+    args = append(args, %s)
+%s    ret, err := `+g.exec("SQL")+`, args...)
+    t.AssertErrorNil(err)
+    count, err := ret.RowsAffected()
+    t.AssertErrorNil(err)
+    return count
+}
+`, modelName, strings.Join(fields, ""), modelName, guard, g.prepared(SQL), set, strings.Join(args, ", "), limitLines_panic(statement, g, conditions, args))
+	}
+	return funcLines, nil
+}
+
+func dm_panic(statement *parser.Statement, g *gen, logic *generator.Logic) (string, []string) {
+	modelName := generator.FirstUpperCamelCase(statement.TableName.Name)
+	funcLines := ""
+	for _, keys := range generator.IndexKeyPairs(statement) {
+		fields, conditions, args, guard := indexConditions(keys, g)
+		if logic != nil {
+			conditions = append(conditions, quote(logic.Alive(g.name)))
+		}
+		SQL := fmt.Sprintf("delete from "+g.ident("%s")+" where %s", statement.TableName, strings.Join(conditions, " and "))
+		if logic != nil {
+			SQL = fmt.Sprintf("update "+g.ident("%s")+" set "+g.ident("%s")+" = %s where %s", statement.TableName, logic.Column, quote(logic.Deleted), strings.Join(conditions, " and "))
+		}
+		funcLines += fmt.Sprintf(`func DeleteMany%sBy%s(`+g.db()+`, s *%s, limit int) int64 {
+    if s == nil {
+        t.AssertErrorNil(fmt.Errorf("pointer can not be nil"))
+    }
+    if %s {
+        t.AssertErrorNil(fmt.Errorf("index field can not be nil"))
+    }
+    SQL := "%s"
+    args := []interface{}{%s}
+%s    ret, err := `+g.exec("SQL")+`, args...)
+    t.AssertErrorNil(err)
+    count, err := ret.RowsAffected()
+    t.AssertErrorNil(err)
+    return count
+}
+`, modelName, strings.Join(fields, ""), modelName, guard, g.prepared(SQL), strings.Join(args, ", "), limitLines_panic(statement, g, conditions, args))
+	}
+	return funcLines, nil
+}
+
+func limitLines_panic(statement *parser.Statement, g *gen, conditions []string, args []string) string {
+	if g.dialect() == MySQL {
+		return `    if limit > 0 {
+        SQL += " limit ?"
+        args = append(args, limit)
+    }
+`
+	}
+	primaryKeys := getPrimaryKeys(statement)
+	if len(primaryKeys) != 1 {
+		return `    if limit > 0 {
+        t.AssertErrorNil(fmt.Errorf("limit needs a single column primary key"))
+    }
+`
+	}
+	key := g.ident(primaryKeys[0].ColumnName.Name)
+	return fmt.Sprintf(`    if limit > 0 {
+        SQL += " and %s in (select %s from `+g.ident("%s")+` where %s order by %s `+g.limit()+`)"
+        args = append(args, %s, limit)
+    }
+`, key, key, statement.TableName.Name, strings.Join(conditions, " and "), key, strings.Join(args, ", "))
+}
+
+func d_panic(statement *parser.Statement, g *gen, logic *generator.Logic) (string, []string) {
+	modelName := generator.FirstUpperCamelCase(statement.TableName.Name)
+	funcLines := ""
+	uniqKeyPairs := generator.UniqKeyPairs(statement)
+	for _, keys := range uniqKeyPairs {
+		fields := make([]string, 0)
+		conditions := make([]string, 0)
+		args := make([]string, 0)
+		for _, col := range keys {
+			fieldName := generator.FirstUpperCamelCase(col.ColumnName.Name)
+			conditions = append(conditions, g.ident(col.ColumnName.Name)+" = ?")
+			arg := "s." + fieldName
+			arg = timeArg(col, arg, g.times)
 			args = append(args, arg)
 			fields = append(fields, fieldName)
 		}
 		SQL := ""
-		if logicDelete {
-			SQL = fmt.Sprintf("update `%s` set `%s` = %s where %s", statement.TableName, logicCol, logicValue, strings.Join(conditions, " and "))
+		if logic != nil {
+			SQL = fmt.Sprintf("update "+g.ident("%s")+" set "+g.ident("%s")+" = %s where %s", statement.TableName, logic.Column, quote(logic.Deleted), strings.Join(conditions, " and "))
 		} else {
-			SQL = fmt.Sprintf("delete from `%s` where %s", statement.TableName, strings.Join(conditions, " and "))
+			SQL = fmt.Sprintf("delete from "+g.ident("%s")+" where %s", statement.TableName, strings.Join(conditions, " and "))
 		}
-		funcTemplate := `func %sDelete%sBy%s(db DataSource, s *%s) int64{
+		funcTemplate := `func %s%sBy%s(` + g.db() + `, s *%s) int64{
     if s == nil {
         t.AssertErrorNil(fmt.Errorf("pointer can not be nil"))
     }
     SQL := "%s"
-    ret, err := db.Exec(SQL, %s)
+    ret, err := ` + g.exec("SQL") + `, %s)
     t.AssertErrorNil(err)
     count, err := ret.RowsAffected()
     t.AssertErrorNil(err)
 	return count
 }
 `
-		funcLines += fmt.Sprintf(funcTemplate, "", modelName, strings.Join(fields, ""), modelName, SQL, strings.Join(args, ", "))
-		if logicDelete {
-			if unDeleteValue, ok := unDeleteMap[logicValue]; ok {
-				UNSQL := fmt.Sprintf("update `%s` set `%s` = %s where %s", statement.TableName, logicCol, unDeleteValue, strings.Join(conditions, " and "))
-				funcLines += fmt.Sprintf(funcTemplate, "Un", modelName, strings.Join(fields, ""), modelName, UNSQL, strings.Join(args, ", "))
+		funcLines += fmt.Sprintf(funcTemplate, g.audited("Delete"), modelName, strings.Join(fields, ""), modelName, g.prepared(SQL), strings.Join(args, ", "))
+		if logic != nil {
+			if logic.Undeleted != "" {
+				UNSQL := fmt.Sprintf("update "+g.ident("%s")+" set "+g.ident("%s")+" = %s where %s", statement.TableName, logic.Column, quote(logic.Undeleted), strings.Join(conditions, " and "))
+				funcLines += fmt.Sprintf(funcTemplate, g.audited("UnDelete"), modelName, strings.Join(fields, ""), modelName, g.prepared(UNSQL), strings.Join(args, ", "))
 			}
 		}
 
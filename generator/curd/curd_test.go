@@ -29,11 +29,23 @@ create table tb_dept2(
     Name varchar(18),#部门名称
     description varchar(100),#描述
 	created_date datetime,
+	version int,
 	key idx_c (created_date)
 );
 `
 
 	s := parser.Parse(sql)
-	file := Generate("model", s, true, "", "id", "created_date", "s")
+	file := Generate("model", s, &Options{Banner: true, Asc: "id", Desc: "created_date", Round: "s", Dialect: "mysql"})
+	t.Log(file)
+	file = Generate("model", s, &Options{Banner: true, Logic: "status=1,tb_dept2.created_date", Asc: "id", Desc: "created_date", Round: "s", Zone: "utc", Dialect: "postgres", Ctx: true, Tx: true, Keyset: true, Filter: true, Sort: true, Projection: true, Aggregate: true, Stream: true, Prepare: true, Mask: true, Audit: true})
+	t.Log(file)
+	file = GeneratePanic("model", s, &Options{Banner: true, Logic: "status=1", Asc: "id", Desc: "created_date", Round: "s", Dialect: "sqlserver", Ctx: true, Prepare: true, Mask: true, Audit: true})
+	t.Log(file)
+	t.Log(GenerateAuditLog("postgres", true))
+	file = GenerateTest("model", s, &Options{Banner: true, Logic: "status=1", Zone: "local", Dialect: "sqlserver", Ctx: true, Sort: true}, true)
+	t.Log(file)
+	file = GenerateRepository("model", s, &Options{Banner: true, Logic: "status=1", Round: "auto", Zone: "utc", Dialect: "postgres", Ctx: true})
+	t.Log(file)
+	file = GenerateRepositoryRuntime("model", &Options{Banner: true, Dialect: "postgres", Ctx: true}, true)
 	t.Log(file)
 }
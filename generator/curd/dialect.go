@@ -0,0 +1,177 @@
+// Copyright 2010-2024 the original author or authors.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+// 	http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package curd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/stella-go/stella/generator/parser"
+)
+
+// The templates write the statements in the dialect, identifiers are quoted and pages written for it
+// when the functions are generated, the dialects numbering the placeholders number them with bind at runtime
+// because the where clauses are built at runtime.
+const (
+	MySQL     = "mysql"
+	Postgres  = "postgres"
+	SQLite    = "sqlite"
+	SQLServer = "sqlserver"
+)
+
+var dialectImports = map[string][]string{
+	Postgres:  {"strconv"},
+	SQLServer: {"strconv"},
+}
+
+// bindLines number the placeholders of a statement, %s is the prefix of the numbers.
+const bindLines = `
+
+// bind numbers the placeholders of query, the question marks in string literals are left alone.
+func bind(query string) string {
+    b := strings.Builder{}
+    n := 0
+    quoted := false
+    for _, c := range query {
+        switch {
+        case c == '\'':
+            quoted = !quoted
+        case c == '?' && !quoted:
+            n++
+            b.WriteString("%s" + strconv.Itoa(n))
+            continue
+        }
+        b.WriteRune(c)
+    }
+    return b.String()
+}`
+
+var placeholders = map[string]string{
+	Postgres:  "$",
+	SQLServer: "@p",
+}
+
+var deadlocks = map[string]string{
+	MySQL:     `strings.Contains(message, "Error 1213") || strings.Contains(message, "Error 1205")`,
+	Postgres:  `strings.Contains(message, "40P01") || strings.Contains(message, "40001") || strings.Contains(message, "deadlock detected")`,
+	SQLite:    `strings.Contains(message, "database is locked") || strings.Contains(message, "SQLITE_BUSY")`,
+	SQLServer: `strings.Contains(message, "deadlocked") || strings.Contains(message, "Error 1205")`,
+}
+
+// dialect is the dialect of the options, mysql when none is set.
+func (g *gen) dialect() string {
+	if g.Dialect == "" {
+		return MySQL
+	}
+	return g.Dialect
+}
+
+// name quotes the identifier n.
+func (g *gen) name(n string) string {
+	switch g.dialect() {
+	case Postgres, SQLite:
+		return `"` + n + `"`
+	case SQLServer:
+		return "[" + n + "]"
+	default:
+		return "`" + n + "`"
+	}
+}
+
+// ident is the quoted identifier n in a Go string literal.
+func (g *gen) ident(n string) string {
+	return quote(g.name(n))
+}
+
+// identOf is the quoted identifier the generated code holds in the string expression expr, in a Go string literal.
+func (g *gen) identOf(expr string) string {
+	return strings.Replace(g.ident("%s"), "%s", `"+`+expr+`+"`, 1)
+}
+
+// page returns the rows of a query sorted by order from the offset argument, as many as the size argument,
+// sql server pages sorted rows only.
+func (g *gen) page(order string) string {
+	switch g.dialect() {
+	case Postgres:
+		return order + "offset ? limit ?"
+	case SQLServer:
+		if order == "" {
+			order = "order by (select null) "
+		}
+		return order + "offset ? rows fetch next ? rows only"
+	default:
+		return order + "limit ?, ?"
+	}
+}
+
+// limit returns as many rows of a sorted query as its argument.
+func (g *gen) limit() string {
+	if g.dialect() == SQLServer {
+		return "offset 0 rows fetch next ? rows only"
+	}
+	return "limit ?"
+}
+
+// bind passes the statement the generated code holds in query to bind in the dialects numbering the placeholders.
+func (g *gen) bind(query string) string {
+	if _, ok := placeholders[g.dialect()]; ok {
+		return "bind(" + query + ")"
+	}
+	return query
+}
+
+// bindLines declares bind in the dialects numbering the placeholders.
+func (g *gen) bindLines() string {
+	prefix, ok := placeholders[g.dialect()]
+	if !ok {
+		return ""
+	}
+	return fmt.Sprintf(bindLines, prefix)
+}
+
+// like matches a column, the dialects but mysql escape with a backslash only when told to.
+func (g *gen) like() string {
+	if g.dialect() == MySQL {
+		return `" like ?"`
+	}
+	return `" like ? escape '\\'"`
+}
+
+// likeEscapes are the characters a prefix escapes, sql server reads brackets as character sets.
+func (g *gen) likeEscapes() string {
+	if g.dialect() == SQLServer {
+		return `"\\", "\\\\", "%", "\\%", "_", "\\_", "[", "\\["`
+	}
+	return `"\\", "\\\\", "%", "\\%", "_", "\\_"`
+}
+
+// upsertable tells whether the rows of the unique key keys can be upserted, postgres and sql server
+// generate the identity column and refuse the values inserted into it.
+func (g *gen) upsertable(keys []*parser.ColumnDefinition) bool {
+	switch g.dialect() {
+	case Postgres, SQLServer:
+		for _, col := range keys {
+			if col.AutoIncrement {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// deadlock tells the errors of a deadlock or a lock timeout WithTxRetry retries.
+func (g *gen) deadlock() string {
+	return deadlocks[g.dialect()]
+}
@@ -0,0 +1,129 @@
+// Copyright 2010-2024 the original author or authors.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package curd
+
+import (
+	"strings"
+
+	"github.com/stella-go/stella/generator"
+)
+
+// Options are the flags of the command the generated functions depend on.
+type Options struct {
+	Banner     bool
+	Logic      string
+	Asc        string
+	Desc       string
+	Round      string
+	Zone       string
+	Dialect    string
+	Ctx        bool
+	Tx         bool
+	Keyset     bool
+	Filter     bool
+	Sort       bool
+	Projection bool
+	Aggregate  bool
+	Stream     bool
+	Prepare    bool
+	Mask       bool
+	Audit      bool
+}
+
+// gen is the state of a generation shared by the templates, the options, the time policy of the columns
+// and the fixed statements -prepare prepares.
+type gen struct {
+	*Options
+	times   *generator.TimePolicy
+	queries []string
+}
+
+func newGen(o *Options) *gen {
+	return &gen{Options: o, times: generator.ParseTimePolicy(o.Round, o.Zone)}
+}
+
+// db is the DataSource parameter of the generated functions, after a context.Context with -ctx.
+func (g *gen) db() string {
+	if g.Ctx {
+		return "ctx context.Context, db DataSource"
+	}
+	return "db DataSource"
+}
+
+func (g *gen) exec(query string) string {
+	return g.call("Exec", query)
+}
+
+func (g *gen) queryRow(query string) string {
+	return g.call("QueryRow", query)
+}
+
+func (g *gen) query(query string) string {
+	return g.call("Query", query)
+}
+
+// call opens the call of a method of the DataSource, its Context variant with -ctx, on the statement held by query.
+func (g *gen) call(method string, query string) string {
+	if g.Ctx {
+		return "db." + method + "Context(ctx, " + g.bind(query)
+	}
+	return "db." + method + "(" + g.bind(query)
+}
+
+// prepared records a statement for -prepare and returns it, the statements completed at runtime are left out.
+func (g *gen) prepared(query string) string {
+	if strings.Contains(query, "%") {
+		return query
+	}
+	for _, q := range g.queries {
+		if q == query {
+			return query
+		}
+	}
+	g.queries = append(g.queries, query)
+	return query
+}
+
+// audited is the name of a write -audit wraps, unexported with -audit for the exported one to record the change.
+func (g *gen) audited(name string) string {
+	if g.Audit {
+		return strings.ToLower(name[:1]) + name[1:]
+	}
+	return name
+}
+
+// begin is the call beginning a transaction on a *sql.DB, BeginTx with -ctx.
+func (g *gen) begin() string {
+	if g.Ctx {
+		return "BeginTx(ctx, nil)"
+	}
+	return "Begin()"
+}
+
+// txArgs are the arguments passing a transaction tx on in place of the DataSource, ctx first with -ctx.
+func (g *gen) txArgs() string {
+	if g.Ctx {
+		return "ctx, tx"
+	}
+	return "tx"
+}
+
+// args are the arguments passing the DataSource parameter on, ctx first with -ctx.
+func (g *gen) args() string {
+	if g.Ctx {
+		return "ctx, db"
+	}
+	return "db"
+}
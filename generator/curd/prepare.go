@@ -0,0 +1,162 @@
+// Copyright 2010-2024 the original author or authors.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+// 	http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package curd
+
+import (
+	"fmt"
+	"strings"
+)
+
+// queriesLines are a DataSource which runs the prepared statements for the queries it knows,
+// the other queries, whose where clauses are built at runtime, go to the database unprepared.
+// The methods are the Context variants with -ctx, %[1]s is their suffix, %[2]s the ctx parameter and %[3]s the ctx argument.
+const queriesLines = `
+
+type Queries struct {
+    db    *sql.DB
+    tx    *sql.Tx
+    stmts map[string]*sql.Stmt
+}
+
+func (q *Queries) Close() error {
+    var ret error
+    for _, stmt := range q.stmts {
+        err := stmt.Close()
+        if err != nil && ret == nil {
+            ret = err
+        }
+    }
+    return ret
+}
+
+func (q *Queries) Exec%[1]s(%[2]squery string, args ...interface{}) (sql.Result, error) {
+    if stmt, ok := q.stmts[query]; ok {
+        return q.stmt(%[3]sstmt).Exec%[1]s(%[3]sargs...)
+    }
+    return q.source().Exec%[1]s(%[3]squery, args...)
+}
+
+func (q *Queries) QueryRow%[1]s(%[2]squery string, args ...interface{}) *sql.Row {
+    if stmt, ok := q.stmts[query]; ok {
+        return q.stmt(%[3]sstmt).QueryRow%[1]s(%[3]sargs...)
+    }
+    return q.source().QueryRow%[1]s(%[3]squery, args...)
+}
+
+func (q *Queries) Query%[1]s(%[2]squery string, args ...interface{}) (*sql.Rows, error) {
+    if stmt, ok := q.stmts[query]; ok {
+        return q.stmt(%[3]sstmt).Query%[1]s(%[3]sargs...)
+    }
+    return q.source().Query%[1]s(%[3]squery, args...)
+}
+
+func (q *Queries) stmt(%[2]sstmt *sql.Stmt) *sql.Stmt {
+    if q.tx != nil {
+        return q.tx.Stmt%[1]s(%[3]sstmt)
+    }
+    return stmt
+}
+
+func (q *Queries) source() DataSource {
+    if q.tx != nil {
+        return q.tx
+    }
+    return q.db
+}`
+
+// prepareLines prepare the statements and bind them to a transaction in WithTx.
+const prepareLines = `
+
+func Prepare(ctx context.Context, db *sql.DB) (*Queries, error) {
+    q := &Queries{db: db, stmts: make(map[string]*sql.Stmt, len(preparedQueries))}
+    for _, query := range preparedQueries {
+        stmt, err := db.PrepareContext(ctx, query)
+        if err != nil {
+            q.Close()
+            return nil, t.Error(err)
+        }
+        q.stmts[query] = stmt
+    }
+    return q, nil
+}
+
+func (q *Queries) WithTx(ctx context.Context, opts *sql.TxOptions, fn func(tx DataSource) error) error {
+    tx, err := q.db.BeginTx(ctx, opts)
+    if err != nil {
+        return t.Error(err)
+    }
+    defer func() {
+        if r := recover(); r != nil {
+            tx.Rollback()
+            panic(r)
+        }
+    }()
+    err = fn(&Queries{db: q.db, tx: tx, stmts: q.stmts})
+    if err != nil {
+        tx.Rollback()
+        return err
+    }
+    err = tx.Commit()
+    if err != nil {
+        return t.Error(err)
+    }
+    return nil
+}`
+
+const preparePanicLines = `
+
+func Prepare(ctx context.Context, db *sql.DB) *Queries {
+    q := &Queries{db: db, stmts: make(map[string]*sql.Stmt, len(preparedQueries))}
+    for _, query := range preparedQueries {
+        stmt, err := db.PrepareContext(ctx, query)
+        if err != nil {
+            q.Close()
+            t.AssertErrorNil(err)
+        }
+        q.stmts[query] = stmt
+    }
+    return q
+}
+
+func (q *Queries) WithTx(ctx context.Context, opts *sql.TxOptions, fn func(tx DataSource)) {
+    tx, err := q.db.BeginTx(ctx, opts)
+    t.AssertErrorNil(err)
+    defer func() {
+        if r := recover(); r != nil {
+            tx.Rollback()
+            panic(r)
+        }
+    }()
+    fn(&Queries{db: q.db, tx: tx, stmts: q.stmts})
+    t.AssertErrorNil(tx.Commit())
+}`
+
+// queriesLines adds the Queries type and the fixed statements the templates recorded for it.
+func (g *gen) queriesLines(panicStyle bool) string {
+	lines := fmt.Sprintf(queriesLines, "", "", "")
+	if g.Ctx {
+		lines = fmt.Sprintf(queriesLines, "Context", "ctx context.Context, ", "ctx, ")
+	}
+	if panicStyle {
+		lines += preparePanicLines
+	} else {
+		lines += prepareLines
+	}
+	queries := make([]string, 0)
+	for _, query := range g.queries {
+		queries = append(queries, "    "+g.bind("\""+query+"\"")+",")
+	}
+	return lines + "\n\nvar preparedQueries = []string{\n" + strings.Join(queries, "\n") + "\n}"
+}
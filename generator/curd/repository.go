@@ -0,0 +1,737 @@
+// Copyright 2010-2024 the original author or authors.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package curd
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/stella-go/stella/common"
+	"github.com/stella-go/stella/generator"
+	"github.com/stella-go/stella/generator/parser"
+	"github.com/stella-go/stella/version"
+)
+
+// repositoryLines are the generic runtime shared by the tables, a table only contributes its Meta
+// and the values and scan targets of its columns.
+func repositoryLines(g *gen) string {
+	return `
+
+var ErrStaleObject = errors.New("stale object")
+
+// Meta describes a table, Keys, Managed, Auto and Version are positions in Columns, -1 when absent.
+type Meta struct {
+    Table     string
+    Columns   []string
+    Keys      []int
+    Managed   []int
+    Auto      int
+    Version   int
+    Alive     string
+    Deleted   string
+    Undeleted string
+}
+
+type Model interface {
+    TableMeta() *Meta
+    ColumnValues() []interface{}
+    ColumnBinds() []interface{}
+}
+
+type Repository[T Model] struct {
+    meta *Meta
+    new  func() T
+}
+
+func NewRepository[T Model](new func() T) *Repository[T] {
+    return &Repository[T]{meta: new().TableMeta(), new: new}
+}
+
+func (r *Repository[T]) Create(` + g.db() + `, s T) (int64, error) {
+    values := s.ColumnValues()
+    if values == nil {
+        return 0, t.Error(fmt.Errorf("pointer can not be nil"))
+    }
+    columns := make([]string, 0)
+    marks := make([]string, 0)
+    args := make([]interface{}, 0)
+    for i, v := range values {
+        if i == r.meta.Version && v == nil {
+            v = 0
+        }
+        if v == nil || hasPosition(r.meta.Managed, i) {
+            continue
+        }
+        columns = append(columns, "` + g.identOf("r.meta.Columns[i]") + `")
+        marks = append(marks, "?")
+        args = append(args, v)
+    }
+%s
+}
+
+func (r *Repository[T]) Update(` + g.db() + `, s T) (int64, error) {
+    values := s.ColumnValues()
+    if values == nil {
+        return 0, t.Error(fmt.Errorf("pointer can not be nil"))
+    }
+    if len(r.meta.Keys) == 0 {
+        return 0, t.Error(fmt.Errorf("table %%s has no primary key", r.meta.Table))
+    }
+    set := make([]string, 0)
+    args := make([]interface{}, 0)
+    for i, v := range values {
+        if v == nil || hasPosition(r.meta.Managed, i) || hasPosition(r.meta.Keys, i) || i == r.meta.Version {
+            continue
+        }
+        set = append(set, "` + g.identOf("r.meta.Columns[i]") + ` = ?")
+        args = append(args, v)
+    }
+    if len(set) == 0 {
+        return 0, t.Error(fmt.Errorf("all field is nil"))
+    }
+    where, keys := r.keys(values)
+    args = append(args, keys...)
+    if r.meta.Version >= 0 {
+        if values[r.meta.Version] == nil {
+            return 0, t.Error(fmt.Errorf("version can not be nil"))
+        }
+        version := "` + g.identOf("r.meta.Columns[r.meta.Version]") + `"
+        set = append(set, version+" = "+version+" + 1")
+        where += " and " + version + " = ?"
+        args = append(args, values[r.meta.Version])
+    }
+    SQL := fmt.Sprintf("update ` + g.ident("%%s") + ` set %%s where %%s", r.meta.Table, strings.Join(set, ", "), where)
+    ret, err := ` + g.exec("SQL") + `, args...)
+    if err != nil {
+        return 0, t.Error(err)
+    }
+    count, err := ret.RowsAffected()
+    if err != nil {
+        return 0, t.Error(err)
+    }
+    if r.meta.Version >= 0 && count == 0 {
+        return 0, ErrStaleObject
+    }
+    return count, nil
+}
+
+func (r *Repository[T]) Query(` + g.db() + `, s T) (T, error) {
+    var zero T
+    values := s.ColumnValues()
+    if values == nil {
+        return zero, t.Error(fmt.Errorf("pointer can not be nil"))
+    }
+    if len(r.meta.Keys) == 0 {
+        return zero, t.Error(fmt.Errorf("table %%s has no primary key", r.meta.Table))
+    }
+    where, args := r.keys(values)
+    if r.meta.Alive != "" {
+        where += " and " + r.meta.Alive
+    }
+    SQL := fmt.Sprintf("select %%s from ` + g.ident("%%s") + ` where %%s", r.columns(), r.meta.Table, where)
+    ret := r.new()
+    err := ` + g.queryRow("SQL") + `, args...).Scan(ret.ColumnBinds()...)
+    if err != nil {
+        if err != sql.ErrNoRows {
+            return zero, t.Error(err)
+        }
+        return zero, nil
+    }
+    return ret, nil
+}
+
+func (r *Repository[T]) QueryMany(` + g.db() + `, s T, page int, size int) (int, []T, error) {
+    if page <= 0 {
+        page = 1
+    }
+    if size <= 0 {
+        size = 10
+    }
+    conditions := make([]string, 0)
+    args := make([]interface{}, 0)
+    if r.meta.Alive != "" {
+        conditions = append(conditions, r.meta.Alive)
+    }
+    for i, v := range s.ColumnValues() {
+        if v == nil {
+            continue
+        }
+        conditions = append(conditions, "` + g.identOf("r.meta.Columns[i]") + ` = ?")
+        args = append(args, v)
+    }
+    where := ""
+    if len(conditions) != 0 {
+        where = "where " + strings.Join(conditions, " and ") + " "
+    }
+    SQL1 := fmt.Sprintf("select count(*) from ` + g.ident("%%s") + ` %%s", r.meta.Table, where)
+    count := 0
+    err := ` + g.queryRow("SQL1") + `, args...).Scan(&count)
+    if err != nil {
+        return 0, nil, t.Error(err)
+    }
+    SQL2 := fmt.Sprintf("select %%s from ` + g.ident("%%s") + ` %%s` + g.page("") + `", r.columns(), r.meta.Table, where)
+    args = append(args, (page-1)*size, size)
+    rows, err := ` + g.query("SQL2") + `, args...)
+    if err != nil {
+        return 0, nil, t.Error(err)
+    }
+    defer rows.Close()
+
+    results := make([]T, 0)
+    for rows.Next() {
+        ret := r.new()
+        err = rows.Scan(ret.ColumnBinds()...)
+        if err != nil {
+            return 0, nil, t.Error(err)
+        }
+        results = append(results, ret)
+    }
+    err = rows.Err()
+    if err != nil {
+        return 0, nil, t.Error(err)
+    }
+    return count, results, nil
+}
+
+func (r *Repository[T]) Delete(` + g.db() + `, s T) (int64, error) {
+    return r.delete(` + g.args() + `, s, r.meta.Deleted)
+}
+
+func (r *Repository[T]) UnDelete(` + g.db() + `, s T) (int64, error) {
+    if r.meta.Undeleted == "" {
+        return 0, t.Error(fmt.Errorf("table %%s has no undelete", r.meta.Table))
+    }
+    return r.delete(` + g.args() + `, s, r.meta.Undeleted)
+}
+
+func (r *Repository[T]) delete(` + g.db() + `, s T, set string) (int64, error) {
+    values := s.ColumnValues()
+    if values == nil {
+        return 0, t.Error(fmt.Errorf("pointer can not be nil"))
+    }
+    if len(r.meta.Keys) == 0 {
+        return 0, t.Error(fmt.Errorf("table %%s has no primary key", r.meta.Table))
+    }
+    where, args := r.keys(values)
+    SQL := fmt.Sprintf("delete from ` + g.ident("%%s") + ` where %%s", r.meta.Table, where)
+    if set != "" {
+        SQL = fmt.Sprintf("update ` + g.ident("%%s") + ` set %%s where %%s", r.meta.Table, set, where)
+    }
+    ret, err := ` + g.exec("SQL") + `, args...)
+    if err != nil {
+        return 0, t.Error(err)
+    }
+    count, err := ret.RowsAffected()
+    if err != nil {
+        return 0, t.Error(err)
+    }
+    return count, nil
+}
+
+func (r *Repository[T]) keys(values []interface{}) (string, []interface{}) {
+    conditions := make([]string, 0, len(r.meta.Keys))
+    args := make([]interface{}, 0, len(r.meta.Keys))
+    for _, i := range r.meta.Keys {
+        conditions = append(conditions, "` + g.identOf("r.meta.Columns[i]") + ` = ?")
+        args = append(args, values[i])
+    }
+    return strings.Join(conditions, " and "), args
+}
+
+func (r *Repository[T]) columns() string {
+    names := make([]string, 0, len(r.meta.Columns))
+    for _, column := range r.meta.Columns {
+        names = append(names, "` + g.identOf("column") + `")
+    }
+    return strings.Join(names, ", ")
+}
+
+func hasPosition(positions []int, i int) bool {
+    for _, p := range positions {
+        if p == i {
+            return true
+        }
+    }
+    return false
+}`
+}
+
+// repositoryCreateLines finish Create, the dialects without LastInsertId return the auto increment column.
+func repositoryCreateLines(g *gen) map[string]string {
+	return map[string]string{
+		MySQL: `    SQL := fmt.Sprintf("insert into ` + g.ident("%s") + ` (%s) values (%s)", r.meta.Table, strings.Join(columns, ", "), strings.Join(marks, ", "))
+    ret, err := ` + g.exec("SQL") + `, args...)
+    if err != nil {
+        return 0, t.Error(err)
+    }
+    _, err = ret.RowsAffected()
+    if err != nil {
+        return 0, t.Error(err)
+    }
+    return ret.LastInsertId()`,
+		Postgres: `    SQL := fmt.Sprintf("insert into ` + g.ident("%s") + ` (%s) values (%s)", r.meta.Table, strings.Join(columns, ", "), strings.Join(marks, ", "))
+    if r.meta.Auto < 0 {
+        _, err := ` + g.exec("SQL") + `, args...)
+        if err != nil {
+            return 0, t.Error(err)
+        }
+        return 0, nil
+    }
+    SQL += " returning ` + g.identOf("r.meta.Columns[r.meta.Auto]") + `"
+    id := int64(0)
+    err := ` + g.queryRow("SQL") + `, args...).Scan(&id)
+    if err != nil {
+        return 0, t.Error(err)
+    }
+    return id, nil`,
+		SQLServer: `    output := ""
+    if r.meta.Auto >= 0 {
+        output = " output inserted.` + g.identOf("r.meta.Columns[r.meta.Auto]") + `"
+    }
+    SQL := fmt.Sprintf("insert into ` + g.ident("%s") + ` (%s)%s values (%s)", r.meta.Table, strings.Join(columns, ", "), output, strings.Join(marks, ", "))
+    if r.meta.Auto < 0 {
+        _, err := ` + g.exec("SQL") + `, args...)
+        if err != nil {
+            return 0, t.Error(err)
+        }
+        return 0, nil
+    }
+    id := int64(0)
+    err := ` + g.queryRow("SQL") + `, args...).Scan(&id)
+    if err != nil {
+        return 0, t.Error(err)
+    }
+    return id, nil`,
+	}
+}
+
+func repositoryPanicLines(g *gen) string {
+	return `
+
+var ErrStaleObject = errors.New("stale object")
+
+// Meta describes a table, Keys, Managed, Auto and Version are positions in Columns, -1 when absent.
+type Meta struct {
+    Table     string
+    Columns   []string
+    Keys      []int
+    Managed   []int
+    Auto      int
+    Version   int
+    Alive     string
+    Deleted   string
+    Undeleted string
+}
+
+type Model interface {
+    TableMeta() *Meta
+    ColumnValues() []interface{}
+    ColumnBinds() []interface{}
+}
+
+type Repository[T Model] struct {
+    meta *Meta
+    new  func() T
+}
+
+func NewRepository[T Model](new func() T) *Repository[T] {
+    return &Repository[T]{meta: new().TableMeta(), new: new}
+}
+
+func (r *Repository[T]) Create(` + g.db() + `, s T) int64 {
+    values := s.ColumnValues()
+    if values == nil {
+        t.AssertErrorNil(fmt.Errorf("pointer can not be nil"))
+    }
+    columns := make([]string, 0)
+    marks := make([]string, 0)
+    args := make([]interface{}, 0)
+    for i, v := range values {
+        if i == r.meta.Version && v == nil {
+            v = 0
+        }
+        if v == nil || hasPosition(r.meta.Managed, i) {
+            continue
+        }
+        columns = append(columns, "` + g.identOf("r.meta.Columns[i]") + `")
+        marks = append(marks, "?")
+        args = append(args, v)
+    }
+%s
+}
+
+func (r *Repository[T]) Update(` + g.db() + `, s T) int64 {
+    values := s.ColumnValues()
+    if values == nil {
+        t.AssertErrorNil(fmt.Errorf("pointer can not be nil"))
+    }
+    if len(r.meta.Keys) == 0 {
+        t.AssertErrorNil(fmt.Errorf("table %%s has no primary key", r.meta.Table))
+    }
+    set := make([]string, 0)
+    args := make([]interface{}, 0)
+    for i, v := range values {
+        if v == nil || hasPosition(r.meta.Managed, i) || hasPosition(r.meta.Keys, i) || i == r.meta.Version {
+            continue
+        }
+        set = append(set, "` + g.identOf("r.meta.Columns[i]") + ` = ?")
+        args = append(args, v)
+    }
+    if len(set) == 0 {
+        t.AssertErrorNil(fmt.Errorf("all field is nil"))
+    }
+    where, keys := r.keys(values)
+    args = append(args, keys...)
+    if r.meta.Version >= 0 {
+        if values[r.meta.Version] == nil {
+            t.AssertErrorNil(fmt.Errorf("version can not be nil"))
+        }
+        version := "` + g.identOf("r.meta.Columns[r.meta.Version]") + `"
+        set = append(set, version+" = "+version+" + 1")
+        where += " and " + version + " = ?"
+        args = append(args, values[r.meta.Version])
+    }
+    SQL := fmt.Sprintf("update ` + g.ident("%%s") + ` set %%s where %%s", r.meta.Table, strings.Join(set, ", "), where)
+    ret, err := ` + g.exec("SQL") + `, args...)
+    t.AssertErrorNil(err)
+    count, err := ret.RowsAffected()
+    t.AssertErrorNil(err)
+    if r.meta.Version >= 0 && count == 0 {
+        panic(ErrStaleObject)
+    }
+    return count
+}
+
+func (r *Repository[T]) Query(` + g.db() + `, s T) T {
+    var zero T
+    values := s.ColumnValues()
+    if values == nil {
+        t.AssertErrorNil(fmt.Errorf("pointer can not be nil"))
+    }
+    if len(r.meta.Keys) == 0 {
+        t.AssertErrorNil(fmt.Errorf("table %%s has no primary key", r.meta.Table))
+    }
+    where, args := r.keys(values)
+    if r.meta.Alive != "" {
+        where += " and " + r.meta.Alive
+    }
+    SQL := fmt.Sprintf("select %%s from ` + g.ident("%%s") + ` where %%s", r.columns(), r.meta.Table, where)
+    ret := r.new()
+    err := ` + g.queryRow("SQL") + `, args...).Scan(ret.ColumnBinds()...)
+    if err != nil {
+        if err != sql.ErrNoRows {
+            t.AssertErrorNil(err)
+        }
+        return zero
+    }
+    return ret
+}
+
+func (r *Repository[T]) QueryMany(` + g.db() + `, s T, page int, size int) (int, []T) {
+    if page <= 0 {
+        page = 1
+    }
+    if size <= 0 {
+        size = 10
+    }
+    conditions := make([]string, 0)
+    args := make([]interface{}, 0)
+    if r.meta.Alive != "" {
+        conditions = append(conditions, r.meta.Alive)
+    }
+    for i, v := range s.ColumnValues() {
+        if v == nil {
+            continue
+        }
+        conditions = append(conditions, "` + g.identOf("r.meta.Columns[i]") + ` = ?")
+        args = append(args, v)
+    }
+    where := ""
+    if len(conditions) != 0 {
+        where = "where " + strings.Join(conditions, " and ") + " "
+    }
+    SQL1 := fmt.Sprintf("select count(*) from ` + g.ident("%%s") + ` %%s", r.meta.Table, where)
+    count := 0
+    err := ` + g.queryRow("SQL1") + `, args...).Scan(&count)
+    t.AssertErrorNil(err)
+    SQL2 := fmt.Sprintf("select %%s from ` + g.ident("%%s") + ` %%s` + g.page("") + `", r.columns(), r.meta.Table, where)
+    args = append(args, (page-1)*size, size)
+    rows, err := ` + g.query("SQL2") + `, args...)
+    t.AssertErrorNil(err)
+    defer rows.Close()
+
+    results := make([]T, 0)
+    for rows.Next() {
+        ret := r.new()
+        err = rows.Scan(ret.ColumnBinds()...)
+        t.AssertErrorNil(err)
+        results = append(results, ret)
+    }
+    err = rows.Err()
+    t.AssertErrorNil(err)
+    return count, results
+}
+
+func (r *Repository[T]) Delete(` + g.db() + `, s T) int64 {
+    return r.delete(` + g.args() + `, s, r.meta.Deleted)
+}
+
+func (r *Repository[T]) UnDelete(` + g.db() + `, s T) int64 {
+    if r.meta.Undeleted == "" {
+        t.AssertErrorNil(fmt.Errorf("table %%s has no undelete", r.meta.Table))
+    }
+    return r.delete(` + g.args() + `, s, r.meta.Undeleted)
+}
+
+func (r *Repository[T]) delete(` + g.db() + `, s T, set string) int64 {
+    values := s.ColumnValues()
+    if values == nil {
+        t.AssertErrorNil(fmt.Errorf("pointer can not be nil"))
+    }
+    if len(r.meta.Keys) == 0 {
+        t.AssertErrorNil(fmt.Errorf("table %%s has no primary key", r.meta.Table))
+    }
+    where, args := r.keys(values)
+    SQL := fmt.Sprintf("delete from ` + g.ident("%%s") + ` where %%s", r.meta.Table, where)
+    if set != "" {
+        SQL = fmt.Sprintf("update ` + g.ident("%%s") + ` set %%s where %%s", r.meta.Table, set, where)
+    }
+    ret, err := ` + g.exec("SQL") + `, args...)
+    t.AssertErrorNil(err)
+    count, err := ret.RowsAffected()
+    t.AssertErrorNil(err)
+    return count
+}
+
+func (r *Repository[T]) keys(values []interface{}) (string, []interface{}) {
+    conditions := make([]string, 0, len(r.meta.Keys))
+    args := make([]interface{}, 0, len(r.meta.Keys))
+    for _, i := range r.meta.Keys {
+        conditions = append(conditions, "` + g.identOf("r.meta.Columns[i]") + ` = ?")
+        args = append(args, values[i])
+    }
+    return strings.Join(conditions, " and "), args
+}
+
+func (r *Repository[T]) columns() string {
+    names := make([]string, 0, len(r.meta.Columns))
+    for _, column := range r.meta.Columns {
+        names = append(names, "` + g.identOf("column") + `")
+    }
+    return strings.Join(names, ", ")
+}
+
+func hasPosition(positions []int, i int) bool {
+    for _, p := range positions {
+        if p == i {
+            return true
+        }
+    }
+    return false
+}`
+}
+
+func repositoryPanicCreateLines(g *gen) map[string]string {
+	return map[string]string{
+		MySQL: `    SQL := fmt.Sprintf("insert into ` + g.ident("%s") + ` (%s) values (%s)", r.meta.Table, strings.Join(columns, ", "), strings.Join(marks, ", "))
+    ret, err := ` + g.exec("SQL") + `, args...)
+    t.AssertErrorNil(err)
+    _, err = ret.RowsAffected()
+    t.AssertErrorNil(err)
+    id, err := ret.LastInsertId()
+    t.AssertErrorNil(err)
+    return id`,
+		Postgres: `    SQL := fmt.Sprintf("insert into ` + g.ident("%s") + ` (%s) values (%s)", r.meta.Table, strings.Join(columns, ", "), strings.Join(marks, ", "))
+    if r.meta.Auto < 0 {
+        _, err := ` + g.exec("SQL") + `, args...)
+        t.AssertErrorNil(err)
+        return 0
+    }
+    SQL += " returning ` + g.identOf("r.meta.Columns[r.meta.Auto]") + `"
+    id := int64(0)
+    err := ` + g.queryRow("SQL") + `, args...).Scan(&id)
+    t.AssertErrorNil(err)
+    return id`,
+		SQLServer: `    output := ""
+    if r.meta.Auto >= 0 {
+        output = " output inserted.` + g.identOf("r.meta.Columns[r.meta.Auto]") + `"
+    }
+    SQL := fmt.Sprintf("insert into ` + g.ident("%s") + ` (%s)%s values (%s)", r.meta.Table, strings.Join(columns, ", "), output, strings.Join(marks, ", "))
+    if r.meta.Auto < 0 {
+        _, err := ` + g.exec("SQL") + `, args...)
+        t.AssertErrorNil(err)
+        return 0
+    }
+    id := int64(0)
+    err := ` + g.queryRow("SQL") + `, args...).Scan(&id)
+    t.AssertErrorNil(err)
+    return id`,
+	}
+}
+
+// GenerateRepositoryRuntime emits the generic Repository and its DataSource, which the tables of every
+// GenerateRepository file of the package share, so it is written once next to them.
+func GenerateRepositoryRuntime(pkg string, o *Options, panicStyle bool) string {
+	importsMap := make(map[string]common.Void)
+	importsMap["database/sql"] = common.Null
+	importsMap["errors"] = common.Null
+	importsMap["fmt"] = common.Null
+	importsMap["strings"] = common.Null
+	importsMap["time"] = common.Null
+	importsMap["github.com/stella-go/siu/t"] = common.Null
+	importsMap["github.com/stella-go/siu/t/n"] = common.Null
+	g := newGen(o)
+	if o.Ctx {
+		importsMap["context"] = common.Null
+	}
+	for _, i := range dialectImports[o.Dialect] {
+		importsMap[i] = common.Null
+	}
+
+	importsLines := make([]string, 0)
+	for i := range importsMap {
+		importsLines = append(importsLines, "\t\""+i+"\"")
+	}
+
+	datasourceLines := `type DataSource interface {
+    Exec(query string, args ...interface{}) (sql.Result, error)
+    QueryRow(query string, args ...interface{}) *sql.Row
+    Query(query string, args ...interface{}) (*sql.Rows, error)
+}`
+	if o.Ctx {
+		datasourceLines = `type DataSource interface {
+    ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+    QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+    QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+}`
+	}
+	lines, createLines := repositoryLines(g), repositoryCreateLines(g)
+	if panicStyle {
+		lines, createLines = repositoryPanicLines(g), repositoryPanicCreateLines(g)
+	}
+	create, ok := createLines[o.Dialect]
+	if !ok {
+		create = createLines[MySQL]
+	}
+	datasourceLines += fmt.Sprintf(lines, create) + inZoneLines + truncateDayLines + g.bindLines()
+	bannerS := ""
+	if o.Banner {
+		bannerS = fmt.Sprintf("\n/**\n * Auto Generate by github.com/stella-go/stella %s on %s.\n */\n", version.VERSION, time.Now().Format("2006/01/02"))
+	}
+	return fmt.Sprintf("package %s\n%s\nimport (\n%s\n)\n\n%s\n", pkg, bannerS, strings.Join(importsLines, "\n"), datasourceLines)
+}
+
+// GenerateRepository emits the Meta, the Model methods and the Repository of every table instead of
+// their functions, on the runtime of GenerateRepositoryRuntime.
+func GenerateRepository(pkg string, statements []*parser.Statement, o *Options) string {
+	g := newGen(o)
+	functions := make([]string, 0)
+	for _, statement := range statements {
+		functions = append(functions, "// ==================== "+generator.FirstUpperCamelCase(statement.TableName.Name)+" ====================")
+		functions = append(functions, repository(statement, g, generator.ParseLogic(statement, o.Logic)))
+	}
+
+	functionLines := strings.Join(functions, "\n")
+	importsLines := ""
+	if strings.Contains(functionLines, "time.") {
+		importsLines = "\nimport (\n\t\"time\"\n)\n"
+	}
+	bannerS := ""
+	if o.Banner {
+		bannerS = fmt.Sprintf("\n/**\n * Auto Generate by github.com/stella-go/stella %s on %s.\n */\n", version.VERSION, time.Now().Format("2006/01/02"))
+	}
+	return fmt.Sprintf("package %s\n%s%s\n%s", pkg, bannerS, importsLines, functionLines)
+}
+
+// repository is the part of a table, its Meta, the Model methods and the Repository variable.
+func repository(statement *parser.Statement, g *gen, logic *generator.Logic) string {
+	modelName := generator.FirstUpperCamelCase(statement.TableName.Name)
+	metaName := strings.ToLower(modelName[:1]) + modelName[1:] + "Meta"
+	columns := make([]string, 0)
+	keys := make([]string, 0)
+	managed := make([]string, 0)
+	auto := -1
+	values := ""
+	binds := make([]string, 0)
+	primaryKeys := getPrimaryKeys(statement)
+	version := generator.VersionColumn(statement)
+	if version != nil && contains(primaryKeys, version) {
+		version = nil
+	}
+	versionIndex := -1
+	for i, col := range statement.Columns {
+		fieldName := generator.FirstUpperCamelCase(col.ColumnName.Name)
+		columns = append(columns, "\""+col.ColumnName.Name+"\"")
+		if contains(primaryKeys, col) {
+			keys = append(keys, strconv.Itoa(i))
+		}
+		if col.AutoIncrement || col.CurrentTimestamp {
+			managed = append(managed, strconv.Itoa(i))
+		}
+		if col.AutoIncrement {
+			auto = i
+		}
+		if col == version {
+			versionIndex = i
+		}
+		arg := timeArg(col, "s."+fieldName, g.times)
+		values += fmt.Sprintf(`    if s.%s != nil {
+        values[%d] = %s
+    }
+`, fieldName, i, arg)
+		binds = append(binds, "&s."+fieldName)
+	}
+	alive, deleted, undeleted := "", "", ""
+	if logic != nil {
+		alive = quote(logic.Alive(g.name))
+		deleted = g.ident(logic.Column) + " = " + quote(logic.Deleted)
+		if logic.Undeleted != "" {
+			undeleted = g.ident(logic.Column) + " = " + quote(logic.Undeleted)
+		}
+	}
+	return fmt.Sprintf(`var %s = &Meta{
+    Table:     "%s",
+    Columns:   []string{%s},
+    Keys:      []int{%s},
+    Managed:   []int{%s},
+    Auto:      %d,
+    Version:   %d,
+    Alive:     "%s",
+    Deleted:   "%s",
+    Undeleted: "%s",
+}
+
+func (*%s) TableMeta() *Meta {
+    return %s
+}
+
+func (s *%s) ColumnValues() []interface{} {
+    if s == nil {
+        return nil
+    }
+    values := make([]interface{}, %d)
+%s    return values
+}
+
+func (s *%s) ColumnBinds() []interface{} {
+    return []interface{}{%s}
+}
+
+var %sRepository = NewRepository(func() *%s { return &%s{} })
+`, metaName, statement.TableName.Name, strings.Join(columns, ", "), strings.Join(keys, ", "), strings.Join(managed, ", "), auto, versionIndex, alive, deleted, undeleted,
+		modelName, metaName, modelName, len(statement.Columns), values, modelName, strings.Join(binds, ", "), modelName, modelName, modelName)
+}
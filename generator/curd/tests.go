@@ -0,0 +1,651 @@
+// Copyright 2010-2024 the original author or authors.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+// 	http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package curd
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/stella-go/stella/common"
+	"github.com/stella-go/stella/generator"
+	"github.com/stella-go/stella/generator/parser"
+	"github.com/stella-go/stella/version"
+)
+
+var testTypes = map[string]string{
+	"TINYINT":   "n.Int",
+	"INT":       "n.Int",
+	"BIGINT":    "n.Int64",
+	"FLOAT":     "n.Float64",
+	"CHAR":      "n.String",
+	"VARCHAR":   "n.String",
+	"TEXT":      "n.String",
+	"DATE":      "n.Time",
+	"DATETIME":  "n.Time",
+	"TIMESTAMP": "n.Time",
+}
+
+// testLines are the fake driver and the helpers shared by the generated tests,
+// the fake records every statement with its arguments and answers the queries with canned rows.
+const testLines = `type fakeDB struct {
+    queries []string
+    args    [][]driver.Value
+    results [][]driver.Value
+}
+
+func newFakeDB(results ...[]driver.Value) (*sql.DB, *fakeDB) {
+    fake := &fakeDB{results: results}
+    return sql.OpenDB(fake), fake
+}
+
+func (db *fakeDB) Connect(context.Context) (driver.Conn, error) { return db, nil }
+func (db *fakeDB) Driver() driver.Driver                      { return db }
+func (db *fakeDB) Open(string) (driver.Conn, error)           { return db, nil }
+func (db *fakeDB) Close() error                               { return nil }
+func (db *fakeDB) Prepare(string) (driver.Stmt, error) {
+    return nil, fmt.Errorf("prepare is not supported")
+}
+func (db *fakeDB) Begin() (driver.Tx, error)                  { return db, nil }
+func (db *fakeDB) Commit() error                               { return nil }
+func (db *fakeDB) Rollback() error                             { return nil }
+
+func (db *fakeDB) record(query string, args []driver.NamedValue) {
+    values := make([]driver.Value, 0, len(args))
+    for _, arg := range args {
+        values = append(values, arg.Value)
+    }
+    db.queries = append(db.queries, query)
+    db.args = append(db.args, values)
+}
+
+func (db *fakeDB) ExecContext(_ context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+    db.record(query, args)
+    return fakeResult{}, nil
+}
+
+func (db *fakeDB) QueryContext(_ context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+    db.record(query, args)
+    rows := &fakeRows{}
+    if len(db.results) > 0 {
+        rows.row, db.results = db.results[0], db.results[1:]
+    }
+    return rows, nil
+}
+
+func (db *fakeDB) expect(t *testing.T, queries []string, args [][]interface{}) {
+    t.Helper()
+    if !reflect.DeepEqual(db.queries, queries) {
+        t.Fatalf("queries %q, want %q", db.queries, queries)
+    }
+    for i := range args {
+        want := make([]driver.Value, 0, len(args[i]))
+        for _, arg := range args[i] {
+            value, err := driver.DefaultParameterConverter.ConvertValue(arg)
+            if err != nil {
+                t.Fatal(err)
+            }
+            want = append(want, value)
+        }
+        if !reflect.DeepEqual(db.args[i], want) {
+            t.Errorf("args of %q are %v, want %v", queries[i], db.args[i], want)
+        }
+    }
+}
+
+type fakeResult struct{}
+
+func (fakeResult) LastInsertId() (int64, error) { return 1, nil }
+func (fakeResult) RowsAffected() (int64, error) { return 1, nil }
+
+type fakeRows struct {
+    row  []driver.Value
+    done bool
+}
+
+func (r *fakeRows) Columns() []string { return make([]string, len(r.row)) }
+func (r *fakeRows) Close() error      { return nil }
+func (r *fakeRows) Next(dest []driver.Value) error {
+    if r.row == nil || r.done {
+        return io.EOF
+    }
+    r.done = true
+    copy(dest, r.row)
+    return nil
+}
+
+func sample[T any, P interface {
+    *T
+    sql.Scanner
+}](v interface{}) P {
+    p := P(new(T))
+    if err := p.Scan(v); err != nil {
+        panic(err)
+    }
+    return p
+}
+
+type curdCase struct {
+    name    string
+    results [][]driver.Value
+    call    func(ctx context.Context, db DataSource) (interface{}, error)
+    queries []string
+    args    [][]interface{}
+    want    interface{}
+}
+
+func runCurdCases(t *testing.T, cases []curdCase) {
+    for _, c := range cases {
+        t.Run(c.name, func(t *testing.T) {
+            db, fake := newFakeDB(c.results...)
+            defer db.Close()
+            got, err := c.call(context.Background(), db)
+            if err != nil {
+                t.Fatal(err)
+            }
+            fake.expect(t, c.queries, c.args)
+            if !reflect.DeepEqual(got, c.want) {
+                t.Errorf("got %v, want %v", got, c.want)
+            }
+        })
+    }
+}`
+
+// GenerateTest generates table driven tests of the curd functions which check the statements,
+// the order of the arguments and the scan of the rows against the models.
+func GenerateTest(pkg string, statements []*parser.Statement, o *Options, panicStyle bool) string {
+	importsMap := make(map[string]common.Void)
+	importsMap["context"] = common.Null
+	importsMap["database/sql"] = common.Null
+	importsMap["database/sql/driver"] = common.Null
+	importsMap["fmt"] = common.Null
+	importsMap["io"] = common.Null
+	importsMap["reflect"] = common.Null
+	importsMap["testing"] = common.Null
+	g := newGen(o)
+	functions := make([]string, 0)
+	for _, statement := range statements {
+		for _, col := range statement.Columns {
+			if _, ok := testTypes[col.Type]; ok {
+				importsMap["github.com/stella-go/siu/t/n"] = common.Null
+			}
+			if col.Type == "DATE" || col.Type == "DATETIME" || col.Type == "TIMESTAMP" {
+				importsMap["time"] = common.Null
+			}
+		}
+		functions = append(functions, testCurd(statement, g, generator.ParseLogic(statement, o.Logic), panicStyle))
+	}
+
+	importsLines := make([]string, 0)
+	for i := range importsMap {
+		importsLines = append(importsLines, "\t\""+i+"\"")
+	}
+	bannerS := ""
+	if o.Banner {
+		bannerS = fmt.Sprintf("\n/**\n * Auto Generate by github.com/stella-go/stella %s on %s.\n */\n", version.VERSION, time.Now().Format("2006/01/02"))
+	}
+	return fmt.Sprintf("package %s\n%s\nimport (\n%s\n)\n\n%s\n\n%s", pkg, bannerS, strings.Join(importsLines, "\n"), testLines, strings.Join(functions, "\n"))
+}
+
+func testCurd(statement *parser.Statement, g *gen, logic *generator.Logic, panicStyle bool) string {
+	modelName := generator.FirstUpperCamelCase(statement.TableName.Name)
+	table := statement.TableName.Name
+	fields := make([]string, 0)
+	row := make([]string, 0)
+	names := make([]string, 0)
+	for i, col := range statement.Columns {
+		value := fmt.Sprintf("%q", col.ColumnName.Name)
+		switch col.Type {
+		case "TINYINT", "INT", "BIGINT":
+			value = fmt.Sprintf("int64(%d)", i+1)
+		case "FLOAT":
+			value = fmt.Sprintf("float64(%d.5)", i+1)
+		case "DATE", "DATETIME", "TIMESTAMP":
+			loc := g.times.Location(col)
+			if loc == "" {
+				loc = "time.UTC"
+			}
+			value = fmt.Sprintf("time.Date(2024, 1, 1, %d, 0, 0, 0, %s)", i+1, loc)
+			if col.Type == "DATE" {
+				value = fmt.Sprintf("time.Date(2024, 1, %d, 0, 0, 0, 0, %s)", i+1, loc)
+			}
+		}
+		row = append(row, value)
+		if typ, ok := testTypes[col.Type]; ok {
+			value = fmt.Sprintf("sample[%s](%s)", typ, value)
+		}
+		fields = append(fields, fmt.Sprintf("        %s: %s,", generator.FirstUpperCamelCase(col.ColumnName.Name), value))
+		names = append(names, g.name(col.ColumnName.Name))
+	}
+	single := "return %s"
+	many := `count, list, err := %s
+                return []interface{}{count, list}, err`
+	after := `next, list, err := %s
+                return []interface{}{next, list}, err`
+	each := `list := make([]*%s, 0)
+                err := %sfunc(ret *%s) error {
+                    list = append(list, ret)
+                    return nil
+                })
+                return list, err`
+	if panicStyle {
+		single = "return %s, nil"
+		many = `count, list := %s
+                return []interface{}{count, list}, nil`
+		after = `next, list := %s
+                return []interface{}{next, list}, nil`
+		each = `list := make([]*%s, 0)
+                %sfunc(ret *%s) bool {
+                    list = append(list, ret)
+                    return true
+                })
+                return list, nil`
+	}
+	projected := make([]string, 0)
+	for _, col := range statement.Columns {
+		projected = append(projected, modelName+"Column"+generator.FirstUpperCamelCase(col.ColumnName.Name))
+	}
+	projection := fmt.Sprintf("[]%sColumn{%s}", modelName, strings.Join(projected, ", "))
+	version := generator.VersionColumn(statement)
+	cases := make([]string, 0)
+
+	inserts := make([]*parser.ColumnDefinition, 0)
+	for _, col := range statement.Columns {
+		if !col.AutoIncrement && !col.CurrentTimestamp && col.DefaultValue == nil {
+			inserts = append(inserts, col)
+		}
+	}
+	for _, col := range statement.Columns {
+		if !col.AutoIncrement && !col.CurrentTimestamp && col.DefaultValue != nil {
+			inserts = append(inserts, col)
+		}
+	}
+	SQL := fmt.Sprintf("insert into "+g.name("%s")+" (%s) values (%s)", table, testColumns(inserts, "", g), testMarks(len(inserts)))
+	results, want := "", "int64(1)"
+	if g.Dialect == Postgres || g.Dialect == SQLServer {
+		want = "int64(0)"
+		for _, col := range statement.Columns {
+			if col.AutoIncrement {
+				results, want = "{int64(1)}", "int64(1)"
+				if g.Dialect == Postgres {
+					SQL = fmt.Sprintf("insert into "+g.name("%s")+" (%s) values (%s) returning "+g.name("%s"), table, testColumns(inserts, "", g), testMarks(len(inserts)), col.ColumnName.Name)
+				} else {
+					SQL = fmt.Sprintf("insert into "+g.name("%s")+" (%s) output inserted."+g.name("%s")+" values (%s)", table, testColumns(inserts, "", g), col.ColumnName.Name, testMarks(len(inserts)))
+				}
+			}
+		}
+	}
+	cases = append(cases, testCase("Create"+modelName, results, fmt.Sprintf(single, fmt.Sprintf("Create%s("+g.args()+", s)", modelName)), []string{testSQL(SQL, g)}, []string{testArgs(inserts)}, want))
+	createSQL, createResults := SQL, results
+
+	SQL = fmt.Sprintf("insert into "+g.name("%s")+" (%s) values (%s)", table, testColumns(inserts, "", g), testMarks(len(inserts)))
+	cases = append(cases, testCase("CreateMany"+modelName, "", fmt.Sprintf(single, fmt.Sprintf("CreateMany%s("+g.args()+", []*%s{s}, 0)", modelName, modelName)), []string{testSQL(SQL, g)}, []string{testArgs(inserts)}, "int64(1)"))
+
+	versioned := false
+	for _, keys := range generator.UniqKeyPairs(statement) {
+		suffix := ""
+		conditions := make([]string, 0)
+		for _, col := range keys {
+			suffix += generator.FirstUpperCamelCase(col.ColumnName.Name)
+			conditions = append(conditions, g.name(col.ColumnName.Name)+" = ?")
+		}
+		keyVersion := version
+		if keyVersion != nil && contains(keys, keyVersion) {
+			keyVersion = nil
+		}
+
+		columns := make([]*parser.ColumnDefinition, 0)
+		columns = append(columns, keys...)
+		sets := make([]string, 0)
+		for _, optional := range []bool{false, true} {
+			for _, col := range statement.Columns {
+				if col.AutoIncrement || col.CurrentTimestamp || contains(keys, col) || (col.DefaultValue != nil) != optional {
+					continue
+				}
+				columns = append(columns, col)
+				if col != keyVersion {
+					name := g.name(col.ColumnName.Name)
+					switch g.Dialect {
+					case Postgres, SQLite:
+						sets = append(sets, name+" = excluded."+name)
+					case SQLServer:
+						sets = append(sets, name+" = source."+name)
+					default:
+						sets = append(sets, name+" = values("+name+")")
+					}
+				}
+			}
+		}
+		if len(sets) > 0 && g.upsertable(keys) {
+			if keyVersion != nil {
+				name := g.name(keyVersion.ColumnName.Name)
+				switch g.Dialect {
+				case Postgres, SQLite:
+					sets = append(sets, name+" = "+g.name(table)+"."+name+" + 1")
+				case SQLServer:
+					sets = append(sets, name+" = target."+name+" + 1")
+				default:
+					sets = append(sets, name+" = "+name+" + 1")
+				}
+			}
+			results := ""
+			conflict := make([]string, 0)
+			matched := make([]string, 0)
+			for _, col := range keys {
+				conflict = append(conflict, g.name(col.ColumnName.Name))
+				matched = append(matched, "target."+g.name(col.ColumnName.Name)+" = source."+g.name(col.ColumnName.Name))
+			}
+			switch g.Dialect {
+			case Postgres:
+				SQL = fmt.Sprintf("insert into "+g.name("%s")+" (%s) values (%s) on conflict (%s) do update set %s returning (xmax = 0)", table, testColumns(columns, "", g), testMarks(len(columns)), strings.Join(conflict, ", "), strings.Join(sets, ", "))
+				results = "{true}"
+			case SQLite:
+				SQL = fmt.Sprintf("insert into "+g.name("%s")+" (%s) values (%s) on conflict (%s) do nothing", table, testColumns(columns, "", g), testMarks(len(columns)), strings.Join(conflict, ", "))
+			case SQLServer:
+				SQL = fmt.Sprintf("merge into "+g.name("%s")+" with (holdlock) as target using (values (%s)) as source (%s) on %s when matched then update set %s when not matched then insert (%s) values (%s) output $action;", table, testMarks(len(columns)), testColumns(columns, "", g), strings.Join(matched, " and "), strings.Join(sets, ", "), testColumns(columns, "", g), testColumns(columns, "source.", g))
+				results = `{"INSERT"}`
+			default:
+				SQL = fmt.Sprintf("insert into "+g.name("%s")+" (%s) values (%s) on duplicate key update %s", table, testColumns(columns, "", g), testMarks(len(columns)), strings.Join(sets, ", "))
+			}
+			cases = append(cases, testCase("Upsert"+modelName+"By"+suffix, results, fmt.Sprintf(single, fmt.Sprintf("Upsert%sBy%s("+g.args()+", s)", modelName, suffix)), []string{testSQL(SQL, g)}, []string{testArgs(columns)}, "UpsertInserted"))
+		}
+
+		updates := make([]*parser.ColumnDefinition, 0)
+		sets = make([]string, 0)
+		for _, col := range statement.Columns {
+			if col.AutoIncrement || col.CurrentTimestamp || contains(keys, col) || col == keyVersion {
+				continue
+			}
+			updates = append(updates, col)
+			sets = append(sets, g.name(col.ColumnName.Name)+" = ?")
+		}
+		if len(updates) > 0 {
+			args := append(append(make([]*parser.ColumnDefinition, 0), updates...), keys...)
+			SQL = fmt.Sprintf("update "+g.name("%s")+" set %s where %s", table, strings.Join(sets, " , "), strings.Join(conditions, " and "))
+			if keyVersion != nil {
+				SQL = fmt.Sprintf("update "+g.name("%s")+" set %s, "+g.name("%s")+" = "+g.name("%s")+" + 1 where %s and "+g.name("%s")+" = ?", table, strings.Join(sets, " , "), keyVersion.ColumnName.Name, keyVersion.ColumnName.Name, strings.Join(conditions, " and "), keyVersion.ColumnName.Name)
+				args = append(args, keyVersion)
+			}
+			cases = append(cases, testCase("Update"+modelName+"By"+suffix, "", fmt.Sprintf(single, fmt.Sprintf("Update%sBy%s("+g.args()+", s)", modelName, suffix)), []string{testSQL(SQL, g)}, []string{testArgs(args)}, "int64(1)"))
+			if keyVersion != nil && !versioned {
+				// a row created without a version starts at 0, the update matches it
+				versioned = true
+				call := fmt.Sprintf(`v := *s
+                v.%[1]s = nil
+                if _, err := Create%[2]s(`+g.args()+`, &v); err != nil {
+                    return nil, err
+                }
+                return Update%[2]sBy%[3]s(`+g.args()+`, &v)`, generator.FirstUpperCamelCase(keyVersion.ColumnName.Name), modelName, suffix)
+				if panicStyle {
+					call = fmt.Sprintf(`v := *s
+                v.%[1]s = nil
+                Create%[2]s(`+g.args()+`, &v)
+                return Update%[2]sBy%[3]s(`+g.args()+`, &v), nil`, generator.FirstUpperCamelCase(keyVersion.ColumnName.Name), modelName, suffix)
+				}
+				cases = append(cases, testCase("CreateThenUpdate"+modelName+"By"+suffix, createResults, call, []string{testSQL(createSQL, g), testSQL(SQL, g)}, []string{testVersionArgs(inserts, keyVersion), testVersionArgs(args, keyVersion)}, "int64(1)"))
+			}
+		}
+
+		for _, policy := range []*generator.Logic{logic, nil} {
+			name := ""
+			where := conditions
+			if policy != nil {
+				where = append(append(make([]string, 0), conditions...), policy.Alive(g.name))
+			} else if logic != nil {
+				name = "WithDeleted"
+			}
+			SQL = fmt.Sprintf("select %s from "+g.name("%s")+" where %s", strings.Join(names, ", "), table, strings.Join(where, " and "))
+			cases = append(cases, testCase("Query"+modelName+"By"+suffix+name, "row", fmt.Sprintf(single, fmt.Sprintf("Query%sBy%s%s("+g.args()+", s)", modelName, suffix, name)), []string{testSQL(SQL, g)}, []string{testArgs(keys)}, "s"))
+			if g.Projection {
+				cases = append(cases, testCase("Query"+modelName+"By"+suffix+"Columns"+name, "row", fmt.Sprintf(single, fmt.Sprintf("Query%sBy%sColumns%s("+g.args()+", s, %s)", modelName, suffix, name, projection)), []string{testSQL(SQL, g)}, []string{testArgs(keys)}, "s"))
+			}
+			SQL = fmt.Sprintf("select count(*) from "+g.name("%s")+" where %s", table, strings.Join(where, " and "))
+			cases = append(cases, testCase("Exists"+modelName+"By"+suffix+name, "{int64(1)}", fmt.Sprintf(single, fmt.Sprintf("Exists%sBy%s%s("+g.args()+", s)", modelName, suffix, name)), []string{testSQL(SQL, g)}, []string{testArgs(keys)}, "true"))
+			if logic == nil {
+				break
+			}
+		}
+
+		if logic != nil {
+			SQL = fmt.Sprintf("update "+g.name("%s")+" set "+g.name("%s")+" = %s where %s", table, logic.Column, logic.Deleted, strings.Join(conditions, " and "))
+		} else {
+			SQL = fmt.Sprintf("delete from "+g.name("%s")+" where %s", table, strings.Join(conditions, " and "))
+		}
+		cases = append(cases, testCase("Delete"+modelName+"By"+suffix, "", fmt.Sprintf(single, fmt.Sprintf("Delete%sBy%s("+g.args()+", s)", modelName, suffix)), []string{testSQL(SQL, g)}, []string{testArgs(keys)}, "int64(1)"))
+		if logic != nil && logic.Undeleted != "" {
+			SQL = fmt.Sprintf("update "+g.name("%s")+" set "+g.name("%s")+" = %s where %s", table, logic.Column, logic.Undeleted, strings.Join(conditions, " and "))
+			cases = append(cases, testCase("UnDelete"+modelName+"By"+suffix, "", fmt.Sprintf(single, fmt.Sprintf("UnDelete%sBy%s("+g.args()+", s)", modelName, suffix)), []string{testSQL(SQL, g)}, []string{testArgs(keys)}, "int64(1)"))
+		}
+	}
+
+	for _, keys := range generator.IndexKeyPairs(statement) {
+		suffix := ""
+		conditions := make([]string, 0)
+		for _, col := range keys {
+			suffix += generator.FirstUpperCamelCase(col.ColumnName.Name)
+			conditions = append(conditions, g.name(col.ColumnName.Name)+" = ?")
+		}
+		if logic != nil {
+			conditions = append(conditions, logic.Alive(g.name))
+		}
+		limit, limitSQL, limitArgs := "10", "", ""
+		if g.dialect() == MySQL {
+			limitSQL, limitArgs = " limit ?", ", 10"
+		} else if primaryKeys := getPrimaryKeys(statement); len(primaryKeys) == 1 {
+			key := g.name(primaryKeys[0].ColumnName.Name)
+			limitSQL = fmt.Sprintf(" and %s in (select %s from "+g.name("%s")+" where %s order by %s "+g.limit()+")", key, key, table, strings.Join(conditions, " and "), key)
+			limitArgs = ", " + strings.Trim(testArgs(keys), "{}") + ", 10"
+		} else {
+			limit = "0"
+		}
+		keyVersion := version
+		if keyVersion != nil && contains(keys, keyVersion) {
+			keyVersion = nil
+		}
+		updates := make([]*parser.ColumnDefinition, 0)
+		sets := make([]string, 0)
+		for _, col := range statement.Columns {
+			if col.AutoIncrement || col.CurrentTimestamp || contains(keys, col) || col == keyVersion {
+				continue
+			}
+			updates = append(updates, col)
+			sets = append(sets, g.name(col.ColumnName.Name)+" = ?")
+		}
+		if len(updates) > 0 {
+			SQL = fmt.Sprintf("update "+g.name("%s")+" set %s where %s", table, strings.Join(sets, " , "), strings.Join(conditions, " and "))
+			if keyVersion != nil {
+				SQL = fmt.Sprintf("update "+g.name("%s")+" set %s, "+g.name("%s")+" = "+g.name("%s")+" + 1 where %s", table, strings.Join(sets, " , "), keyVersion.ColumnName.Name, keyVersion.ColumnName.Name, strings.Join(conditions, " and "))
+			}
+			args := append(append(make([]*parser.ColumnDefinition, 0), updates...), keys...)
+			cases = append(cases, testCase("UpdateMany"+modelName+"By"+suffix, "", fmt.Sprintf(single, fmt.Sprintf("UpdateMany%sBy%s("+g.args()+", s, %s)", modelName, suffix, limit)), []string{testSQL(SQL+limitSQL, g)}, []string{strings.TrimSuffix(testArgs(args), "}") + limitArgs + "}"}, "int64(1)"))
+		}
+		SQL = fmt.Sprintf("delete from "+g.name("%s")+" where %s", table, strings.Join(conditions, " and "))
+		if logic != nil {
+			SQL = fmt.Sprintf("update "+g.name("%s")+" set "+g.name("%s")+" = %s where %s", table, logic.Column, logic.Deleted, strings.Join(conditions, " and "))
+		}
+		cases = append(cases, testCase("DeleteMany"+modelName+"By"+suffix, "", fmt.Sprintf(single, fmt.Sprintf("DeleteMany%sBy%s("+g.args()+", s, %s)", modelName, suffix, limit)), []string{testSQL(SQL+limitSQL, g)}, []string{strings.TrimSuffix(testArgs(keys), "}") + limitArgs + "}"}, "int64(1)"))
+	}
+
+	for _, policy := range []*generator.Logic{logic, nil} {
+		name := ""
+		if policy == nil && logic != nil {
+			name = "WithDeleted"
+		}
+		for _, keys := range generator.IndexKeyPairs(statement) {
+			suffix := ""
+			where := make([]string, 0)
+			for _, col := range keys {
+				suffix += generator.FirstUpperCamelCase(col.ColumnName.Name)
+				where = append(where, g.name(col.ColumnName.Name)+" = ?")
+			}
+			if policy != nil {
+				where = append(where, policy.Alive(g.name))
+			}
+			SQL1 := fmt.Sprintf("select count(*) from "+g.name("%s")+" where %s", table, strings.Join(where, " and "))
+			SQL2 := fmt.Sprintf("select %s from "+g.name("%s")+" where %s "+g.page(""), strings.Join(names, ", "), table, strings.Join(where, " and "))
+			cases = append(cases, testCase("Count"+modelName+"By"+suffix+name, "{int64(1)}", fmt.Sprintf(single, fmt.Sprintf("Count%sBy%s%s("+g.args()+", s)", modelName, suffix, name)), []string{testSQL(SQL1, g)}, []string{testArgs(keys)}, "1"))
+			cases = append(cases, testCase("QueryMany"+modelName+"By"+suffix+name, "{int64(1)}, row", fmt.Sprintf(many, fmt.Sprintf("QueryMany%sBy%s%s("+g.args()+", s, 1, 10)", modelName, suffix, name)), []string{testSQL(SQL1, g), testSQL(SQL2, g)}, []string{testArgs(keys), strings.TrimSuffix(testArgs(keys), "}") + ", 0, 10}"}, fmt.Sprintf("[]interface{}{1, []*%s{s}}", modelName)))
+		}
+
+		where := make([]string, 0)
+		if policy != nil {
+			where = append(where, policy.Alive(g.name))
+		}
+		for _, col := range statement.Columns {
+			where = append(where, g.name(col.ColumnName.Name)+" = ?")
+		}
+		SQL1 := fmt.Sprintf("select count(*) from "+g.name("%s")+" where %s", table, strings.Join(where, " and "))
+		SQL2 := fmt.Sprintf("select %s from "+g.name("%s")+" where %s "+g.page(""), strings.Join(names, ", "), table, strings.Join(where, " and "))
+		orders := ""
+		if g.Sort {
+			orders = "nil, "
+		}
+		cases = append(cases, testCase("Count"+modelName+name, "{int64(1)}", fmt.Sprintf(single, fmt.Sprintf("Count%s%s("+g.args()+", s)", modelName, name)), []string{testSQL(SQL1, g)}, []string{testArgs(statement.Columns)}, "1"))
+		cases = append(cases, testCase("QueryMany"+modelName+name, "{int64(1)}, row", fmt.Sprintf(many, fmt.Sprintf("QueryMany%s%s("+g.args()+", s, %s1, 10)", modelName, name, orders)), []string{testSQL(SQL1, g), testSQL(SQL2, g)}, []string{testArgs(statement.Columns), strings.TrimSuffix(testArgs(statement.Columns), "}") + ", 0, 10}"}, fmt.Sprintf("[]interface{}{1, []*%s{s}}", modelName)))
+		if g.Projection {
+			cases = append(cases, testCase("QueryMany"+modelName+"Columns"+name, "{int64(1)}, row", fmt.Sprintf(many, fmt.Sprintf("QueryMany%sColumns%s("+g.args()+", s, %s%s, 1, 10)", modelName, name, orders, projection)), []string{testSQL(SQL1, g), testSQL(SQL2, g)}, []string{testArgs(statement.Columns), strings.TrimSuffix(testArgs(statement.Columns), "}") + ", 0, 10}"}, fmt.Sprintf("[]interface{}{1, []*%s{s}}", modelName)))
+		}
+		if primaryKeys := getPrimaryKeys(statement); g.Keyset && len(primaryKeys) > 0 {
+			sorts := make([]string, 0)
+			for _, col := range primaryKeys {
+				sorts = append(sorts, g.name(col.ColumnName.Name))
+			}
+			SQL := fmt.Sprintf("select %s from "+g.name("%s")+" where %s order by %s "+g.limit(), strings.Join(names, ", "), table, strings.Join(where, " and "), strings.Join(sorts, ", "))
+			cases = append(cases, testCase("QueryMany"+modelName+"After"+name, "row", fmt.Sprintf(after, fmt.Sprintf("QueryMany%sAfter%s("+g.args()+", s, \"\", 10)", modelName, name)), []string{testSQL(SQL, g)}, []string{strings.TrimSuffix(testArgs(statement.Columns), "}") + ", 10}"}, fmt.Sprintf("[]interface{}{\"\", []*%s{s}}", modelName)))
+		}
+		if g.Filter {
+			col := statement.Columns[0]
+			filtered := make([]string, 0)
+			if policy != nil {
+				filtered = append(filtered, policy.Alive(g.name))
+			}
+			filtered = append(filtered, g.name(col.ColumnName.Name)+" is not null")
+			SQL1 := fmt.Sprintf("select count(*) from "+g.name("%s")+" where %s", table, strings.Join(filtered, " and "))
+			SQL2 := fmt.Sprintf("select %s from "+g.name("%s")+" where %s "+g.page(""), strings.Join(names, ", "), table, strings.Join(filtered, " and "))
+			filter := fmt.Sprintf("&%sFilter{%s: %s}", modelName, generator.FirstUpperCamelCase(col.ColumnName.Name), testFilter(col))
+			cases = append(cases, testCase("QueryMany"+modelName+"ByFilter"+name, "{int64(1)}, row", "null := false\n                "+fmt.Sprintf(many, fmt.Sprintf("QueryMany%sByFilter%s("+g.args()+", %s, %s1, 10)", modelName, name, filter, orders)), []string{testSQL(SQL1, g), testSQL(SQL2, g)}, []string{"{}", "{0, 10}"}, fmt.Sprintf("[]interface{}{1, []*%s{s}}", modelName)))
+			if g.Projection {
+				cases = append(cases, testCase("QueryMany"+modelName+"ByFilterColumns"+name, "{int64(1)}, row", "null := false\n                "+fmt.Sprintf(many, fmt.Sprintf("QueryMany%sByFilterColumns%s("+g.args()+", %s, %s%s, 1, 10)", modelName, name, filter, orders, projection)), []string{testSQL(SQL1, g), testSQL(SQL2, g)}, []string{"{}", "{0, 10}"}, fmt.Sprintf("[]interface{}{1, []*%s{s}}", modelName)))
+			}
+		}
+		if g.Aggregate {
+			for _, col := range statement.Columns {
+				want := "int64(1)"
+				switch col.Type {
+				case "TINYINT", "INT", "BIGINT":
+				case "FLOAT":
+					want = "float64(1)"
+				default:
+					continue
+				}
+				for _, fn := range []string{"Sum", "Max", "Min"} {
+					SQL := fmt.Sprintf("select %s(%s) from "+g.name("%s")+" where %s", strings.ToLower(fn), g.name(col.ColumnName.Name), table, strings.Join(where, " and "))
+					call := fmt.Sprintf("%s%s%s%s("+g.args()+", s)", fn, modelName, generator.FirstUpperCamelCase(col.ColumnName.Name), name)
+					cases = append(cases, testCase(fn+modelName+generator.FirstUpperCamelCase(col.ColumnName.Name)+name, "{int64(1)}", fmt.Sprintf(single, call), []string{testSQL(SQL, g)}, []string{testArgs(statement.Columns)}, want))
+				}
+			}
+		}
+		if g.Stream {
+			SQL := fmt.Sprintf("select %s from "+g.name("%s")+" where %s", strings.Join(names, ", "), table, strings.Join(where, " and "))
+			cases = append(cases, testCase("Each"+modelName+name, "row", fmt.Sprintf(each, modelName, fmt.Sprintf("Each%s%s("+g.args()+", s, ", modelName, name), modelName), []string{testSQL(SQL, g)}, []string{testArgs(statement.Columns)}, fmt.Sprintf("[]*%s{s}", modelName)))
+		}
+		if logic == nil {
+			break
+		}
+	}
+
+	return fmt.Sprintf(`func Test%sCurd(t *testing.T) {
+    s := &%s{
+%s
+    }
+    row := []driver.Value{%s}
+    runCurdCases(t, []curdCase{
+%s
+    })
+}
+`, modelName, modelName, strings.Join(fields, "\n"), strings.Join(row, ", "), strings.Join(cases, "\n"))
+}
+
+func testCase(name string, results string, call string, queries []string, args []string, want string) string {
+	if results != "" {
+		results = fmt.Sprintf("\n            results: [][]driver.Value{%s},", results)
+	}
+	return fmt.Sprintf(`        {
+            name: "%s",%s
+            call: func(ctx context.Context, db DataSource) (interface{}, error) {
+                %s
+            },
+            queries: []string{%s},
+            args: [][]interface{}{%s},
+            want: %s,
+        },`, name, results, call, strings.Join(queries, ", "), strings.Join(args, ", "), want)
+}
+
+// testSQL is the expected statement as a Go literal, passed through bind like the generated functions do.
+func testSQL(query string, g *gen) string {
+	return g.bind("\"" + quote(query) + "\"")
+}
+
+// testFilter is a filter of the column col which only keeps the rows where it is not null.
+func testFilter(col *parser.ColumnDefinition) string {
+	typ, ok := filterTypes[col.Type]
+	if !ok {
+		typ = filterTypes["default"]
+	}
+	switch {
+	case strings.HasPrefix(typ, "OrderedFieldFilter"):
+		return fmt.Sprintf("&%s{FieldFilter: FieldFilter%s{IsNull: &null}}", typ, strings.TrimPrefix(typ, "OrderedFieldFilter"))
+	case typ == "StringFieldFilter":
+		return "&StringFieldFilter{FieldFilter: FieldFilter[string]{IsNull: &null}}"
+	}
+	return fmt.Sprintf("&%s{IsNull: &null}", typ)
+}
+
+func testColumns(columns []*parser.ColumnDefinition, prefix string, g *gen) string {
+	names := make([]string, 0)
+	for _, col := range columns {
+		names = append(names, prefix+g.name(col.ColumnName.Name))
+	}
+	return strings.Join(names, ", ")
+}
+
+func testMarks(n int) string {
+	marks := make([]string, n)
+	for i := range marks {
+		marks[i] = "?"
+	}
+	return strings.Join(marks, ", ")
+}
+
+// testVersionArgs are the arguments of columns where the version is 0.
+func testVersionArgs(columns []*parser.ColumnDefinition, version *parser.ColumnDefinition) string {
+	args := make([]string, 0)
+	for _, col := range columns {
+		arg := "s." + generator.FirstUpperCamelCase(col.ColumnName.Name)
+		if col == version {
+			arg = "int64(0)"
+		}
+		args = append(args, arg)
+	}
+	return "{" + strings.Join(args, ", ") + "}"
+}
+
+func testArgs(columns []*parser.ColumnDefinition) string {
+	args := make([]string, 0)
+	for _, col := range columns {
+		args = append(args, "s."+generator.FirstUpperCamelCase(col.ColumnName.Name))
+	}
+	return "{" + strings.Join(args, ", ") + "}"
+}
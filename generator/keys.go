@@ -0,0 +1,78 @@
+// Copyright 2010-2024 the original author or authors.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+// 	http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package generator
+
+import (
+	"strings"
+
+	"github.com/stella-go/stella/generator/parser"
+)
+
+// UniqKeyPairs returns the columns of the primary and unique keys of a table, the curd functions of single rows are keyed by them.
+func UniqKeyPairs(statement *parser.Statement) [][]*parser.ColumnDefinition {
+	keyPairs := make([][]*parser.ColumnDefinition, 0)
+	for _, col := range statement.Columns {
+		if col.PrimaryKey || col.UniqueKey {
+			keyPairs = append(keyPairs, []*parser.ColumnDefinition{col})
+		}
+	}
+	for _, pair := range statement.PrimaryKeyPairs {
+		p := make([]*parser.ColumnDefinition, 0)
+		for _, k := range pair {
+			for _, c := range statement.Columns {
+				if strings.EqualFold(c.ColumnName.Name, k.Name) {
+					p = append(p, c)
+					break
+				}
+			}
+		}
+		if len(p) != 0 {
+			keyPairs = append(keyPairs, p)
+		}
+	}
+	for _, pair := range statement.UniqKeyPairs {
+		p := make([]*parser.ColumnDefinition, 0)
+		for _, k := range pair {
+			for _, c := range statement.Columns {
+				if strings.EqualFold(c.ColumnName.Name, k.Name) {
+					p = append(p, c)
+					break
+				}
+			}
+		}
+		if len(p) != 0 {
+			keyPairs = append(keyPairs, p)
+		}
+	}
+	return keyPairs
+}
+
+// IndexKeyPairs returns the columns of the indexes of a table, the curd functions of many rows are keyed by them.
+func IndexKeyPairs(statement *parser.Statement) [][]*parser.ColumnDefinition {
+	keyPairs := make([][]*parser.ColumnDefinition, 0)
+	for _, pair := range statement.IndexKeyPairs {
+		p := make([]*parser.ColumnDefinition, 0)
+		for _, k := range pair {
+			for _, c := range statement.Columns {
+				if c.ColumnName.Name == k.Name {
+					p = append(p, c)
+					break
+				}
+			}
+		}
+		keyPairs = append(keyPairs, p)
+	}
+	return keyPairs
+}
@@ -0,0 +1,118 @@
+// Copyright 2010-2024 the original author or authors.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+// 	http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package generator
+
+import (
+	"strings"
+
+	"github.com/stella-go/stella/generator/parser"
+)
+
+var unDeleteMap = map[string]string{
+	`'0'`: `'1'`,
+	`'1'`: `'0'`,
+	`1`:   `0`,
+	`0`:   `1`,
+}
+
+// Logic is the logical delete policy of a table, the values are SQL literals.
+type Logic struct {
+	Column    string
+	Deleted   string
+	Undeleted string
+}
+
+// Alive is the condition of the rows which are not deleted, name quotes the column in the dialect of the statement.
+func (l *Logic) Alive(name func(string) string) string {
+	column := name(l.Column)
+	switch {
+	case l.Deleted == "current_timestamp":
+		return column + " is null"
+	case l.Undeleted != "":
+		return column + " = " + l.Undeleted
+	default:
+		return "(" + column + " is null or " + column + " <> " + l.Deleted + ")"
+	}
+}
+
+// ParseLogic returns the logical delete policy of the table or nil.
+// logic is a comma separated list of [table.]column=deleted[:undeleted] for flag columns
+// and [table.]column for timestamp columns which are set on delete and null otherwise,
+// an entry with a table prefix takes precedence over the entries without.
+func ParseLogic(statement *parser.Statement, logic string) *Logic {
+	var global *Logic
+	for _, entry := range strings.Split(logic, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		name, value, hasValue := entry, "", false
+		if index := strings.Index(entry, "="); index != -1 {
+			name, value, hasValue = entry[:index], entry[index+1:], true
+		}
+		table := ""
+		if index := strings.Index(name, "."); index != -1 {
+			table, name = name[:index], name[index+1:]
+			if !strings.EqualFold(table, statement.TableName.Name) {
+				continue
+			}
+		}
+		var column *parser.ColumnDefinition
+		for _, c := range statement.Columns {
+			if c.ColumnName.Name == name {
+				column = c
+				break
+			}
+		}
+		if column == nil {
+			continue
+		}
+		var policy *Logic
+		if hasValue {
+			deleted, undeleted := value, ""
+			if index := strings.Index(value, ":"); index != -1 {
+				deleted, undeleted = value[:index], value[index+1:]
+			}
+			deleted = logicLiteral(column, deleted)
+			if undeleted != "" {
+				undeleted = logicLiteral(column, undeleted)
+			} else {
+				undeleted = unDeleteMap[deleted]
+			}
+			policy = &Logic{Column: name, Deleted: deleted, Undeleted: undeleted}
+		} else if column.Type == "DATE" || column.Type == "DATETIME" || column.Type == "TIMESTAMP" {
+			policy = &Logic{Column: name, Deleted: "current_timestamp", Undeleted: "null"}
+		} else {
+			continue
+		}
+		if table != "" {
+			return policy
+		}
+		if global == nil {
+			global = policy
+		}
+	}
+	return global
+}
+
+func logicLiteral(column *parser.ColumnDefinition, value string) string {
+	if len(value) >= 2 && ((strings.HasPrefix(value, "\"") && strings.HasSuffix(value, "\"")) || (strings.HasPrefix(value, "'") && strings.HasSuffix(value, "'"))) {
+		value = value[1 : len(value)-1]
+	}
+	if column.Type != "TINYINT" && column.Type != "INT" && column.Type != "BIGINT" && column.Type != "FLOAT" {
+		value = "'" + value + "'"
+	}
+	return value
+}
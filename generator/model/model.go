@@ -85,7 +85,8 @@ func (s *Struct) toString() string {
 	return "func (s *" + s.name + ") String() string {\n\treturn fmt.Sprintf(\"" + s.name + "{" + strings.Join(formats, ", ") + "}\", " + strings.Join(args, ", ") + ")\n}\n"
 }
 
-func Generate(pkg string, statements []*parser.Statement, banner bool, gorm bool) string {
+func Generate(pkg string, statements []*parser.Statement, banner bool, gorm bool, round string) string {
+	times := generator.ParseTimePolicy(round, "")
 	importsMap := make(map[string]common.Void)
 	importsMap["fmt"] = common.Null
 	structs := make([]string, 0)
@@ -108,6 +109,9 @@ func Generate(pkg string, statements []*parser.Statement, banner bool, gorm bool
 				if col.NotNull {
 					gormTags = append(gormTags, "not null")
 				}
+				if generator.IsTime(col) && col.Precision > 0 {
+					gormTags = append(gormTags, fmt.Sprintf("precision:%d", col.Precision))
+				}
 				if col.DefaultValue != nil && col.DefaultValue.DefaultValue {
 					gormTags = append(gormTags, "default:"+col.DefaultValue.Value)
 				}
@@ -126,8 +130,8 @@ func Generate(pkg string, statements []*parser.Statement, banner bool, gorm bool
 				if col.CurrentTimestamp {
 					freeTags = append(freeTags, "current-timestamp")
 				}
-				if col.Type == "DATE" || col.Type == "DATETIME" || col.Type == "TIMESTAMP" {
-					freeTags = append(freeTags, "round='s'")
+				if precision := times.Precision(col); precision != "" {
+					freeTags = append(freeTags, fmt.Sprintf("round='%s'", precision))
 				}
 
 				tag := fmt.Sprintf("form:\"%s\" json:\"%s,omitempty\" @free:\"%s\"", generator.ToSnakeCase(col.ColumnName.Name), generator.ToSnakeCase(col.ColumnName.Name), strings.Join(freeTags, ","))
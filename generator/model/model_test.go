@@ -36,8 +36,8 @@ func TestGenerate(t *testing.T) {
 `
 
 	s := parser.Parse(sql)
-	file := Generate("model", s, true, true)
+	file := Generate("model", s, true, true, "s")
 	t.Log(file)
-	file = Generate("model", s, true, false)
+	file = Generate("model", s, true, false, "auto")
 	t.Log(file)
 }
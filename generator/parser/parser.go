@@ -16,6 +16,7 @@ package parser
 
 import (
 	"fmt"
+	"strconv"
 	"strings"
 
 	"github.com/stella-go/stella/antlr4/antlr"
@@ -110,6 +111,11 @@ func (v *MysqlVisitor) VisitColumnDeclaration(ctx *mysql.ColumnDeclarationContex
 
 func (v *MysqlVisitor) VisitColumnDefinition(ctx *mysql.ColumnDefinitionContext) interface{} {
 	column := &ColumnDefinition{Type: ctx.DataType().Accept(v).(string)}
+	if dataType, ok := ctx.DataType().(*mysql.DimensionDataTypeContext); ok && (column.Type == "DATETIME" || column.Type == "TIMESTAMP") {
+		if dimension, ok := dataType.LengthOneDimension().(*mysql.LengthOneDimensionContext); ok {
+			column.Precision, _ = strconv.Atoi(dimension.DecimalLiteral().GetText())
+		}
+	}
 	autoIncrementOnUpdate, defaultOnUpdate := false, false
 	for _, constraint := range ctx.AllColumnConstraint() {
 		obj := constraint.Accept(v)
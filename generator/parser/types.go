@@ -42,6 +42,7 @@ func (p *NameDefinition) String() string {
 type ColumnDefinition struct {
 	ColumnName       *NameDefinition
 	Type             string
+	Precision        int
 	PrimaryKey       bool
 	UniqueKey        bool
 	AutoIncrement    bool
@@ -50,6 +51,7 @@ type ColumnDefinition struct {
 	DefaultValue     *DefaultValue
 	CurrentTimestamp bool
 	Comment          *Comment
+	Version          bool
 }
 
 func (p *ColumnDefinition) Fill(sql string) {
@@ -65,9 +67,10 @@ func (p *ColumnDefinition) Fill(sql string) {
 }
 
 func (p *ColumnDefinition) String() string {
-	return fmt.Sprintf("ColumnDefinition{ ColumnName: %v, Type: %v, PrimaryKey: %v, UniqKey: %v, AutoIncrement: %v, OnUpdate: %v, NotNull: %v, DefaultValue: %v, CurrentTimestamp: %v, Comment: %s}",
+	return fmt.Sprintf("ColumnDefinition{ ColumnName: %v, Type: %v, Precision: %v, PrimaryKey: %v, UniqKey: %v, AutoIncrement: %v, OnUpdate: %v, NotNull: %v, DefaultValue: %v, CurrentTimestamp: %v, Comment: %s, Version: %v}",
 		p.ColumnName,
 		p.Type,
+		p.Precision,
 		p.PrimaryKey,
 		p.UniqueKey,
 		p.AutoIncrement,
@@ -76,6 +79,7 @@ func (p *ColumnDefinition) String() string {
 		p.DefaultValue,
 		p.CurrentTimestamp,
 		p.Comment,
+		p.Version,
 	)
 }
 
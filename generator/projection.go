@@ -0,0 +1,45 @@
+// Copyright 2010-2024 the original author or authors.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+// 	http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package generator
+
+import (
+	"strings"
+
+	"github.com/stella-go/stella/generator/parser"
+)
+
+var largeTypes = map[string]bool{
+	"TINYTEXT":   true,
+	"TEXT":       true,
+	"MEDIUMTEXT": true,
+	"LONGTEXT":   true,
+	"TINYBLOB":   true,
+	"BLOB":       true,
+	"MEDIUMBLOB": true,
+	"LONGBLOB":   true,
+	"JSON":       true,
+}
+
+// ListColumns returns the columns a list query selects when the caller does not ask for others,
+// every column but the large text and blob ones.
+func ListColumns(statement *parser.Statement) []*parser.ColumnDefinition {
+	columns := make([]*parser.ColumnDefinition, 0)
+	for _, col := range statement.Columns {
+		if !largeTypes[strings.ToUpper(col.Type)] {
+			columns = append(columns, col)
+		}
+	}
+	return columns
+}
@@ -8,6 +8,6 @@ import (
 
 func TestGenerateDoc(t *testing.T) {
 	s := parser.Parse(sql)
-	file := GenerateDoc(s, true)
+	file := GenerateDoc(s, true, true, true, true, true, true, "auto", "utc")
 	t.Log(file)
 }
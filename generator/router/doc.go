@@ -65,6 +65,20 @@ func (p *LinkedMap) MarshalJSON() ([]byte, error) {
 	return []byte(s), nil
 }
 
+var filterSample = map[string]string{
+	"TINYINT":   "gte",
+	"INT":       "gte",
+	"BIGINT":    "gte",
+	"FLOAT":     "gte",
+	"CHAR":      "prefix",
+	"VARCHAR":   "prefix",
+	"TEXT":      "prefix",
+	"DATE":      "lt",
+	"DATETIME":  "lt",
+	"TIMESTAMP": "lt",
+	"default":   "eq",
+}
+
 var typeSample = map[string]interface{}{
 	"TINYINT":   1,
 	"INT":       1,
@@ -79,12 +93,13 @@ var typeSample = map[string]interface{}{
 	"default":   struct{}{},
 }
 
-func GenerateDoc(statements []*parser.Statement, banner bool) string {
+func GenerateDoc(statements []*parser.Statement, banner bool, keyset bool, filter bool, sort bool, projection bool, mask bool, round string, zone string) string {
 	header := `HOST=127.0.0.1
 PORT=8080
 `
 
 	paragraphs := make([]string, 0)
+	times := generator.ParseTimePolicy(round, zone)
 
 	for _, statement := range statements {
 		name := ""
@@ -95,19 +110,31 @@ PORT=8080
 		}
 		paragraphs = append(paragraphs, "## ==================== "+name+" ====================\n")
 
-		paragraph := fields_doc(statement)
+		paragraph := fields_doc(statement, times)
 		paragraphs = append(paragraphs, paragraph)
 
-		paragraph = c_doc(statement)
+		paragraph = c_doc(statement, times)
 		paragraphs = append(paragraphs, paragraph)
 
-		paragraph = u_doc(statement)
+		paragraph = u_doc(statement, mask, times)
 		paragraphs = append(paragraphs, paragraph)
 
-		paragraph = r_doc(statement)
+		paragraph = r_doc(statement, sort, projection, times)
 		paragraphs = append(paragraphs, paragraph)
 
-		paragraph = d_doc(statement)
+		if filter {
+			paragraph = rf_doc(statement, sort, projection, times)
+			paragraphs = append(paragraphs, paragraph)
+		}
+
+		if keyset {
+			paragraph = ra_doc(statement, times)
+			if paragraph != "" {
+				paragraphs = append(paragraphs, paragraph)
+			}
+		}
+
+		paragraph = d_doc(statement, times)
 		paragraphs = append(paragraphs, paragraph)
 	}
 
@@ -118,7 +145,7 @@ PORT=8080
 	return fmt.Sprintf("# Application Document\n%s\n%s\n%s", bannerS, header, strings.Join(paragraphs, "\n"))
 }
 
-func fields_doc(statement *parser.Statement) string {
+func fields_doc(statement *parser.Statement, times *generator.TimePolicy) string {
 	name := ""
 	if statement.Comment != nil {
 		name = statement.Comment.Comment
@@ -140,12 +167,25 @@ func fields_doc(statement *parser.Statement) string {
 		} else {
 			name = column.ColumnName.Name
 		}
+		notes := make([]string, 0)
+		if digits := fractionDigits(column, times); digits > 0 {
+			notes = append(notes, fmt.Sprintf("%d fractional second digits", digits))
+		}
+		switch times.Location(column) {
+		case "time.UTC":
+			notes = append(notes, "UTC")
+		case "time.Local":
+			notes = append(notes, "server local time")
+		}
+		if len(notes) != 0 {
+			name += " (" + strings.Join(notes, ", ") + ")"
+		}
 		doc += fmt.Sprintf(fmt.Sprintf("%%-%ds : %%s\n", maxLenth), generator.ToSnakeCase(column.ColumnName.Name), name)
 	}
 	return doc
 }
 
-func c_doc(statement *parser.Statement) string {
+func c_doc(statement *parser.Statement, times *generator.TimePolicy) string {
 	name := ""
 	if statement.Comment != nil {
 		name = statement.Comment.Comment
@@ -157,7 +197,7 @@ func c_doc(statement *parser.Statement) string {
 		if column.AutoIncrement || column.OnUpdate || column.CurrentTimestamp {
 			continue
 		}
-		data.Put(generator.ToSnakeCase(column.ColumnName.Name), typeSample[column.Type])
+		data.Put(generator.ToSnakeCase(column.ColumnName.Name), sampleOf(column, times))
 	}
 	bts, err := json.Marshal(&RequestBean{Timestamp: 1681466601123, Data: data})
 	if err != nil {
@@ -190,7 +230,7 @@ curl -XPOST -H "Content-Type: application/json" "http://${HOST}:${PORT}/api/%s"
 `, name, generator.ToStrikeCase(statement.TableName.Name), content, result, generator.ToStrikeCase(statement.TableName.Name), content, result)
 }
 
-func u_doc(statement *parser.Statement) string {
+func u_doc(statement *parser.Statement, mask bool, times *generator.TimePolicy) string {
 	name := ""
 	if statement.Comment != nil {
 		name = statement.Comment.Comment
@@ -202,7 +242,7 @@ func u_doc(statement *parser.Statement) string {
 		if column.OnUpdate || column.CurrentTimestamp {
 			continue
 		}
-		data.Put(generator.ToSnakeCase(column.ColumnName.Name), typeSample[column.Type])
+		data.Put(generator.ToSnakeCase(column.ColumnName.Name), sampleOf(column, times))
 	}
 	bts, err := json.Marshal(&RequestBean{Timestamp: 1681466601123, Data: data})
 	if err != nil {
@@ -216,8 +256,15 @@ func u_doc(statement *parser.Statement) string {
 	}
 	result := string(bts)
 
+	note := ""
+	if version := generator.VersionColumn(statement); version != nil {
+		note = fmt.Sprintf("The request must carry the current `%s`, code 409 is returned when the row has been modified in the meantime.\n", generator.ToSnakeCase(version.ColumnName.Name))
+	}
+	if mask && hasPrimaryKey(statement) {
+		note += "The query parameter `fields` is a comma separated list of the columns to update, they are written even when absent from `data`, which sets them to null, without it only the columns present in `data` are updated.\n"
+	}
 	return fmt.Sprintf(`### Update %s
-- Ruquest
+%s- Ruquest
 PUT /api/%s
 
 Content-Type: application/json;
@@ -232,10 +279,10 @@ Content-Type: application/json;
 curl -XPUT -H "Content-Type: application/json" "http://${HOST}:${PORT}/api/%s" -d '%s'
 %s
 `+"```"+`
-`, name, generator.ToStrikeCase(statement.TableName.Name), content, result, generator.ToStrikeCase(statement.TableName.Name), content, result)
+`, name, note, generator.ToStrikeCase(statement.TableName.Name), content, result, generator.ToStrikeCase(statement.TableName.Name), content, result)
 }
 
-func r_doc(statement *parser.Statement) string {
+func r_doc(statement *parser.Statement, sort bool, projection bool, times *generator.TimePolicy) string {
 	paragraph := ""
 	name := ""
 	if statement.Comment != nil {
@@ -248,10 +295,17 @@ func r_doc(statement *parser.Statement) string {
 		if column.AutoIncrement || column.OnUpdate || column.CurrentTimestamp {
 			continue
 		}
-		data.Put(generator.ToSnakeCase(column.ColumnName.Name), typeSample[column.Type])
+		data.Put(generator.ToSnakeCase(column.ColumnName.Name), sampleOf(column, times))
 	}
 	data.Put("page", 1)
 	data.Put("size", 10)
+	note := ""
+	if sort {
+		note = sortNote(statement, data)
+	}
+	if projection {
+		note += columnsNote(statement, data)
+	}
 	bts, err := json.Marshal(&RequestBean{Timestamp: 1681466601123, Data: data})
 	if err != nil {
 		panic(err)
@@ -260,7 +314,7 @@ func r_doc(statement *parser.Statement) string {
 
 	data = NewLinkedMap()
 	for _, column := range statement.Columns {
-		data.Put(generator.ToSnakeCase(column.ColumnName.Name), typeSample[column.Type])
+		data.Put(generator.ToSnakeCase(column.ColumnName.Name), sampleOf(column, times))
 	}
 	bts, err = json.Marshal(&ResultBean{Code: 200, Message: "success", Data: map[string]interface{}{
 		"count": 1,
@@ -271,7 +325,7 @@ func r_doc(statement *parser.Statement) string {
 	}
 	result := string(bts)
 
-	paragraph += fmt.Sprintf(`### Query All %s
+	paragraph += fmt.Sprintf(`### Query All %s%s
 - Ruquest
 POST /api/%s/all
 
@@ -288,14 +342,14 @@ curl -XPOST -H "Content-Type: application/json" "http://${HOST}:${PORT}/api/%s/m
 %s
 `+"```"+`
 
-`, name, generator.ToStrikeCase(statement.TableName.Name), content, result, generator.ToStrikeCase(statement.TableName.Name), content, result)
+`, name, note, generator.ToStrikeCase(statement.TableName.Name), content, result, generator.ToStrikeCase(statement.TableName.Name), content, result)
 
 	data = NewLinkedMap()
 	primaryKeys := getPrimaryKeyPairs(statement)
 	if len(primaryKeys) > 0 {
 		keys := primaryKeys[0]
 		for _, column := range keys {
-			data.Put(generator.ToSnakeCase(column.ColumnName.Name), typeSample[column.Type])
+			data.Put(generator.ToSnakeCase(column.ColumnName.Name), sampleOf(column, times))
 		}
 	}
 	bts, err = json.Marshal(&RequestBean{Timestamp: 1681466601123, Data: data})
@@ -306,7 +360,7 @@ curl -XPOST -H "Content-Type: application/json" "http://${HOST}:${PORT}/api/%s/m
 
 	data = NewLinkedMap()
 	for _, column := range statement.Columns {
-		data.Put(generator.ToSnakeCase(column.ColumnName.Name), typeSample[column.Type])
+		data.Put(generator.ToSnakeCase(column.ColumnName.Name), sampleOf(column, times))
 	}
 	bts, err = json.Marshal(&ResultBean{Code: 200, Message: "success", Data: data})
 	if err != nil {
@@ -334,7 +388,133 @@ curl -XPOST -H "Content-Type: application/json" "http://${HOST}:${PORT}/api/%s/o
 	return paragraph
 }
 
-func d_doc(statement *parser.Statement) string {
+func rf_doc(statement *parser.Statement, sort bool, projection bool, times *generator.TimePolicy) string {
+	name := ""
+	if statement.Comment != nil {
+		name = statement.Comment.Comment
+	} else {
+		name = statement.TableName.Name
+	}
+	filter := NewLinkedMap()
+	for _, column := range statement.Columns {
+		if column.AutoIncrement || column.OnUpdate || column.CurrentTimestamp {
+			continue
+		}
+		operator, ok := filterSample[column.Type]
+		if !ok {
+			operator = filterSample["default"]
+		}
+		filter.Put(generator.ToSnakeCase(column.ColumnName.Name), map[string]interface{}{operator: sampleOf(column, times)})
+	}
+	data := NewLinkedMap()
+	data.Put("filter", filter)
+	data.Put("page", 1)
+	data.Put("size", 10)
+	note := ""
+	if sort {
+		note = sortNote(statement, data)
+	}
+	if projection {
+		note += columnsNote(statement, data)
+	}
+	bts, err := json.Marshal(&RequestBean{Timestamp: 1681466601123, Data: data})
+	if err != nil {
+		panic(err)
+	}
+	content := string(bts)
+
+	data = NewLinkedMap()
+	for _, column := range statement.Columns {
+		data.Put(generator.ToSnakeCase(column.ColumnName.Name), sampleOf(column, times))
+	}
+	bts, err = json.Marshal(&ResultBean{Code: 200, Message: "success", Data: map[string]interface{}{
+		"count": 1,
+		"list":  []*LinkedMap{data},
+	}})
+	if err != nil {
+		panic(err)
+	}
+	result := string(bts)
+
+	return fmt.Sprintf(`### Query All %s By Filter
+Every column accepts `+"`eq` `ne` `in` `not_in` `is_null`"+`, numbers and times also accept `+"`gt` `gte` `lt` `lte` `between`"+`, strings also accept `+"`like` `prefix`"+`. The columns are combined with and, `+"`or`"+` is a list of filters of which at least one must match.%s
+- Request
+POST /api/%s/many
+
+Content-Type: application/json;
+
+%s
+
+- Response
+%s
+
+- example
+`+"```bash"+`
+curl -XPOST -H "Content-Type: application/json" "http://${HOST}:${PORT}/api/%s/many" -d '%s'
+%s
+`+"```"+`
+`, name, note, generator.ToStrikeCase(statement.TableName.Name), content, result, generator.ToStrikeCase(statement.TableName.Name), content, result)
+}
+
+func ra_doc(statement *parser.Statement, times *generator.TimePolicy) string {
+	if !hasPrimaryKey(statement) {
+		return ""
+	}
+	name := ""
+	if statement.Comment != nil {
+		name = statement.Comment.Comment
+	} else {
+		name = statement.TableName.Name
+	}
+	data := NewLinkedMap()
+	for _, column := range statement.Columns {
+		if column.AutoIncrement || column.OnUpdate || column.CurrentTimestamp {
+			continue
+		}
+		data.Put(generator.ToSnakeCase(column.ColumnName.Name), sampleOf(column, times))
+	}
+	data.Put("cursor", "")
+	data.Put("size", 10)
+	bts, err := json.Marshal(&RequestBean{Timestamp: 1681466601123, Data: data})
+	if err != nil {
+		panic(err)
+	}
+	content := string(bts)
+
+	data = NewLinkedMap()
+	for _, column := range statement.Columns {
+		data.Put(generator.ToSnakeCase(column.ColumnName.Name), sampleOf(column, times))
+	}
+	bts, err = json.Marshal(&ResultBean{Code: 200, Message: "success", Data: map[string]interface{}{
+		"next": "WzEwXQ",
+		"list": []*LinkedMap{data},
+	}})
+	if err != nil {
+		panic(err)
+	}
+	result := string(bts)
+
+	return fmt.Sprintf(`### Query After %s
+Pass the `+"`next`"+` of the previous response as `+"`cursor`"+` to fetch the following page, an empty `+"`next`"+` means there are no more rows.
+- Request
+POST /api/%s/after
+
+Content-Type: application/json;
+
+%s
+
+- Response
+%s
+
+- example
+`+"```bash"+`
+curl -XPOST -H "Content-Type: application/json" "http://${HOST}:${PORT}/api/%s/after" -d '%s'
+%s
+`+"```"+`
+`, name, generator.ToStrikeCase(statement.TableName.Name), content, result, generator.ToStrikeCase(statement.TableName.Name), content, result)
+}
+
+func d_doc(statement *parser.Statement, times *generator.TimePolicy) string {
 	name := ""
 	if statement.Comment != nil {
 		name = statement.Comment.Comment
@@ -346,7 +526,7 @@ func d_doc(statement *parser.Statement) string {
 	if len(primaryKeys) > 0 {
 		keys := primaryKeys[0]
 		for _, column := range keys {
-			data.Put(generator.ToSnakeCase(column.ColumnName.Name), typeSample[column.Type])
+			data.Put(generator.ToSnakeCase(column.ColumnName.Name), sampleOf(column, times))
 		}
 	}
 	bts, err := json.Marshal(&RequestBean{Timestamp: 1681466601123, Data: data})
@@ -380,6 +560,44 @@ curl -XDELETE -H "Content-Type: application/json" "http://${HOST}:${PORT}/api/%s
 `, name, generator.ToStrikeCase(statement.TableName.Name), content, result, generator.ToStrikeCase(statement.TableName.Name), content, result)
 }
 
+// sampleOf returns the sample value of a column, DATETIME samples carry the fractional seconds the column keeps.
+func sampleOf(column *parser.ColumnDefinition, times *generator.TimePolicy) interface{} {
+	if digits := fractionDigits(column, times); column.Type == "DATETIME" && digits > 0 {
+		return typeSample[column.Type].(string) + "." + "123456"[:digits]
+	}
+	return typeSample[column.Type]
+}
+
+// fractionDigits returns the fractional second digits the values of a time column keep, the precision of the
+// column cut to the unit the curd rounds them to.
+func fractionDigits(column *parser.ColumnDefinition, times *generator.TimePolicy) int {
+	if !generator.IsTime(column) || column.Type == "DATE" || column.Precision <= 0 {
+		return 0
+	}
+	digits := column.Precision
+	if digits > 6 {
+		digits = 6
+	}
+	switch times.Precision(column) {
+	case "s":
+		return 0
+	case "ms":
+		if digits > 3 {
+			return 3
+		}
+	}
+	return digits
+}
+
+func hasPrimaryKey(statement *parser.Statement) bool {
+	for _, col := range statement.Columns {
+		if col.PrimaryKey {
+			return true
+		}
+	}
+	return len(statement.PrimaryKeyPairs) > 0
+}
+
 func getPrimaryKeyPairs(statement *parser.Statement) [][]*parser.ColumnDefinition {
 	keyPairs := make([][]*parser.ColumnDefinition, 0)
 	for _, col := range statement.Columns {
@@ -403,3 +621,26 @@ func getPrimaryKeyPairs(statement *parser.Statement) [][]*parser.ColumnDefinitio
 	}
 	return keyPairs
 }
+
+// sortNote puts a sort sample into the request data and describes the sortable columns.
+func sortNote(statement *parser.Statement, data *LinkedMap) string {
+	columns := make([]string, 0)
+	for _, column := range generator.SortableColumns(statement) {
+		columns = append(columns, "`"+generator.ToSnakeCase(column.ColumnName.Name)+"`")
+	}
+	if len(columns) == 0 {
+		return "\nNo column of this table can be sorted."
+	}
+	data.Put("sort", "-"+strings.Trim(columns[0], "`"))
+	return "\n`sort` is a comma separated list of columns, a column prefixed with `-` is sorted descending. Sortable columns: " + strings.Join(columns, " ") + "."
+}
+
+// columnsNote puts the columns listed by default into the request data and describes them.
+func columnsNote(statement *parser.Statement, data *LinkedMap) string {
+	columns := make([]string, 0)
+	for _, column := range generator.ListColumns(statement) {
+		columns = append(columns, generator.ToSnakeCase(column.ColumnName.Name))
+	}
+	data.Put("columns", strings.Join(columns, ","))
+	return "\n`columns` is a comma separated list of the columns to return, without it every column but the large text and blob ones is returned."
+}
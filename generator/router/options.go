@@ -0,0 +1,34 @@
+// Copyright 2010-2024 the original author or authors.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package router
+
+// Options are the flags of the command the generated router depends on.
+type Options struct {
+	Banner     bool
+	Ctx        bool
+	Keyset     bool
+	Filter     bool
+	Sort       bool
+	Projection bool
+	Mask       bool
+}
+
+// ctx passes the context of the request as the first argument of the service calls with -ctx.
+func (o *Options) ctx() string {
+	if o.Ctx {
+		return "c.Request.Context(), "
+	}
+	return ""
+}
@@ -25,7 +25,7 @@ import (
 	"github.com/stella-go/stella/version"
 )
 
-func Generate(pkg string, statements []*parser.Statement, banner bool) string {
+func Generate(pkg string, statements []*parser.Statement, o *Options) string {
 	importsMap := make(map[string]common.Void)
 	functions := make([]string, 0)
 	routers := make([]string, 0)
@@ -35,27 +35,37 @@ func Generate(pkg string, statements []*parser.Statement, banner bool) string {
 	importsMap["github.com/stella-go/siu/t"] = common.Null
 	for _, statement := range statements {
 		functions = append(functions, "// ==================== "+generator.FirstUpperCamelCase(statement.TableName.Name)+" ====================")
-		function, imports, router := c(statement)
+		function, imports, router := c(statement, o)
 		functions = append(functions, function)
 		for _, i := range imports {
 			importsMap[i] = common.Null
 		}
 		routers = append(routers, router)
 
-		function, imports, router = u(statement)
+		function, imports, router = u(statement, o)
 		functions = append(functions, function)
 		for _, i := range imports {
 			importsMap[i] = common.Null
 		}
 		routers = append(routers, router)
 
-		function, imports, router = r(statement)
+		function, imports, router = r(statement, o)
 		functions = append(functions, function)
 		for _, i := range imports {
 			importsMap[i] = common.Null
 		}
 		routers = append(routers, router)
-		function, imports, router = d(statement)
+		if o.Keyset {
+			function, imports, router = ra(statement, o)
+			if router != "" {
+				functions = append(functions, function)
+				for _, i := range imports {
+					importsMap[i] = common.Null
+				}
+				routers = append(routers, router)
+			}
+		}
+		function, imports, router = d(statement, o)
 		functions = append(functions, function)
 		for _, i := range imports {
 			importsMap[i] = common.Null
@@ -72,7 +82,7 @@ func Generate(pkg string, statements []*parser.Statement, banner bool) string {
 	}
 
 	typeLines := `type Router struct {
-    Service *service.Service ` + "`" + `@siu:""` + "`" + `
+    Service service.Interface ` + "`" + `@siu:""` + "`" + `
 }
 
 func (p *Router) Router() map[string]gin.HandlerFunc {
@@ -81,13 +91,14 @@ func (p *Router) Router() map[string]gin.HandlerFunc {
     }
 }`
 	bannerS := ""
-	if banner {
+	if o.Banner {
 		bannerS = fmt.Sprintf("\n/**\n * Auto Generate by github.com/stella-go/stella %s on %s.\n */\n", version.VERSION, time.Now().Format("2006/01/02"))
 	}
-	return fmt.Sprintf("package %s\n%s\nimport (\n%s\n)\n\n%s\n\n%s", pkg, bannerS, strings.Join(importsLines, "\n"), fmt.Sprintf(typeLines, strings.Join(routers, "\n")), strings.Join(functions, "\n"))
+	functionLines := strings.Join(functions, "\n")
+	return fmt.Sprintf("package %s\n%s\nimport (\n%s\n)\n\n%s\n\n%s", pkg, bannerS, strings.Join(importsLines, "\n"), fmt.Sprintf(typeLines, strings.Join(routers, "\n")), functionLines)
 }
 
-func c(statement *parser.Statement) (string, []string, string) {
+func c(statement *parser.Statement, o *Options) (string, []string, string) {
 	modelName := generator.FirstUpperCamelCase(statement.TableName.Name)
 
 	funcLines := fmt.Sprintf(`func (p *Router) Create%s(c *gin.Context) {
@@ -104,7 +115,7 @@ func c(statement *parser.Statement) (string, []string, string) {
         c.JSON(200, t.FailWith(400, "bad request"))
         return
     }
-    err = p.Service.Create%s(s)
+    err = p.Service.Create%s(`+o.ctx()+`s)
     if err != nil {
         siu.ERROR("__LINE__ create %s error:", err)
         c.JSON(200, t.FailWith(500, "system error"))
@@ -116,9 +127,27 @@ func c(statement *parser.Statement) (string, []string, string) {
 	return funcLines, nil, fmt.Sprintf(`        "POST /api/%s": p.Create%s,`, generator.ToStrikeCase(statement.TableName.Name), modelName)
 }
 
-func u(statement *parser.Statement) (string, []string, string) {
+func u(statement *parser.Statement, o *Options) (string, []string, string) {
 	modelName := generator.FirstUpperCamelCase(statement.TableName.Name)
+	stale := ""
+	var imports []string
+	if generator.VersionColumn(statement) != nil {
+		stale = `        if errors.Is(err, model.ErrStaleObject) {
+            c.JSON(200, t.FailWith(409, "stale object"))
+            return
+        }
+`
+		imports = []string{"errors"}
+	}
 
+	update := fmt.Sprintf("err = p.Service.Update%s("+o.ctx()+"s)", modelName)
+	if o.Mask && hasPrimaryKey(statement) {
+		update = fmt.Sprintf(`if fields := c.Query("fields"); fields != "" {
+        err = p.Service.Update%sFields(`+o.ctx()+`s, model.ParseColumns[model.%sColumn](fields))
+    } else {
+        err = p.Service.Update%s(`+o.ctx()+`s)
+    }`, modelName, modelName, modelName)
+	}
 	funcLines := fmt.Sprintf(`func (p *Router) Update%s(c *gin.Context) {
     request := &t.RequestBean[*model.%s]{}
     err := c.ShouldBind(request)
@@ -133,28 +162,70 @@ func u(statement *parser.Statement) (string, []string, string) {
         c.JSON(200, t.FailWith(400, "bad request"))
         return
     }
-    err = p.Service.Update%s(s)
+    %s
     if err != nil {
-        siu.ERROR("__LINE__ update %s error:", err)
+%s        siu.ERROR("__LINE__ update %s error:", err)
         c.JSON(200, t.FailWith(500, "system error"))
     } else {
         c.JSON(200, t.Success())
     }
 }
-`, modelName, modelName, modelName, modelName)
-	return funcLines, nil, fmt.Sprintf(`        "PUT /api/%s": p.Update%s,`, generator.ToStrikeCase(statement.TableName.Name), modelName)
+`, modelName, modelName, update, stale, modelName)
+	return funcLines, imports, fmt.Sprintf(`        "PUT /api/%s": p.Update%s,`, generator.ToStrikeCase(statement.TableName.Name), modelName)
 }
 
-func r(statement *parser.Statement) (string, []string, string) {
+// argumentCheck answers the ArgumentError of a query, a bad order, column, cursor or filter, with 400.
+const argumentCheck = `        var argument *model.ArgumentError
+        if errors.As(err, &argument) {
+            c.JSON(200, t.FailWith(400, argument.Message))
+            return
+        }
+`
+
+func r(statement *parser.Statement, o *Options) (string, []string, string) {
 	funcLines := ""
 	routers := make([]string, 0)
 	modelName := generator.FirstUpperCamelCase(statement.TableName.Name)
+	sortField, sortVar, sortAssign, ordersArg := "", "", "", ""
+	if o.Sort {
+		sortField = "\n        Sort string `form:\"sort\" json:\"sort\"`"
+		sortVar = "\n    var orders []model.Order"
+		sortAssign = "\n        orders = model.ParseOrders(data.Sort)"
+		ordersArg = "orders, "
+	}
+	columnsField, columnsVar, columnsAssign, columnsArg := "", "", "", ""
+	if o.Projection {
+		columnsField = "\n        Columns string `form:\"columns\" json:\"columns\"`"
+		columnsVar = fmt.Sprintf("\n    columns := model.%sListColumns", modelName)
+		columnsAssign = fmt.Sprintf(`
+        if data.Columns != "" {
+            columns = model.ParseColumns[model.%sColumn](data.Columns)
+        }`, modelName)
+		columnsArg = "columns, "
+	}
+	refused := ""
+	var imports []string
+	if o.Sort || o.Filter || o.Projection {
+		refused, imports = argumentCheck, []string{"errors"}
+	}
+	filterField := ""
+	query := fmt.Sprintf("count, list, err := p.Service.QueryMany%s("+o.ctx()+"s, %s%spage, size)", modelName, ordersArg, columnsArg)
+	if o.Filter {
+		filterField = fmt.Sprintf("\n        Filter *model.%sFilter `form:\"filter\" json:\"filter\"`", modelName)
+		query = fmt.Sprintf(`var count int
+    var list []*model.%s
+    if data != nil && data.Filter != nil {
+        count, list, err = p.Service.QueryMany%sByFilter(`+o.ctx()+`data.Filter, %s%spage, size)
+    } else {
+        count, list, err = p.Service.QueryMany%s(`+o.ctx()+`s, %s%spage, size)
+    }`, modelName, modelName, ordersArg, columnsArg, modelName, ordersArg, columnsArg)
+	}
 
 	funcLines += fmt.Sprintf(`func (p *Router) QueryMany%s(c *gin.Context) {
     type Pageable struct {
         *model.%s
         Page int `+"`form:\"page\" json:\"page\"`"+`
-        Size int `+"`form:\"size\" json:\"size\"`"+`
+        Size int `+"`form:\"size\" json:\"size\"`"+`%s%s%s
     }
     request := &t.RequestBean[*Pageable]{}
     err := c.ShouldBind(request)
@@ -165,11 +236,11 @@ func r(statement *parser.Statement) (string, []string, string) {
     }
     data := request.Data
     var s *model.%s
-    var page, size int
+    var page, size int%s%s
     if data != nil {
         s = data.%s
         page = data.Page
-        size = data.Size
+        size = data.Size%s%s
     }
     if page <= 0 {
         page = 1
@@ -181,15 +252,15 @@ func r(statement *parser.Statement) (string, []string, string) {
         Count int `+"`json:\"count\"`"+`
         List []*model.%s `+"`json:\"list\"`"+`
     }
-    count, list, err := p.Service.QueryMany%s(s, page, size)
+    %s
     if err != nil {
-        siu.ERROR("__LINE__ query %s error:", err)
+%s        siu.ERROR("__LINE__ query %s error:", err)
         c.JSON(200, t.FailWith(500, "system error"))
     } else {
         c.JSON(200, t.SuccessWith(&PageableResult{Count: count, List: list}))
     }
 }
-`, modelName, modelName, modelName, modelName, modelName, modelName, modelName)
+`, modelName, modelName, filterField, sortField, columnsField, modelName, sortVar, columnsVar, modelName, sortAssign, columnsAssign, modelName, query, refused, modelName)
 	routers = append(routers, fmt.Sprintf(`        "POST /api/%s/many": p.QueryMany%s,`, generator.ToStrikeCase(statement.TableName.Name), modelName))
 	primaryKeyNames := make([]string, 0)
 	if len(statement.PrimaryKeyPairs) > 0 {
@@ -213,7 +284,7 @@ func r(statement *parser.Statement) (string, []string, string) {
         c.JSON(200, t.FailWith(400, "bad request"))
         return
     }
-    one, err := p.Service.Query%s(s)
+    one, err := p.Service.Query%s(`+o.ctx()+`s)
     if err != nil {
         siu.ERROR("__LINE__ query %s error:", err)
         c.JSON(200, t.FailWith(500, "system error"))
@@ -224,10 +295,57 @@ func r(statement *parser.Statement) (string, []string, string) {
 `, modelName, modelName, modelName, modelName)
 		routers = append(routers, fmt.Sprintf(`        "POST /api/%s/one": p.Query%s,`, generator.ToStrikeCase(statement.TableName.Name), modelName))
 	}
-	return funcLines, nil, strings.Join(routers, "\n")
+	return funcLines, imports, strings.Join(routers, "\n")
+}
+
+func ra(statement *parser.Statement, o *Options) (string, []string, string) {
+	modelName := generator.FirstUpperCamelCase(statement.TableName.Name)
+	if !hasPrimaryKey(statement) {
+		return "", nil, ""
+	}
+
+	funcLines := fmt.Sprintf(`func (p *Router) QueryMany%sAfter(c *gin.Context) {
+    type Cursorable struct {
+        *model.%s
+        Cursor string `+"`form:\"cursor\" json:\"cursor\"`"+`
+        Size int `+"`form:\"size\" json:\"size\"`"+`
+    }
+    request := &t.RequestBean[*Cursorable]{}
+    err := c.ShouldBind(request)
+    if err != nil {
+        siu.ERROR("__LINE__ bad request:", err)
+        c.JSON(200, t.FailWith(400, "bad request"))
+        return
+    }
+    data := request.Data
+    var s *model.%s
+    var cursor string
+    var size int
+    if data != nil {
+        s = data.%s
+        cursor = data.Cursor
+        size = data.Size
+    }
+    if size <= 0 {
+        size = 10
+    }
+    type CursorableResult struct {
+        Next string `+"`json:\"next\"`"+`
+        List []*model.%s `+"`json:\"list\"`"+`
+    }
+    next, list, err := p.Service.QueryMany%sAfter(`+o.ctx()+`s, cursor, size)
+    if err != nil {
+`+argumentCheck+`        siu.ERROR("__LINE__ query %s error:", err)
+        c.JSON(200, t.FailWith(500, "system error"))
+    } else {
+        c.JSON(200, t.SuccessWith(&CursorableResult{Next: next, List: list}))
+    }
+}
+`, modelName, modelName, modelName, modelName, modelName, modelName, modelName)
+	return funcLines, []string{"errors"}, fmt.Sprintf(`        "POST /api/%s/after": p.QueryMany%sAfter,`, generator.ToStrikeCase(statement.TableName.Name), modelName)
 }
 
-func d(statement *parser.Statement) (string, []string, string) {
+func d(statement *parser.Statement, o *Options) (string, []string, string) {
 	modelName := generator.FirstUpperCamelCase(statement.TableName.Name)
 
 	funcLines := fmt.Sprintf(`func (p *Router) Delete%s(c *gin.Context) {
@@ -244,7 +362,7 @@ func d(statement *parser.Statement) (string, []string, string) {
         c.JSON(200, t.FailWith(400, "bad request"))
         return
     }
-    err = p.Service.Delete%s(s)
+    err = p.Service.Delete%s(`+o.ctx()+`s)
     if err != nil {
         siu.ERROR("__LINE__ delete %s error:", err)
         c.JSON(200, t.FailWith(500, "system error"))
@@ -25,7 +25,7 @@ import (
 	"github.com/stella-go/stella/version"
 )
 
-func GeneratePanic(pkg string, statements []*parser.Statement, banner bool) string {
+func GeneratePanic(pkg string, statements []*parser.Statement, o *Options) string {
 	importsMap := make(map[string]common.Void)
 	functions := make([]string, 0)
 	routers := make([]string, 0)
@@ -35,27 +35,37 @@ func GeneratePanic(pkg string, statements []*parser.Statement, banner bool) stri
 	importsMap["github.com/stella-go/siu/t"] = common.Null
 	for _, statement := range statements {
 		functions = append(functions, "// ==================== "+generator.FirstUpperCamelCase(statement.TableName.Name)+" ====================")
-		function, imports, router := c_panic(statement)
+		function, imports, router := c_panic(statement, o)
 		functions = append(functions, function)
 		for _, i := range imports {
 			importsMap[i] = common.Null
 		}
 		routers = append(routers, router)
 
-		function, imports, router = u_panic(statement)
+		function, imports, router = u_panic(statement, o)
 		functions = append(functions, function)
 		for _, i := range imports {
 			importsMap[i] = common.Null
 		}
 		routers = append(routers, router)
 
-		function, imports, router = r_panic(statement)
+		function, imports, router = r_panic(statement, o)
 		functions = append(functions, function)
 		for _, i := range imports {
 			importsMap[i] = common.Null
 		}
 		routers = append(routers, router)
-		function, imports, router = d_panic(statement)
+		if o.Keyset {
+			function, imports, router = ra_panic(statement, o)
+			if router != "" {
+				functions = append(functions, function)
+				for _, i := range imports {
+					importsMap[i] = common.Null
+				}
+				routers = append(routers, router)
+			}
+		}
+		function, imports, router = d_panic(statement, o)
 		functions = append(functions, function)
 		for _, i := range imports {
 			importsMap[i] = common.Null
@@ -72,7 +82,7 @@ func GeneratePanic(pkg string, statements []*parser.Statement, banner bool) stri
 	}
 
 	typeLines := `type Router struct {
-    Service *service.Service ` + "`" + `@siu:""` + "`" + `
+    Service service.Interface ` + "`" + `@siu:""` + "`" + `
 }
 
 func (p *Router) Router() map[string]gin.HandlerFunc {
@@ -81,13 +91,14 @@ func (p *Router) Router() map[string]gin.HandlerFunc {
     }
 }`
 	bannerS := ""
-	if banner {
+	if o.Banner {
 		bannerS = fmt.Sprintf("\n/**\n * Auto Generate by github.com/stella-go/stella %s on %s.\n */\n", version.VERSION, time.Now().Format("2006/01/02"))
 	}
-	return fmt.Sprintf("package %s\n%s\nimport (\n%s\n)\n\n%s\n\n%s", pkg, bannerS, strings.Join(importsLines, "\n"), fmt.Sprintf(typeLines, strings.Join(routers, "\n")), strings.Join(functions, "\n"))
+	functionLines := strings.Join(functions, "\n")
+	return fmt.Sprintf("package %s\n%s\nimport (\n%s\n)\n\n%s\n\n%s", pkg, bannerS, strings.Join(importsLines, "\n"), fmt.Sprintf(typeLines, strings.Join(routers, "\n")), functionLines)
 }
 
-func c_panic(statement *parser.Statement) (string, []string, string) {
+func c_panic(statement *parser.Statement, o *Options) (string, []string, string) {
 	modelName := generator.FirstUpperCamelCase(statement.TableName.Name)
 
 	funcLines := fmt.Sprintf(`func (p *Router) Create%s(c *gin.Context) {
@@ -105,19 +116,35 @@ func c_panic(statement *parser.Statement) (string, []string, string) {
         c.JSON(200, t.FailWith(400, "bad request"))
         return
     }
-    p.Service.Create%s(s)
+    p.Service.Create%s(`+o.ctx()+`s)
     c.JSON(200, t.Success())
 }
 `, modelName, modelName, modelName)
 	return funcLines, nil, fmt.Sprintf(`        "POST /api/%s": p.Create%s,`, generator.ToStrikeCase(statement.TableName.Name), modelName)
 }
 
-func u_panic(statement *parser.Statement) (string, []string, string) {
+func u_panic(statement *parser.Statement, o *Options) (string, []string, string) {
 	modelName := generator.FirstUpperCamelCase(statement.TableName.Name)
+	stale := ""
+	if generator.VersionColumn(statement) != nil {
+		stale = `
+            if err == model.ErrStaleObject {
+                c.JSON(200, t.FailWith(409, "stale object"))
+                return
+            }`
+	}
 
+	update := fmt.Sprintf("p.Service.Update%s("+o.ctx()+"s)", modelName)
+	if o.Mask && hasPrimaryKey(statement) {
+		update = fmt.Sprintf(`if fields := c.Query("fields"); fields != "" {
+        p.Service.Update%sFields(`+o.ctx()+`s, model.ParseColumns[model.%sColumn](fields))
+    } else {
+        p.Service.Update%s(`+o.ctx()+`s)
+    }`, modelName, modelName, modelName)
+	}
 	funcLines := fmt.Sprintf(`func (p *Router) Update%s(c *gin.Context) {
     defer func() {
-        if err := recover(); err != nil {
+        if err := recover(); err != nil {%s
             c.JSON(200, t.FailWith(500, "system error"))
         }
     }()
@@ -130,39 +157,79 @@ func u_panic(statement *parser.Statement) (string, []string, string) {
         c.JSON(200, t.FailWith(400, "bad request"))
         return
     }
-    p.Service.Update%s(s)
+    %s
     c.JSON(200, t.Success())
 }
-`, modelName, modelName, modelName)
+`, modelName, stale, modelName, update)
 	return funcLines, nil, fmt.Sprintf(`        "PUT /api/%s": p.Update%s,`, generator.ToStrikeCase(statement.TableName.Name), modelName)
 }
 
-func r_panic(statement *parser.Statement) (string, []string, string) {
+// argumentCheckPanic answers the ArgumentError a query panics with, a bad order, column, cursor or filter, with 400.
+const argumentCheckPanic = `
+            if argument, ok := err.(*model.ArgumentError); ok {
+                c.JSON(200, t.FailWith(400, argument.Message))
+                return
+            }`
+
+func r_panic(statement *parser.Statement, o *Options) (string, []string, string) {
 	funcLines := ""
 	routers := make([]string, 0)
 	modelName := generator.FirstUpperCamelCase(statement.TableName.Name)
+	sortField, sortVar, sortAssign, ordersArg := "", "", "", ""
+	if o.Sort {
+		sortField = "\n        Sort string `form:\"sort\" json:\"sort\"`"
+		sortVar = "\n    var orders []model.Order"
+		sortAssign = "\n        orders = model.ParseOrders(data.Sort)"
+		ordersArg = "orders, "
+	}
+	columnsField, columnsVar, columnsAssign, columnsArg := "", "", "", ""
+	if o.Projection {
+		columnsField = "\n        Columns string `form:\"columns\" json:\"columns\"`"
+		columnsVar = fmt.Sprintf("\n    columns := model.%sListColumns", modelName)
+		columnsAssign = fmt.Sprintf(`
+        if data.Columns != "" {
+            columns = model.ParseColumns[model.%sColumn](data.Columns)
+        }`, modelName)
+		columnsArg = "columns, "
+	}
+	refused := ""
+	if o.Sort || o.Filter || o.Projection {
+		refused = argumentCheckPanic
+	}
+	filterField := ""
+	query := fmt.Sprintf("count, list := p.Service.QueryMany%s("+o.ctx()+"s, %s%spage, size)", modelName, ordersArg, columnsArg)
+	if o.Filter {
+		filterField = fmt.Sprintf("\n        Filter *model.%sFilter `form:\"filter\" json:\"filter\"`", modelName)
+		query = fmt.Sprintf(`var count int
+    var list []*model.%s
+    if data != nil && data.Filter != nil {
+        count, list = p.Service.QueryMany%sByFilter(`+o.ctx()+`data.Filter, %s%spage, size)
+    } else {
+        count, list = p.Service.QueryMany%s(`+o.ctx()+`s, %s%spage, size)
+    }`, modelName, modelName, ordersArg, columnsArg, modelName, ordersArg, columnsArg)
+	}
 
 	funcLines += fmt.Sprintf(`func (p *Router) QueryMany%s(c *gin.Context) {
     defer func() {
-        if err := recover(); err != nil {
+        if err := recover(); err != nil {%s
             c.JSON(200, t.FailWith(500, "system error"))
         }
     }()
     type Pageable struct {
         *model.%s
         Page int `+"`form:\"page\" json:\"page\"`"+`
-        Size int `+"`form:\"size\" json:\"size\"`"+`
+        Size int `+"`form:\"size\" json:\"size\"`"+`%s%s%s
     }
     request := &t.RequestBean[*Pageable]{}
     err := c.ShouldBind(request)
     t.AssertErrorNil(err)
     data := request.Data
     var s *model.%s
-    var page, size int
+    var page, size int%s%s
     if data != nil {
         s = data.%s
         page = data.Page
-        size = data.Size
+        size = data.Size%s%s
     }
     if page <= 0 {
         page = 1
@@ -174,10 +241,10 @@ func r_panic(statement *parser.Statement) (string, []string, string) {
         Count int `+"`json:\"count\"`"+`
         List []*model.%s `+"`json:\"list\"`"+`
     }
-    count, list := p.Service.QueryMany%s(s, page, size)
+    %s
     c.JSON(200, t.SuccessWith(&PageableResult{Count: count, List: list}))
 }
-`, modelName, modelName, modelName, modelName, modelName, modelName)
+`, modelName, refused, modelName, filterField, sortField, columnsField, modelName, sortVar, columnsVar, modelName, sortAssign, columnsAssign, modelName, query)
 	routers = append(routers, fmt.Sprintf(`        "POST /api/%s/many": p.QueryMany%s,`, generator.ToStrikeCase(statement.TableName.Name), modelName))
 	primaryKeyNames := make([]string, 0)
 	if len(statement.PrimaryKeyPairs) > 0 {
@@ -202,7 +269,7 @@ func r_panic(statement *parser.Statement) (string, []string, string) {
         c.JSON(200, t.FailWith(400, "bad request"))
         return
     }
-    one := p.Service.Query%s(s)
+    one := p.Service.Query%s(`+o.ctx()+`s)
     c.JSON(200, t.SuccessWith(one))
 }
 `, modelName, modelName, modelName)
@@ -211,7 +278,50 @@ func r_panic(statement *parser.Statement) (string, []string, string) {
 	return funcLines, nil, strings.Join(routers, "\n")
 }
 
-func d_panic(statement *parser.Statement) (string, []string, string) {
+func ra_panic(statement *parser.Statement, o *Options) (string, []string, string) {
+	modelName := generator.FirstUpperCamelCase(statement.TableName.Name)
+	if !hasPrimaryKey(statement) {
+		return "", nil, ""
+	}
+
+	funcLines := fmt.Sprintf(`func (p *Router) QueryMany%sAfter(c *gin.Context) {
+    defer func() {
+        if err := recover(); err != nil {`+argumentCheckPanic+`
+            c.JSON(200, t.FailWith(500, "system error"))
+        }
+    }()
+    type Cursorable struct {
+        *model.%s
+        Cursor string `+"`form:\"cursor\" json:\"cursor\"`"+`
+        Size int `+"`form:\"size\" json:\"size\"`"+`
+    }
+    request := &t.RequestBean[*Cursorable]{}
+    err := c.ShouldBind(request)
+    t.AssertErrorNil(err)
+    data := request.Data
+    var s *model.%s
+    var cursor string
+    var size int
+    if data != nil {
+        s = data.%s
+        cursor = data.Cursor
+        size = data.Size
+    }
+    if size <= 0 {
+        size = 10
+    }
+    type CursorableResult struct {
+        Next string `+"`json:\"next\"`"+`
+        List []*model.%s `+"`json:\"list\"`"+`
+    }
+    next, list := p.Service.QueryMany%sAfter(`+o.ctx()+`s, cursor, size)
+    c.JSON(200, t.SuccessWith(&CursorableResult{Next: next, List: list}))
+}
+`, modelName, modelName, modelName, modelName, modelName, modelName)
+	return funcLines, nil, fmt.Sprintf(`        "POST /api/%s/after": p.QueryMany%sAfter,`, generator.ToStrikeCase(statement.TableName.Name), modelName)
+}
+
+func d_panic(statement *parser.Statement, o *Options) (string, []string, string) {
 	modelName := generator.FirstUpperCamelCase(statement.TableName.Name)
 
 	funcLines := fmt.Sprintf(`func (p *Router) Delete%s(c *gin.Context) {
@@ -229,7 +339,7 @@ func d_panic(statement *parser.Statement) (string, []string, string) {
         c.JSON(200, t.FailWith(400, "bad request"))
         return
     }
-    p.Service.Delete%s(s)
+    p.Service.Delete%s(`+o.ctx()+`s)
     c.JSON(200, t.Success())
 }
 `, modelName, modelName, modelName)
@@ -0,0 +1,536 @@
+// Copyright 2010-2024 the original author or authors.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/stella-go/stella/generator"
+	"github.com/stella-go/stella/generator/parser"
+	"github.com/stella-go/stella/version"
+)
+
+const cacheLines = `// Cache stores the cached rows of the lookups, a missing key is not an error and a value set with a TTL
+// which is not positive does not expire.
+type Cache interface {
+    Get(ctx context.Context, key string) ([]byte, bool, error)
+    Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+    Delete(ctx context.Context, keys ...string) error
+}
+
+// CacheTable is the key prefix and the time to live of the cached rows of a table, a table whose TTL is
+// not positive is not cached.
+type CacheTable struct {
+    Prefix string
+    TTL    time.Duration
+}
+
+// Cached is a cache aside decorator of the service, the lookups by nothing but a primary or unique key go
+// to the Cache first, the updates and deletes evict the rows they touch and the other methods pass through.
+// The keys hold a generation of their table, which WithTx renews for every table once it commits since
+// the rows its writes touch are not seen.
+type Cached struct {
+    Interface
+    Cache  Cache
+    Tables map[string]CacheTable
+    group  callGroup
+}
+
+var _ Interface = (*Cached)(nil)
+
+// NewCached decorates next with cache, the tables start as CacheTables.
+func NewCached(next Interface, cache Cache) *Cached {
+    tables := make(map[string]CacheTable, len(CacheTables))
+    for k, v := range CacheTables {
+        tables[k] = v
+    }
+    return &Cached{Interface: next, Cache: cache, Tables: tables}
+}
+
+func (p *Cached) key(table string, generation string, name string, values ...interface{}) string {
+    data, _ := json.Marshal(values)
+    return p.Tables[table].Prefix + generation + ":" + name + ":" + string(data)
+}
+
+// generation returns the generation of the cached rows of table, ok is false when the cache fails.
+func (p *Cached) generation(ctx context.Context, table string) (string, bool) {
+    data, ok, err := p.Cache.Get(ctx, p.Tables[table].Prefix+"generation")
+    if err != nil {
+        return "", false
+    }
+    if !ok {
+        return "0", true
+    }
+    return string(data), true
+}
+
+// renew starts a new generation of every cached table, the rows cached before are not read again.
+func (p *Cached) renew(ctx context.Context) {
+    generation := []byte(strconv.FormatInt(time.Now().UnixNano(), 10))
+    for _, table := range p.Tables {
+        if table.TTL > 0 {
+            p.Cache.Set(ctx, table.Prefix+"generation", generation, 0)
+        }
+    }
+}
+
+// lookup reads the cached row of key into ret, or loads it once for all the concurrent callers and caches it.
+// A failing cache falls back to load, found is false when the row does not exist.
+func (p *Cached) lookup(ctx context.Context, table string, key string, ret interface{}, load func() ([]byte, error)) (bool, error) {
+    if data, ok, err := p.Cache.Get(ctx, key); err == nil && ok && json.Unmarshal(data, ret) == nil {
+        return true, nil
+    }
+    data, err := p.group.do(key, func() ([]byte, error) {
+        data, err := load()
+        if err == nil && data != nil {
+            p.Cache.Set(ctx, key, data, p.Tables[table].TTL)
+        }
+        return data, err
+    })
+    if err != nil || data == nil {
+        return false, err
+    }
+    return true, json.Unmarshal(data, ret)
+}
+
+func (p *Cached) evict(ctx context.Context, keys []string) {
+    if len(keys) > 0 {
+        p.Cache.Delete(ctx, keys...)
+    }
+}
+
+type call struct {
+    wg    sync.WaitGroup
+    data  []byte
+    err   error
+    panic interface{}
+}
+
+// callGroup runs the loads of a key once at a time and hands the result to every caller waiting for it.
+type callGroup struct {
+    mu    sync.Mutex
+    calls map[string]*call
+}
+
+func (g *callGroup) do(key string, fn func() ([]byte, error)) ([]byte, error) {
+    g.mu.Lock()
+    if g.calls == nil {
+        g.calls = make(map[string]*call)
+    }
+    if c, ok := g.calls[key]; ok {
+        g.mu.Unlock()
+        c.wg.Wait()
+        if c.panic != nil {
+            panic(c.panic)
+        }
+        return c.data, c.err
+    }
+    c := &call{}
+    c.wg.Add(1)
+    g.calls[key] = c
+    g.mu.Unlock()
+
+    defer func() {
+        c.panic = recover()
+        g.mu.Lock()
+        delete(g.calls, key)
+        g.mu.Unlock()
+        c.wg.Done()
+        if c.panic != nil {
+            panic(c.panic)
+        }
+    }()
+    c.data, c.err = fn()
+    return c.data, c.err
+}
+
+type memoryEntry struct {
+    value   []byte
+    expires time.Time
+}
+
+// MemoryCache is a Cache in the memory of the process, for the tests and a single instance.
+type MemoryCache struct {
+    mu      sync.Mutex
+    entries map[string]memoryEntry
+}
+
+func NewMemoryCache() *MemoryCache {
+    return &MemoryCache{entries: make(map[string]memoryEntry)}
+}
+
+func (c *MemoryCache) Get(ctx context.Context, key string) ([]byte, bool, error) {
+    c.mu.Lock()
+    defer c.mu.Unlock()
+    e, ok := c.entries[key]
+    if !ok {
+        return nil, false, nil
+    }
+    if !e.expires.IsZero() && time.Now().After(e.expires) {
+        delete(c.entries, key)
+        return nil, false, nil
+    }
+    return e.value, true, nil
+}
+
+func (c *MemoryCache) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+    c.mu.Lock()
+    defer c.mu.Unlock()
+    expires := time.Time{}
+    if ttl > 0 {
+        expires = time.Now().Add(ttl)
+    }
+    c.entries[key] = memoryEntry{value: value, expires: expires}
+    return nil
+}
+
+func (c *MemoryCache) Delete(ctx context.Context, keys ...string) error {
+    c.mu.Lock()
+    defer c.mu.Unlock()
+    for _, key := range keys {
+        delete(c.entries, key)
+    }
+    return nil
+}`
+
+const redisCacheLines = `// RedisCache is a Cache on redis.
+type RedisCache struct {
+    Client redis.Cmdable
+}
+
+func NewRedisCache(client redis.Cmdable) *RedisCache {
+    return &RedisCache{Client: client}
+}
+
+func (c *RedisCache) Get(ctx context.Context, key string) ([]byte, bool, error) {
+    data, err := c.Client.Get(ctx, key).Bytes()
+    if err != nil {
+        if err == redis.Nil {
+            return nil, false, nil
+        }
+        return nil, false, err
+    }
+    return data, true, nil
+}
+
+func (c *RedisCache) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+    return c.Client.Set(ctx, key, value, ttl).Err()
+}
+
+func (c *RedisCache) Delete(ctx context.Context, keys ...string) error {
+    if len(keys) == 0 {
+        return nil
+    }
+    return c.Client.Del(ctx, keys...).Err()
+}`
+
+// GenerateCache generates the Cached decorator of the service of the options, it caches the lookups
+// of the service by the primary and unique keys.
+func GenerateCache(pkg string, statements []*parser.Statement, o *Options) string {
+	methods := make(map[string]*method)
+	for _, m := range serviceMethods(statements, o) {
+		methods[m.Name] = m
+	}
+	tables := make([]string, 0)
+	functions := make([]string, 0)
+	for _, statement := range statements {
+		modelName := generator.FirstUpperCamelCase(statement.TableName.Name)
+		lookups := make([]*method, 0)
+		keys := make([][]*parser.ColumnDefinition, 0)
+		if m, ok := methods["Query"+modelName]; ok && len(getKeyColumns(statement)) > 0 {
+			lookups = append(lookups, m)
+			keys = append(keys, getKeyColumns(statement))
+		}
+		for _, pair := range generator.UniqKeyPairs(statement) {
+			if m, ok := methods["Query"+modelName+"By"+keysName(pair)]; ok {
+				lookups = append(lookups, m)
+				keys = append(keys, pair)
+			}
+		}
+		if len(lookups) == 0 || !cacheable(statement) {
+			continue
+		}
+		tables = append(tables, fmt.Sprintf(`    "%s": {Prefix: "%s:", TTL: 5 * time.Minute},`, statement.TableName.Name, statement.TableName.Name))
+		functions = append(functions, "// ==================== "+modelName+" ====================")
+		for i, m := range lookups {
+			functions = append(functions, cachedLookup(statement, m, keys[i]))
+		}
+		functions = append(functions, cachedKeys(statement, methods["Query"+modelName], keys))
+		for _, name := range []string{"Update" + modelName, "Update" + modelName + "Fields", "Delete" + modelName} {
+			if m, ok := methods[name]; ok {
+				functions = append(functions, cachedWrite(statement, m))
+			}
+		}
+	}
+
+	if m := o.withTx(); m != nil && len(tables) > 0 {
+		functions = append([]string{cachedWithTx(m)}, functions...)
+	}
+
+	bannerS := ""
+	if o.Banner {
+		bannerS = fmt.Sprintf("\n/**\n * Auto Generate by github.com/stella-go/stella %s on %s.\n */\n", version.VERSION, time.Now().Format("2006/01/02"))
+
+	}
+	typeLines := fmt.Sprintf(`%s
+
+// CacheTables are the tables cached by default.
+var CacheTables = map[string]CacheTable{
+%s
+}`, cacheLines, strings.Join(tables, "\n"))
+	importsLines := []string{"\t\"context\"", "\t\"encoding/json\"", "\t\"strconv\"", "\t\"sync\"", "\t\"time\""}
+	if o.Gorm && !o.Curd && o.Tx && len(tables) > 0 {
+		importsLines = append(importsLines, "", "\t\"gorm.io/gorm\"")
+	}
+	return fmt.Sprintf("package %s\n%s\nimport (\n%s\n)\n\n%s\n\n%s", pkg, bannerS, strings.Join(importsLines, "\n"), typeLines, strings.Join(functions, "\n"))
+}
+
+// GenerateRedisCache generates the Cache on go-redis, the client of siu.
+func GenerateRedisCache(pkg string, banner bool) string {
+	bannerS := ""
+	if banner {
+		bannerS = fmt.Sprintf("\n/**\n * Auto Generate by github.com/stella-go/stella %s on %s.\n */\n", version.VERSION, time.Now().Format("2006/01/02"))
+
+	}
+	importsLines := []string{"\t\"context\"", "\t\"time\"", "", "\t\"github.com/go-redis/redis/v8\""}
+	return fmt.Sprintf("package %s\n%s\nimport (\n%s\n)\n\n%s\n", pkg, bannerS, strings.Join(importsLines, "\n"), redisCacheLines)
+}
+
+// cacheTypes are the column types of the typed fields of the models, the other columns are interface{} fields.
+var cacheTypes = map[string]bool{
+	"TINYINT":   true,
+	"INT":       true,
+	"BIGINT":    true,
+	"FLOAT":     true,
+	"CHAR":      true,
+	"VARCHAR":   true,
+	"TEXT":      true,
+	"DATE":      true,
+	"DATETIME":  true,
+	"TIMESTAMP": true,
+}
+
+// cacheable reports whether the rows of a table can be cached, json does not bring back what an interface{}
+// field held, the []byte of a DECIMAL comes back as a base64 string, so such tables are not cached.
+func cacheable(statement *parser.Statement) bool {
+	for _, col := range statement.Columns {
+		if !cacheTypes[col.Type] {
+			return false
+		}
+	}
+	return true
+}
+
+// callArgs returns the context line the cache needs and the arguments the method passes on.
+func callArgs(m *method) (string, string) {
+	names := m.args()
+	ctxLine := ""
+	if !strings.HasPrefix(m.Params, "ctx context.Context") {
+		ctxLine = "    ctx := context.Background()\n"
+	}
+	return ctxLine, strings.Join(names, ", ")
+}
+
+// keyArgs returns the conditions of a nil key field and of all the key fields set, and the key fields.
+func keyArgs(keys []*parser.ColumnDefinition, v string) (string, string, string) {
+	nils := make([]string, 0)
+	sets := make([]string, 0)
+	fields := make([]string, 0)
+	for _, col := range keys {
+		field := v + "." + generator.FirstUpperCamelCase(col.ColumnName.Name)
+		nils = append(nils, field+" == nil")
+		sets = append(sets, field+" != nil")
+		fields = append(fields, field)
+	}
+	return strings.Join(nils, " || "), strings.Join(sets, " && "), strings.Join(fields, ", ")
+}
+
+// otherArgs returns the condition of a field set outside of keys.
+func otherArgs(statement *parser.Statement, keys []*parser.ColumnDefinition, v string) string {
+	key := make(map[*parser.ColumnDefinition]bool)
+	for _, col := range keys {
+		key[col] = true
+	}
+	sets := make([]string, 0)
+	for _, col := range statement.Columns {
+		if !key[col] {
+			sets = append(sets, v+"."+generator.FirstUpperCamelCase(col.ColumnName.Name)+" != nil")
+		}
+	}
+	return strings.Join(sets, " || ")
+}
+
+// cachedLookup caches m when s holds nothing but the key, the services match every field set.
+func cachedLookup(statement *parser.Statement, m *method, keys []*parser.ColumnDefinition) string {
+	modelName := generator.FirstUpperCamelCase(statement.TableName.Name)
+	tableName := statement.TableName.Name
+	ctxLine, args := callArgs(m)
+	nilKey, _, fields := keyArgs(keys, "s")
+	if others := otherArgs(statement, keys, "s"); others != "" {
+		nilKey += " || " + others
+	}
+	if strings.Contains(m.Results, "error") {
+		return fmt.Sprintf(`func (p *Cached) %s(%s) %s {
+    if s == nil || %s || p.Tables["%s"].TTL <= 0 {
+        return p.Interface.%s(%s)
+    }
+%s    generation, ok := p.generation(ctx, "%s")
+    if !ok {
+        return p.Interface.%s(%s)
+    }
+    ret := &model.%s{}
+    found, err := p.lookup(ctx, "%s", p.key("%s", generation, "%s", %s), ret, func() ([]byte, error) {
+        one, err := p.Interface.%s(%s)
+        if err != nil || one == nil {
+            return nil, err
+        }
+        return json.Marshal(one)
+    })
+    if err != nil || !found {
+        return nil, err
+    }
+    return ret, nil
+}
+`, m.Name, m.Params, m.Results, nilKey, tableName, m.Name, args, ctxLine, tableName, m.Name, args, modelName, tableName, tableName, keysName(keys), fields, m.Name, args)
+	}
+	return fmt.Sprintf(`func (p *Cached) %s(%s) %s {
+    if s == nil || %s || p.Tables["%s"].TTL <= 0 {
+        return p.Interface.%s(%s)
+    }
+%s    generation, ok := p.generation(ctx, "%s")
+    if !ok {
+        return p.Interface.%s(%s)
+    }
+    ret := &model.%s{}
+    found, err := p.lookup(ctx, "%s", p.key("%s", generation, "%s", %s), ret, func() ([]byte, error) {
+        one := p.Interface.%s(%s)
+        if one == nil {
+            return nil, nil
+        }
+        return json.Marshal(one)
+    })
+    if err != nil {
+        panic(err)
+    }
+    if !found {
+        return nil
+    }
+    return ret
+}
+`, m.Name, m.Params, m.Results, nilKey, tableName, m.Name, args, ctxLine, tableName, m.Name, args, modelName, tableName, tableName, keysName(keys), fields, m.Name, args)
+}
+
+// cachedKeys returns the keys of the cached rows a write of s touches, those of s and, when the table is
+// cached by more than one key, those of the row before the write.
+func cachedKeys(statement *parser.Statement, query *method, keys [][]*parser.ColumnDefinition) string {
+	modelName := generator.FirstUpperCamelCase(statement.TableName.Name)
+	tableName := statement.TableName.Name
+	names := make(map[string]bool)
+	lines := make([]string, 0)
+	for _, pair := range keys {
+		name := keysName(pair)
+		if names[name] {
+			continue
+		}
+		names[name] = true
+		_, setKey, fields := keyArgs(pair, "r")
+		lines = append(lines, fmt.Sprintf(`        if %s {
+            keys = append(keys, p.key("%s", generation, "%s", %s))
+        }`, setKey, tableName, name, fields))
+	}
+	old := ""
+	if query != nil && len(names) > 1 {
+		args := query.args()
+		for i, arg := range args {
+			if arg == "s" {
+				args[i] = "key"
+			}
+		}
+		_, setKey, _ := keyArgs(getKeyColumns(statement), "s")
+		assigns := make([]string, 0)
+		for _, col := range getKeyColumns(statement) {
+			field := generator.FirstUpperCamelCase(col.ColumnName.Name)
+			assigns = append(assigns, field+": s."+field)
+		}
+		if strings.Contains(query.Results, "error") {
+			old = fmt.Sprintf(`
+    if %s {
+        key := &model.%s{%s}
+        if one, err := p.Interface.%s(%s); err == nil && one != nil {
+            rows = append(rows, one)
+        }
+    }`, setKey, modelName, strings.Join(assigns, ", "), query.Name, strings.Join(args, ", "))
+		} else {
+			old = fmt.Sprintf(`
+    if %s {
+        key := &model.%s{%s}
+        if one := p.Interface.%s(%s); one != nil {
+            rows = append(rows, one)
+        }
+    }`, setKey, modelName, strings.Join(assigns, ", "), query.Name, strings.Join(args, ", "))
+		}
+	}
+	return fmt.Sprintf(`func (p *Cached) keys%s(ctx context.Context, s *model.%s) []string {
+    if s == nil {
+        return nil
+    }
+    generation, ok := p.generation(ctx, "%s")
+    if !ok {
+        return nil
+    }
+    rows := []*model.%s{s}%s
+    keys := make([]string, 0)
+    for _, r := range rows {
+%s
+    }
+    return keys
+}
+`, modelName, modelName, tableName, modelName, old, strings.Join(lines, "\n"))
+}
+
+func cachedWrite(statement *parser.Statement, m *method) string {
+	modelName := generator.FirstUpperCamelCase(statement.TableName.Name)
+	ctxLine, args := callArgs(m)
+	ret := ""
+	if m.Results != "" {
+		ret = "return "
+	}
+	return fmt.Sprintf(`func (p *Cached) %s(%s) %s {
+%s    defer p.evict(ctx, p.keys%s(ctx, s))
+    %sp.Interface.%s(%s)
+}
+`, m.Name, m.Params, m.Results, ctxLine, modelName, ret, m.Name, args)
+}
+
+// cachedWithTx renews the generations once the transaction m runs commits.
+func cachedWithTx(m *method) string {
+	if m.Results == "" {
+		return fmt.Sprintf(`func (p *Cached) %s(%s) {
+    p.Interface.%s(%s)
+    p.renew(ctx)
+}
+`, m.Name, m.Params, m.Name, strings.Join(m.args(), ", "))
+	}
+	return fmt.Sprintf(`func (p *Cached) %s(%s) %s {
+    err := p.Interface.%s(%s)
+    if err == nil {
+        p.renew(ctx)
+    }
+    return err
+}
+`, m.Name, m.Params, m.Results, m.Name, strings.Join(m.args(), ", "))
+}
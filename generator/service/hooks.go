@@ -0,0 +1,166 @@
+// Copyright 2010-2024 the original author or authors.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/stella-go/stella/generator"
+	"github.com/stella-go/stella/generator/parser"
+)
+
+// write is a write method of the service and the operation of its hooks.
+type write struct {
+	Name string
+	Op   string
+}
+
+func writes(statement *parser.Statement) []*write {
+	modelName := generator.FirstUpperCamelCase(statement.TableName.Name)
+	return []*write{
+		{Name: "Create" + modelName, Op: "Create"},
+		{Name: "Update" + modelName, Op: "Update"},
+		{Name: "Update" + modelName + "Fields", Op: "Update"},
+		{Name: "Delete" + modelName, Op: "Delete"},
+	}
+}
+
+// withHooks moves the body of every write method of the service to an unexported method and calls it
+// between the Before and After hooks the Service implements, and declares the interfaces of those hooks.
+func withHooks(statements []*parser.Statement, methods [][]*method) ([][]*method, string) {
+	hooks := make([]string, 0)
+	hooked := make([][]*method, 0)
+	for i, statement := range statements {
+		modelName := generator.FirstUpperCamelCase(statement.TableName.Name)
+		ops := make(map[string]string)
+		for _, w := range writes(statement) {
+			ops[w.Name] = w.Op
+		}
+		declared := make(map[string]bool)
+		list := make([]*method, 0)
+		for _, m := range methods[i] {
+			op, ok := ops[m.Name]
+			if !ok {
+				list = append(list, m)
+				continue
+			}
+			inner := &method{Name: strings.ToLower(m.Name[:1]) + m.Name[1:], Params: m.Params, Results: m.Results, Body: m.Body}
+			call := fmt.Sprintf("err := p.%s(%s)", inner.Name, strings.Join(m.args(), ", "))
+			if m.Results == "" {
+				call = fmt.Sprintf("p.%s(%s)", inner.Name, strings.Join(m.args(), ", "))
+			}
+			wrapper := &method{Name: m.Name, Params: m.Params, Results: m.Results, Body: hookedBody(modelName, op, m, "p", call)}
+			list = append(list, wrapper, inner)
+			if !declared[op] {
+				declared[op] = true
+				hooks = append(hooks, hookInterfaces(modelName, op, m)...)
+			}
+		}
+		hooked = append(hooked, list)
+	}
+	return hooked, strings.Join(hooks, "\n\n")
+}
+
+// hookParams returns the parameters of the hooks of the write method m, its context and model.
+func hookParams(m *method) (string, string) {
+	params := splitList(m.Params)
+	names := m.args()
+	if strings.HasPrefix(m.Params, "ctx context.Context") {
+		return strings.Join(params[:2], ", "), strings.Join(names[:2], ", ")
+	}
+	return params[0], names[0]
+}
+
+func hookInterfaces(modelName string, op string, m *method) []string {
+	params, _ := hookParams(m)
+	result := " error"
+	if m.Results == "" {
+		result = ""
+	}
+	lines := make([]string, 0)
+	for _, when := range []string{"Before", "After"} {
+		lines = append(lines, fmt.Sprintf(`// %s%s%sHook is implemented by the Service in a hand written file of the package to run %s the %s of %s.
+type %s%s%sHook interface {
+    %s%s%s(%s)%s
+}`, when, op, modelName, strings.ToLower(when), strings.ToLower(op), modelName, when, op, modelName, when, op, modelName, params, result))
+	}
+	return lines
+}
+
+// hookedBody calls the hooks of the write method m of the service receiver around the statement call,
+// which sets err in the error style.
+func hookedBody(modelName string, op string, m *method, receiver string, call string) string {
+	_, args := hookParams(m)
+	if m.Results == "" {
+		return fmt.Sprintf(`    if h, ok := interface{}(%s).(Before%s%sHook); ok {
+        h.Before%s%s(%s)
+    }
+    %s
+    if h, ok := interface{}(%s).(After%s%sHook); ok {
+        h.After%s%s(%s)
+    }
+`, receiver, op, modelName, op, modelName, args, call, receiver, op, modelName, op, modelName, args)
+	}
+	return fmt.Sprintf(`    if h, ok := interface{}(%s).(Before%s%sHook); ok {
+        if err := h.Before%s%s(%s); err != nil {
+            return err
+        }
+    }
+    if %s; err != nil {
+        return err
+    }
+    if h, ok := interface{}(%s).(After%s%sHook); ok {
+        return h.After%s%s(%s)
+    }
+    return nil
+`, receiver, op, modelName, op, modelName, args, call, receiver, op, modelName, op, modelName, args)
+}
+
+// txLines binds the service to the transactions of WithTx, the writes of the bound service run the hooks
+// and call the functions generated by -curd on the transaction.
+func txLines() string {
+	return `// NewTx returns the service p running in the transaction tx of WithTx.
+func NewTx(p *Service, tx model.DataSource) *Service {
+    return &Service{DB: p.DB, tx: tx}
+}
+
+// db is the transaction the service is bound to by NewTx, or its DB.
+func (p *Service) db() model.DataSource {
+    if p.tx != nil {
+        return p.tx
+    }
+    return p.DB
+}
+`
+}
+
+// bindTx runs the methods of the service on the transaction of NewTx.
+func bindTx(methods [][]*method) {
+	for _, list := range methods {
+		for _, m := range list {
+			m.Body = strings.ReplaceAll(m.Body, "p.DB", "p.db()")
+		}
+	}
+}
+
+// gormTxLines binds the service to the transactions of WithTx, the writes of the bound service run the hooks.
+func gormTxLines() string {
+	return `// NewTx returns the service p running in the transaction tx of WithTx.
+func NewTx(p *Service, tx *gorm.DB) *Service {
+    return &Service{DB: tx}
+}
+`
+}
@@ -0,0 +1,122 @@
+// Copyright 2010-2024 the original author or authors.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/stella-go/stella/generator/parser"
+	"github.com/stella-go/stella/version"
+)
+
+// GenerateMock generates a mock of the Interface of the service of the options, it records the calls
+// and returns what the <Method>Func fields return or else the zero values.
+func GenerateMock(pkg string, statements []*parser.Statement, o *Options) string {
+	methods := serviceMethods(statements, o)
+	signatures := ""
+	for _, m := range methods {
+		signatures += m.Params + m.Results
+	}
+	imports := []string{"\t\"sync\""}
+	if strings.Contains(signatures, "context.Context") {
+		imports = append(imports, "\t\"context\"")
+	}
+	if strings.Contains(signatures, "*gorm.DB") {
+		imports = append(imports, "\t\"gorm.io/gorm\"")
+	}
+	fields := make([]string, 0)
+	functions := make([]string, 0)
+	for _, m := range methods {
+		names := m.args()
+		results := splitList(m.Results)
+		named := make([]string, 0)
+		for i, r := range results {
+			named = append(named, fmt.Sprintf("r%d %s", i, r))
+		}
+		signature := fmt.Sprintf("%s(%s)", m.Name, m.Params)
+		if m.Results != "" {
+			signature += " (" + strings.Join(named, ", ") + ")"
+		}
+		fields = append(fields, "    "+strings.TrimSpace(fmt.Sprintf("%sFunc func(%s) %s", m.Name, m.Params, m.Results)))
+		call := fmt.Sprintf("m.%sFunc(%s)", m.Name, strings.Join(names, ", "))
+		ret := ""
+		if m.Results != "" {
+			call = "return " + call
+			ret = "\n    return"
+		}
+		recorded := ""
+		if len(names) > 0 {
+			recorded = ", " + strings.Join(names, ", ")
+		}
+		functions = append(functions, fmt.Sprintf(`func (m *Service) %s {
+    m.record("%s"%s)
+    if m.%sFunc != nil {
+        %s
+    }%s
+}
+`, signature, m.Name, recorded, m.Name, call, ret))
+	}
+
+	bannerS := ""
+	if o.Banner {
+		bannerS = fmt.Sprintf("\n/**\n * Auto Generate by github.com/stella-go/stella %s on %s.\n */\n", version.VERSION, time.Now().Format("2006/01/02"))
+
+	}
+	typeLines := fmt.Sprintf(`// Call is a recorded call of a Service method.
+type Call struct {
+    Method string
+    Args   []interface{}
+}
+
+// Service is a mock of service.Interface, every call is recorded and answered by the <Method>Func field
+// of the method, a method whose field is nil returns the zero values.
+type Service struct {
+    mu    sync.Mutex
+    calls []Call
+
+%s
+}
+
+var _ service.Interface = (*Service)(nil)
+
+func (m *Service) record(method string, args ...interface{}) {
+    m.mu.Lock()
+    defer m.mu.Unlock()
+    m.calls = append(m.calls, Call{Method: method, Args: args})
+}
+
+// Calls returns the recorded calls of the method, or every recorded call when method is empty.
+func (m *Service) Calls(method string) []Call {
+    m.mu.Lock()
+    defer m.mu.Unlock()
+    calls := make([]Call, 0)
+    for _, c := range m.calls {
+        if method == "" || c.Method == method {
+            calls = append(calls, c)
+        }
+    }
+    return calls
+}
+
+// Reset forgets the recorded calls.
+func (m *Service) Reset() {
+    m.mu.Lock()
+    defer m.mu.Unlock()
+    m.calls = nil
+}`, strings.Join(fields, "\n"))
+	return fmt.Sprintf("package %s\n%s\nimport (\n%s\n)\n\n%s\n\n%s", pkg, bannerS, strings.Join(imports, "\n"), typeLines, strings.Join(functions, "\n"))
+}
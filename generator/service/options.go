@@ -0,0 +1,214 @@
+// Copyright 2010-2024 the original author or authors.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/stella-go/stella/generator"
+	"github.com/stella-go/stella/generator/parser"
+)
+
+// Options are the flags of the command the generated service depends on,
+// Gorm, Curd and Panic pick the flavor GenerateMock and GenerateCache decorate.
+type Options struct {
+	Banner     bool
+	Logic      string
+	Ctx        bool
+	Tx         bool
+	Keyset     bool
+	Filter     bool
+	Sort       bool
+	Projection bool
+	Mask       bool
+	Hooks      bool
+	Gorm       bool
+	Curd       bool
+	Panic      bool
+}
+
+// ctx is the context.Context parameter the methods take first with -ctx.
+func (o *Options) ctx() string {
+	if o.Ctx {
+		return "ctx context.Context, "
+	}
+	return ""
+}
+
+// ctxArg passes ctx on to the functions generated by -curd.
+func (o *Options) ctxArg() string {
+	if o.Ctx {
+		return "ctx, "
+	}
+	return ""
+}
+
+// gormDB is the *gorm.DB of the statements, bound to ctx with -ctx.
+func (o *Options) gormDB() string {
+	if o.Ctx {
+		return "p.DB.WithContext(ctx)"
+	}
+	return "p.DB"
+}
+
+// method is a generated service method.
+type method struct {
+	Name    string
+	Params  string
+	Results string
+	Body    string
+}
+
+func (m *method) lines(receiver string) string {
+	results := ""
+	if m.Results != "" {
+		results = " " + m.Results
+	}
+	return fmt.Sprintf("func (%s) %s(%s)%s {\n%s}\n", receiver, m.Name, m.Params, results, m.Body)
+}
+
+// args are the names of the parameters of m.
+func (m *method) args() []string {
+	names := make([]string, 0)
+	for _, p := range splitList(m.Params) {
+		names = append(names, strings.Fields(p)[0])
+	}
+	return names
+}
+
+func (m *method) exported() bool {
+	return unicode.IsUpper(rune(m.Name[0]))
+}
+
+// withTx is the WithTx method of the Service with -tx, nil without.
+func (o *Options) withTx() *method {
+	if !o.Tx {
+		return nil
+	}
+	switch {
+	case o.Gorm && !o.Curd:
+		return &method{
+			Name:    "WithTx",
+			Params:  "ctx context.Context, fn func(tx *gorm.DB) error",
+			Results: "error",
+			Body:    "    return p.DB.WithContext(ctx).Transaction(fn)\n",
+		}
+	case o.Panic:
+		return &method{
+			Name:   "WithTx",
+			Params: "ctx context.Context, fn func(tx model.DataSource)",
+			Body:   "    model.WithTx(ctx, p.DB, nil, fn)\n",
+		}
+	default:
+		return &method{
+			Name:    "WithTx",
+			Params:  "ctx context.Context, fn func(tx model.DataSource) error",
+			Results: "error",
+			Body:    "    return model.WithTx(ctx, p.DB, nil, fn)\n",
+		}
+	}
+}
+
+// serviceMethods returns the methods of the Interface of the service flavor of the options.
+func serviceMethods(statements []*parser.Statement, o *Options) []*method {
+	var methods [][]*method
+	switch {
+	case o.Curd && o.Panic:
+		methods, _ = curdPanicMethods(statements, o)
+	case o.Curd:
+		methods, _ = curdMethods(statements, o)
+	case o.Gorm:
+		methods, _ = gormMethods(statements, o)
+	case o.Panic:
+		methods, _ = panicMethods(statements, o)
+	default:
+		methods, _ = siuMethods(statements, o)
+	}
+	return exportedMethods(o, methods)
+}
+
+// exportedMethods are the methods of the Interface of the service, WithTx first.
+func exportedMethods(o *Options, methods [][]*method) []*method {
+	exported := make([]*method, 0)
+	if m := o.withTx(); m != nil {
+		exported = append(exported, m)
+	}
+	for _, list := range methods {
+		for _, m := range list {
+			if m.exported() {
+				exported = append(exported, m)
+			}
+		}
+	}
+	return exported
+}
+
+// methodLines declares the methods of every table on the Service.
+func methodLines(statements []*parser.Statement, methods [][]*method) string {
+	functions := make([]string, 0)
+	for i, statement := range statements {
+		functions = append(functions, "// ==================== "+generator.FirstUpperCamelCase(statement.TableName.Name)+" ====================")
+		for _, m := range methods[i] {
+			functions = append(functions, m.lines("p *Service"))
+		}
+	}
+	return strings.Join(functions, "\n")
+}
+
+// splitList splits a parameter or result list on the commas outside of parentheses.
+func splitList(list string) []string {
+	list = strings.TrimSpace(list)
+	if strings.HasPrefix(list, "(") && strings.HasSuffix(list, ")") {
+		list = list[1 : len(list)-1]
+	}
+	items := make([]string, 0)
+	depth, start := 0, 0
+	for i, r := range list {
+		switch r {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case ',':
+			if depth == 0 {
+				items = append(items, strings.TrimSpace(list[start:i]))
+				start = i + 1
+			}
+		}
+	}
+	if last := strings.TrimSpace(list[start:]); last != "" {
+		items = append(items, last)
+	}
+	return items
+}
+
+// interfaceLines declares the Interface of the methods of the generated service, the router calls the service through it.
+func interfaceLines(methods []*method) string {
+	lines := make([]string, 0)
+	for _, m := range methods {
+		results := ""
+		if m.Results != "" {
+			results = " " + m.Results
+		}
+		lines = append(lines, fmt.Sprintf("    %s(%s)%s", m.Name, m.Params, results))
+	}
+	return fmt.Sprintf(`type Interface interface {
+%s
+}
+
+var _ Interface = (*Service)(nil)`, strings.Join(lines, "\n"))
+}
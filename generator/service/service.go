@@ -25,90 +25,211 @@ import (
 	"github.com/stella-go/stella/version"
 )
 
-func Generate(pkg string, statements []*parser.Statement, banner bool) string {
-	importsMap := make(map[string]common.Void)
+func Generate(pkg string, statements []*parser.Statement, o *Options) string {
+	methods, importsMap := siuMethods(statements, o)
 	importsMap["database/sql"] = common.Null
 	importsMap["github.com/stella-go/siu/fn/data"] = common.Null
-	functions := make([]string, 0)
+	if o.Ctx || o.Tx {
+		importsMap["context"] = common.Null
+	}
+	importsLines := make([]string, 0)
+	for i := range importsMap {
+		if i == "" {
+			continue
+		}
+		importsLines = append(importsLines, "\t\""+i+"\"")
+	}
+
+	typeLines := `type Service struct {
+    DB *sql.DB ` + "`" + `@siu:""` + "`" + `
+}`
+	if m := o.withTx(); m != nil {
+		typeLines += "\n\n" + strings.TrimSuffix(m.lines("p *Service"), "\n")
+	}
+	bannerS := ""
+	if o.Banner {
+		bannerS = fmt.Sprintf("\n/**\n * Auto Generate by github.com/stella-go/stella %s on %s.\n */\n", version.VERSION, time.Now().Format("2006/01/02"))
+
+	}
+	hookLines := ""
+	if o.Hooks {
+		methods, hookLines = withHooks(statements, methods)
+	}
+	functionLines := methodLines(statements, methods)
+	typeLines = interfaceLines(exportedMethods(o, methods)) + "\n\n" + typeLines
+	if hookLines != "" {
+		typeLines += "\n\n" + hookLines
+	}
+	return fmt.Sprintf("package %s\n%s\nimport (\n%s\n)\n\n%s\n\n%s", pkg, bannerS, strings.Join(importsLines, "\n"), typeLines, functionLines)
+}
+
+// siuMethods are the methods of every table on the reflection of siu.
+func siuMethods(statements []*parser.Statement, o *Options) ([][]*method, map[string]common.Void) {
+	importsMap := make(map[string]common.Void)
+	methods := make([][]*method, 0)
 
 	for _, statement := range statements {
-		functions = append(functions, "// ==================== "+generator.FirstUpperCamelCase(statement.TableName.Name)+" ====================")
-		function, imports := c(statement)
-		functions = append(functions, function)
+		functions := make([]*method, 0)
+		policy := generator.ParseLogic(statement, o.Logic)
+		function, imports := c(statement, o)
+		functions = append(functions, function...)
 		for _, i := range imports {
 			importsMap[i] = common.Null
 		}
 
-		function, imports = u(statement)
-		functions = append(functions, function)
+		function, imports = u(statement, o)
+		functions = append(functions, function...)
 		for _, i := range imports {
 			importsMap[i] = common.Null
 		}
+		if o.Mask {
+			function, imports = uf(statement, o)
+			functions = append(functions, function...)
+			for _, i := range imports {
+				importsMap[i] = common.Null
+			}
+		}
 
-		function, imports = r(statement)
-		functions = append(functions, function)
+		function, imports = r(statement, o, policy)
+		functions = append(functions, function...)
 		for _, i := range imports {
 			importsMap[i] = common.Null
 		}
-		function, imports = d(statement)
-		functions = append(functions, function)
+		if o.Keyset {
+			function, imports = ra(statement, o)
+			functions = append(functions, function...)
+			for _, i := range imports {
+				importsMap[i] = common.Null
+			}
+		}
+		if o.Filter {
+			function, imports = rf(statement, o)
+			functions = append(functions, function...)
+			for _, i := range imports {
+				importsMap[i] = common.Null
+			}
+		}
+		function, imports = d(statement, o, policy)
+		functions = append(functions, function...)
 		for _, i := range imports {
 			importsMap[i] = common.Null
 		}
+		methods = append(methods, functions)
 	}
-
-	importsLines := make([]string, 0)
-	for i := range importsMap {
-		if i == "" {
-			continue
-		}
-		importsLines = append(importsLines, "\t\""+i+"\"")
-	}
-
-	typeLines := `type Service struct {
-    DB *sql.DB ` + "`" + `@siu:""` + "`" + `
-}`
-	bannerS := ""
-	if banner {
-		bannerS = fmt.Sprintf("\n/**\n * Auto Generate by github.com/stella-go/stella %s on %s.\n */\n", version.VERSION, time.Now().Format("2006/01/02"))
-
-	}
-	return fmt.Sprintf("package %s\n%s\nimport (\n%s\n)\n\n%s\n\n%s", pkg, bannerS, strings.Join(importsLines, "\n"), typeLines, strings.Join(functions, "\n"))
+	return methods, importsMap
 }
 
-func c(statement *parser.Statement) (string, []string) {
+func c(statement *parser.Statement, o *Options) ([]*method, []string) {
+	methods := make([]*method, 0)
 	modelName := generator.FirstUpperCamelCase(statement.TableName.Name)
 
-	funcLines := fmt.Sprintf(`func (p *Service) Create%s(s *model.%s) error {
-    _, err := data.Create(p.DB, s)
+	methods = append(methods, &method{
+		Name:    "Create" + modelName,
+		Params:  o.ctx() + "s *model." + modelName,
+		Results: "error",
+		Body: `    _, err := data.Create(p.DB, s)
     return err
-}
-`, modelName, modelName)
-	return funcLines, nil
+`,
+	})
+	return methods, nil
 }
 
-func u(statement *parser.Statement) (string, []string) {
+func u(statement *parser.Statement, o *Options) ([]*method, []string) {
+	methods := make([]*method, 0)
 	modelName := generator.FirstUpperCamelCase(statement.TableName.Name)
 	primaryKeys := getPrimaryKeyPairs(statement)
 
+	if update := versionedUpdate(statement); update != "" {
+		methods = append(methods, &method{
+			Name:    "Update" + modelName,
+			Params:  o.ctx() + "s *model." + modelName,
+			Results: "error",
+			Body: fmt.Sprintf(`    _, err := model.%s(`+o.ctxArg()+`p.DB, s)
+    return err
+`, update),
+		})
+		return methods, nil
+	}
 	if len(primaryKeys) != 0 {
-		funcLines := fmt.Sprintf(`func (p *Service) Update%s(s *model.%s) error {
-    _, err := data.Update(p.DB, s)
+		methods = append(methods, &method{
+			Name:    "Update" + modelName,
+			Params:  o.ctx() + "s *model." + modelName,
+			Results: "error",
+			Body: `    _, err := data.Update(p.DB, s)
     return err
-}
-`, modelName, modelName)
-		return funcLines, nil
+`,
+		})
+		return methods, nil
 	}
-	return "", nil
+	return nil, nil
 }
 
-func r(statement *parser.Statement) (string, []string) {
-	funcLines := ""
+// uf updates the columns of the field mask by the primary key.
+func uf(statement *parser.Statement, o *Options) ([]*method, []string) {
+	methods := make([]*method, 0)
 	modelName := generator.FirstUpperCamelCase(statement.TableName.Name)
-	funcLines += fmt.Sprintf(`func (p *Service) QueryMany%s(s *model.%s, page int, size int) (int, []*model.%s, error) {
-    return data.QueryMany(p.DB, s, page, size)
+	name := getPrimaryKeyName(statement)
+	if name == "" {
+		return nil, nil
+	}
+	methods = append(methods, &method{
+		Name:    "Update" + modelName + "Fields",
+		Params:  o.ctx() + "s *model." + modelName + ", fields []model." + modelName + "Column",
+		Results: "error",
+		Body: fmt.Sprintf(`    _, err := model.Update%sBy%sFields(`+o.ctxArg()+`p.DB, s, fields)
+    return err
+`, modelName, name),
+	})
+	return methods, nil
 }
-`, modelName, modelName, modelName)
+
+func r(statement *parser.Statement, o *Options, logic *generator.Logic) ([]*method, []string) {
+	methods := make([]*method, 0)
+	modelName := generator.FirstUpperCamelCase(statement.TableName.Name)
+	orders, ordersArg := "", ""
+	if o.Sort {
+		orders, ordersArg = "orders []model.Order, ", "orders, "
+	}
+	columns, columnsArg, columnsSuffix := "", "", ""
+	if o.Projection {
+		columns, columnsArg, columnsSuffix = "columns []model."+modelName+"Column, ", "columns, ", "Columns"
+	}
+	if logic != nil {
+		methods = append(methods, &method{
+			Name:    "QueryMany" + modelName,
+			Params:  o.ctx() + "s *model." + modelName + ", " + orders + columns + "page int, size int",
+			Results: "(int, []*model." + modelName + ", error)",
+			Body: fmt.Sprintf(`    return model.QueryMany%s%s(`+o.ctxArg()+`p.DB, s, %s%spage, size)
+`, modelName, columnsSuffix, ordersArg, columnsArg),
+		})
+		if len(statement.PrimaryKeyPairs) > 0 {
+			methods = append(methods, &method{
+				Name:    "Query" + modelName,
+				Params:  o.ctx() + "s *model." + modelName,
+				Results: "(*model." + modelName + ", error)",
+				Body: fmt.Sprintf(`    return model.Query%sBy%s(`+o.ctxArg()+`p.DB, s)
+`, modelName, getPrimaryKeyName(statement)),
+			})
+		}
+		return methods, nil
+	}
+	if o.Sort || o.Projection {
+		methods = append(methods, &method{
+			Name:    "QueryMany" + modelName,
+			Params:  o.ctx() + "s *model." + modelName + ", " + orders + columns + "page int, size int",
+			Results: "(int, []*model." + modelName + ", error)",
+			Body: fmt.Sprintf(`    return model.QueryMany%s%s(`+o.ctxArg()+`p.DB, s, %s%spage, size)
+`, modelName, columnsSuffix, ordersArg, columnsArg),
+		})
+	} else {
+		methods = append(methods, &method{
+			Name:    "QueryMany" + modelName,
+			Params:  o.ctx() + "s *model." + modelName + ", page int, size int",
+			Results: "(int, []*model." + modelName + ", error)",
+			Body: `    return data.QueryMany(p.DB, s, page, size)
+`,
+		})
+	}
 	primaryKeyNames := make([]string, 0)
 	if len(statement.PrimaryKeyPairs) > 0 {
 		keys := statement.PrimaryKeyPairs[0]
@@ -117,27 +238,142 @@ func r(statement *parser.Statement) (string, []string) {
 		}
 	}
 	if len(primaryKeyNames) > 0 {
-		funcLines += fmt.Sprintf(`func (p *Service) Query%s(s *model.%s) (*model.%s, error) {
-    return data.Query(p.DB, s)
+		methods = append(methods, &method{
+			Name:    "Query" + modelName,
+			Params:  o.ctx() + "s *model." + modelName,
+			Results: "(*model." + modelName + ", error)",
+			Body: `    return data.Query(p.DB, s)
+`,
+		})
+	}
+	return methods, nil
 }
-`, modelName, modelName, modelName)
+
+func ra(statement *parser.Statement, o *Options) ([]*method, []string) {
+	methods := make([]*method, 0)
+	modelName := generator.FirstUpperCamelCase(statement.TableName.Name)
+	if !hasPrimaryKey(statement) {
+		return nil, nil
 	}
-	return funcLines, nil
+	methods = append(methods, &method{
+		Name:    "QueryMany" + modelName + "After",
+		Params:  o.ctx() + "s *model." + modelName + ", cursor string, size int",
+		Results: "(string, []*model." + modelName + ", error)",
+		Body: fmt.Sprintf(`    return model.QueryMany%sAfter(`+o.ctxArg()+`p.DB, s, cursor, size)
+`, modelName),
+	})
+	return methods, nil
 }
 
-func d(statement *parser.Statement) (string, []string) {
+func rf(statement *parser.Statement, o *Options) ([]*method, []string) {
+	methods := make([]*method, 0)
+	modelName := generator.FirstUpperCamelCase(statement.TableName.Name)
+	orders, ordersArg := "", ""
+	if o.Sort {
+		orders, ordersArg = "orders []model.Order, ", "orders, "
+	}
+	columns, columnsArg, columnsSuffix := "", "", ""
+	if o.Projection {
+		columns, columnsArg, columnsSuffix = "columns []model."+modelName+"Column, ", "columns, ", "Columns"
+	}
+	methods = append(methods, &method{
+		Name:    "QueryMany" + modelName + "ByFilter",
+		Params:  o.ctx() + "f *model." + modelName + "Filter, " + orders + columns + "page int, size int",
+		Results: "(int, []*model." + modelName + ", error)",
+		Body: fmt.Sprintf(`    return model.QueryMany%sByFilter%s(`+o.ctxArg()+`p.DB, f, %s%spage, size)
+`, modelName, columnsSuffix, ordersArg, columnsArg),
+	})
+	return methods, nil
+}
+
+func d(statement *parser.Statement, o *Options, logic *generator.Logic) ([]*method, []string) {
+	methods := make([]*method, 0)
 	modelName := generator.FirstUpperCamelCase(statement.TableName.Name)
 	primaryKeys := getPrimaryKeyPairs(statement)
 
+	if name := getPrimaryKeyName(statement); name != "" && logic != nil {
+		methods = append(methods, &method{
+			Name:    "Delete" + modelName,
+			Params:  o.ctx() + "s *model." + modelName,
+			Results: "error",
+			Body: fmt.Sprintf(`    _, err := model.Delete%sBy%s(`+o.ctxArg()+`p.DB, s)
+    return err
+`, modelName, name),
+		})
+		return methods, nil
+	}
 	if len(primaryKeys) != 0 {
-		funcLines := fmt.Sprintf(`func (p *Service) Delete%s(s *model.%s) error {
-    _, err := data.Delete(p.DB, s)
+		methods = append(methods, &method{
+			Name:    "Delete" + modelName,
+			Params:  o.ctx() + "s *model." + modelName,
+			Results: "error",
+			Body: `    _, err := data.Delete(p.DB, s)
     return err
+`,
+		})
+		return methods, nil
+	}
+	return nil, nil
+}
+
+// versionedUpdate returns the name of the generated curd update used for tables with a version column,
+// the reflection based updates do not check the version.
+func versionedUpdate(statement *parser.Statement) string {
+	version := generator.VersionColumn(statement)
+	if version == nil {
+		return ""
+	}
+	name := getPrimaryKeyName(statement)
+	if name == "" {
+		return ""
+	}
+	for _, col := range getPrimaryKeys(statement) {
+		if col == version {
+			return ""
+		}
+	}
+	return fmt.Sprintf("Update%sBy%s", generator.FirstUpperCamelCase(statement.TableName.Name), name)
 }
-`, modelName, modelName)
-		return funcLines, nil
+
+// getPrimaryKeyName returns the suffix of the generated curd functions keyed by the primary key.
+func getPrimaryKeyName(statement *parser.Statement) string {
+	fields := make([]string, 0)
+	for _, col := range getPrimaryKeys(statement) {
+		fields = append(fields, generator.FirstUpperCamelCase(col.ColumnName.Name))
+	}
+	return strings.Join(fields, "")
+}
+
+func getPrimaryKeys(statement *parser.Statement) []*parser.ColumnDefinition {
+	for _, col := range statement.Columns {
+		if col.PrimaryKey {
+			return []*parser.ColumnDefinition{col}
+		}
+	}
+	for _, pair := range statement.PrimaryKeyPairs {
+		p := make([]*parser.ColumnDefinition, 0)
+		for _, k := range pair {
+			for _, c := range statement.Columns {
+				if strings.EqualFold(c.ColumnName.Name, k.Name) {
+					p = append(p, c)
+					break
+				}
+			}
+		}
+		if len(p) != 0 {
+			return p
+		}
+	}
+	return nil
+}
+
+func hasPrimaryKey(statement *parser.Statement) bool {
+	for _, col := range statement.Columns {
+		if col.PrimaryKey {
+			return true
+		}
 	}
-	return "", nil
+	return len(statement.PrimaryKeyPairs) > 0
 }
 
 func getPrimaryKeyPairs(statement *parser.Statement) [][]*parser.ColumnDefinition {
@@ -0,0 +1,292 @@
+// Copyright 2010-2024 the original author or authors.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/stella-go/stella/common"
+	"github.com/stella-go/stella/generator"
+	"github.com/stella-go/stella/generator/parser"
+	"github.com/stella-go/stella/version"
+)
+
+// GenerateCurd generates a service calling the functions generated by -curd instead of the reflection of siu,
+// the logic delete is already applied by those functions.
+func GenerateCurd(pkg string, statements []*parser.Statement, o *Options) string {
+	methods, importsMap := curdMethods(statements, o)
+	importsMap["database/sql"] = common.Null
+	if o.Ctx || o.Tx {
+		importsMap["context"] = common.Null
+	}
+	importsLines := make([]string, 0)
+	for i := range importsMap {
+		if i == "" {
+			continue
+		}
+		importsLines = append(importsLines, "\t\""+i+"\"")
+	}
+
+	typeLines := `type Service struct {
+    DB *sql.DB ` + "`" + `@siu:""` + "`" + `
+}`
+	if o.Hooks && o.Tx {
+		typeLines = `type Service struct {
+    DB *sql.DB ` + "`" + `@siu:""` + "`" + `
+    tx model.DataSource
+}`
+	}
+	if m := o.withTx(); m != nil {
+		typeLines += "\n\n" + strings.TrimSuffix(m.lines("p *Service"), "\n")
+	}
+	bannerS := ""
+	if o.Banner {
+		bannerS = fmt.Sprintf("\n/**\n * Auto Generate by github.com/stella-go/stella %s on %s.\n */\n", version.VERSION, time.Now().Format("2006/01/02"))
+
+	}
+	hookLines := ""
+	if o.Hooks {
+		methods, hookLines = withHooks(statements, methods)
+	}
+	if o.Hooks && o.Tx {
+		bindTx(methods)
+	}
+	functionLines := methodLines(statements, methods)
+	if o.Hooks && o.Tx {
+		functionLines += "\n" + txLines()
+	}
+	typeLines = interfaceLines(exportedMethods(o, methods)) + "\n\n" + typeLines
+	if hookLines != "" {
+		typeLines += "\n\n" + hookLines
+	}
+	return fmt.Sprintf("package %s\n%s\nimport (\n%s\n)\n\n%s\n\n%s", pkg, bannerS, strings.Join(importsLines, "\n"), typeLines, functionLines)
+}
+
+// curdMethods are the methods of every table calling the functions generated by -curd.
+func curdMethods(statements []*parser.Statement, o *Options) ([][]*method, map[string]common.Void) {
+	importsMap := make(map[string]common.Void)
+	methods := make([][]*method, 0)
+
+	for _, statement := range statements {
+		functions := make([]*method, 0)
+		function, imports := c_curd(statement, o)
+		functions = append(functions, function...)
+		for _, i := range imports {
+			importsMap[i] = common.Null
+		}
+
+		function, imports = u_curd(statement, o)
+		functions = append(functions, function...)
+		for _, i := range imports {
+			importsMap[i] = common.Null
+		}
+		if o.Mask {
+			function, imports = uf(statement, o)
+			functions = append(functions, function...)
+			for _, i := range imports {
+				importsMap[i] = common.Null
+			}
+		}
+
+		function, imports = r_curd(statement, o)
+		functions = append(functions, function...)
+		for _, i := range imports {
+			importsMap[i] = common.Null
+		}
+		if o.Keyset {
+			function, imports = ra(statement, o)
+			functions = append(functions, function...)
+			for _, i := range imports {
+				importsMap[i] = common.Null
+			}
+		}
+		if o.Filter {
+			function, imports = rf(statement, o)
+			functions = append(functions, function...)
+			for _, i := range imports {
+				importsMap[i] = common.Null
+			}
+		}
+		function, imports = d_curd(statement, o)
+		functions = append(functions, function...)
+		for _, i := range imports {
+			importsMap[i] = common.Null
+		}
+		methods = append(methods, functions)
+	}
+	return methods, importsMap
+}
+
+func c_curd(statement *parser.Statement, o *Options) ([]*method, []string) {
+	methods := make([]*method, 0)
+	modelName := generator.FirstUpperCamelCase(statement.TableName.Name)
+
+	id, typ := getCreatedId(statement)
+	if id == "" {
+		methods = append(methods, &method{
+			Name:    "Create" + modelName,
+			Params:  o.ctx() + "s *model." + modelName,
+			Results: "error",
+			Body: fmt.Sprintf(`    _, err := model.Create%s(`+o.ctxArg()+`p.DB, s)
+    return err
+`, modelName),
+		})
+		return methods, nil
+	}
+	methods = append(methods, &method{
+		Name:    "Create" + modelName,
+		Params:  o.ctx() + "s *model." + modelName,
+		Results: "error",
+		Body: fmt.Sprintf(`    id, err := model.Create%s(`+o.ctxArg()+`p.DB, s)
+    if err != nil {
+        return err
+    }
+    if s.%s == nil && id != 0 {
+        v := %s(id)
+        s.%s = &v
+    }
+    return nil
+`, modelName, id, typ, id),
+	})
+	return methods, []string{"github.com/stella-go/siu/t/n"}
+}
+
+// getCreatedId returns the field and the type of the auto increment column, Create sets the generated id on it.
+func getCreatedId(statement *parser.Statement) (string, string) {
+	for _, col := range statement.Columns {
+		if !col.AutoIncrement {
+			continue
+		}
+		switch col.Type {
+		case "TINYINT", "INT":
+			return generator.FirstUpperCamelCase(col.ColumnName.Name), "n.Int"
+		case "BIGINT":
+			return generator.FirstUpperCamelCase(col.ColumnName.Name), "n.Int64"
+		}
+	}
+	return "", ""
+}
+
+func u_curd(statement *parser.Statement, o *Options) ([]*method, []string) {
+	methods := make([]*method, 0)
+	modelName := generator.FirstUpperCamelCase(statement.TableName.Name)
+	name := getKeyName(statement)
+	if name == "" {
+		return nil, nil
+	}
+	methods = append(methods, &method{
+		Name:    "Update" + modelName,
+		Params:  o.ctx() + "s *model." + modelName,
+		Results: "error",
+		Body: fmt.Sprintf(`    _, err := model.Update%sBy%s(`+o.ctxArg()+`p.DB, s)
+    return err
+`, modelName, name),
+	})
+	return methods, nil
+}
+
+func r_curd(statement *parser.Statement, o *Options) ([]*method, []string) {
+	methods := make([]*method, 0)
+	modelName := generator.FirstUpperCamelCase(statement.TableName.Name)
+	orders, ordersArg := "", ""
+	if o.Sort {
+		orders, ordersArg = "orders []model.Order, ", "orders, "
+	}
+	columns, columnsArg, columnsSuffix := "", "", ""
+	if o.Projection {
+		columns, columnsArg, columnsSuffix = "columns []model."+modelName+"Column, ", "columns, ", "Columns"
+	}
+	methods = append(methods, &method{
+		Name:    "QueryMany" + modelName,
+		Params:  o.ctx() + "s *model." + modelName + ", " + orders + columns + "page int, size int",
+		Results: "(int, []*model." + modelName + ", error)",
+		Body: fmt.Sprintf(`    return model.QueryMany%s%s(`+o.ctxArg()+`p.DB, s, %s%spage, size)
+`, modelName, columnsSuffix, ordersArg, columnsArg),
+	})
+	if name := getKeyName(statement); name != "" {
+		methods = append(methods, &method{
+			Name:    "Query" + modelName,
+			Params:  o.ctx() + "s *model." + modelName,
+			Results: "(*model." + modelName + ", error)",
+			Body: fmt.Sprintf(`    return model.Query%sBy%s(`+o.ctxArg()+`p.DB, s)
+`, modelName, name),
+		})
+	}
+	for _, keys := range generator.UniqKeyPairs(statement) {
+		name := keysName(keys)
+		methods = append(methods, &method{
+			Name:    "Query" + modelName + "By" + name,
+			Params:  o.ctx() + "s *model." + modelName,
+			Results: "(*model." + modelName + ", error)",
+			Body: fmt.Sprintf(`    return model.Query%sBy%s(`+o.ctxArg()+`p.DB, s)
+`, modelName, name),
+		})
+	}
+	for _, keys := range generator.IndexKeyPairs(statement) {
+		name := keysName(keys)
+		methods = append(methods, &method{
+			Name:    "QueryMany" + modelName + "By" + name,
+			Params:  o.ctx() + "s *model." + modelName + ", page int, size int",
+			Results: "(int, []*model." + modelName + ", error)",
+			Body: fmt.Sprintf(`    return model.QueryMany%sBy%s(`+o.ctxArg()+`p.DB, s, page, size)
+`, modelName, name),
+		})
+	}
+	return methods, nil
+}
+
+func d_curd(statement *parser.Statement, o *Options) ([]*method, []string) {
+	methods := make([]*method, 0)
+	modelName := generator.FirstUpperCamelCase(statement.TableName.Name)
+	name := getKeyName(statement)
+	if name == "" {
+		return nil, nil
+	}
+	methods = append(methods, &method{
+		Name:    "Delete" + modelName,
+		Params:  o.ctx() + "s *model." + modelName,
+		Results: "error",
+		Body: fmt.Sprintf(`    _, err := model.Delete%sBy%s(`+o.ctxArg()+`p.DB, s)
+    return err
+`, modelName, name),
+	})
+	return methods, nil
+}
+
+// getKeyName returns the suffix of the curd functions the single rows are updated, queried and deleted by.
+func getKeyName(statement *parser.Statement) string {
+	return keysName(getKeyColumns(statement))
+}
+
+// getKeyColumns returns the columns the single rows are found by, the primary key or else the first unique key.
+func getKeyColumns(statement *parser.Statement) []*parser.ColumnDefinition {
+	if keys := getPrimaryKeys(statement); len(keys) > 0 {
+		return keys
+	}
+	if pairs := generator.UniqKeyPairs(statement); len(pairs) > 0 {
+		return pairs[0]
+	}
+	return nil
+}
+
+func keysName(keys []*parser.ColumnDefinition) string {
+	fields := make([]string, 0)
+	for _, col := range keys {
+		fields = append(fields, generator.FirstUpperCamelCase(col.ColumnName.Name))
+	}
+	return strings.Join(fields, "")
+}
@@ -25,88 +25,273 @@ import (
 	"github.com/stella-go/stella/version"
 )
 
-func GenerateGorm(pkg string, statements []*parser.Statement, banner bool) string {
-	importsMap := make(map[string]common.Void)
-	importsMap["errors"] = common.Null
+func GenerateGorm(pkg string, statements []*parser.Statement, o *Options) string {
+	methods, importsMap := gormMethods(statements, o)
 	importsMap["gorm.io/gorm"] = common.Null
-	functions := make([]string, 0)
+	if o.Ctx || o.Tx {
+		importsMap["context"] = common.Null
+	}
+
+	typeLines := `type Service struct {
+    DB *gorm.DB ` + "`" + `@siu:""` + "`" + `
+}`
+	if m := o.withTx(); m != nil {
+		typeLines += "\n\n" + strings.TrimSuffix(m.lines("p *Service"), "\n")
+	}
+	bannerS := ""
+	if o.Banner {
+		bannerS = fmt.Sprintf("\n/**\n * Auto Generate by github.com/stella-go/stella %s on %s.\n */\n", version.VERSION, time.Now().Format("2006/01/02"))
+
+	}
+	hookLines := ""
+	if o.Hooks {
+		methods, hookLines = withHooks(statements, methods)
+	}
+	functionLines := methodLines(statements, methods)
+	if strings.Contains(functionLines, "p.dataSource()") {
+		importsMap["database/sql"] = common.Null
+		functionLines += "\n" + gormDataSourceLines()
+	}
+	if o.Hooks && o.Tx {
+		functionLines += "\n" + gormTxLines()
+	}
+	importsLines := make([]string, 0)
+	for i := range importsMap {
+		if i == "" {
+			continue
+		}
+		importsLines = append(importsLines, "\t\""+i+"\"")
+	}
+	typeLines = interfaceLines(exportedMethods(o, methods)) + "\n\n" + typeLines
+	if hookLines != "" {
+		typeLines += "\n\n" + hookLines
+	}
+	return fmt.Sprintf("package %s\n%s\nimport (\n%s\n)\n\n%s\n\n%s", pkg, bannerS, strings.Join(importsLines, "\n"), typeLines, functionLines)
+}
+
+// gormDataSourceLines hand the functions of -curd the transaction a gorm DB runs in, so a service of NewTx
+// or of the tx of WithTx does not run them on the database outside of it.
+func gormDataSourceLines() string {
+	return `// dataSource is the transaction p.DB runs in, or its database.
+func (p *Service) dataSource() (model.DataSource, error) {
+    if tx, ok := p.DB.Statement.ConnPool.(*sql.Tx); ok {
+        return tx, nil
+    }
+    return p.DB.DB()
+}
+`
+}
+
+// gormMethods are the methods of every table on gorm.
+func gormMethods(statements []*parser.Statement, o *Options) ([][]*method, map[string]common.Void) {
+	importsMap := make(map[string]common.Void)
+	methods := make([][]*method, 0)
 
 	for _, statement := range statements {
-		functions = append(functions, "// ==================== "+generator.FirstUpperCamelCase(statement.TableName.Name)+" ====================")
-		function, imports := c_gorm(statement)
-		functions = append(functions, function)
+		functions := make([]*method, 0)
+		policy := generator.ParseLogic(statement, o.Logic)
+		function, imports := c_gorm(statement, o)
+		functions = append(functions, function...)
 		for _, i := range imports {
 			importsMap[i] = common.Null
 		}
 
-		function, imports = u_gorm(statement)
-		functions = append(functions, function)
+		function, imports = u_gorm(statement, o)
+		functions = append(functions, function...)
 		for _, i := range imports {
 			importsMap[i] = common.Null
 		}
+		if o.Mask {
+			function, imports = uf_gorm(statement, o)
+			functions = append(functions, function...)
+			for _, i := range imports {
+				importsMap[i] = common.Null
+			}
+		}
 
-		function, imports = r_gorm(statement)
-		functions = append(functions, function)
+		function, imports = r_gorm(statement, o, policy)
+		functions = append(functions, function...)
 		for _, i := range imports {
 			importsMap[i] = common.Null
 		}
-		function, imports = d_gorm(statement)
-		functions = append(functions, function)
+		if o.Keyset {
+			function, imports = ra_gorm(statement, o)
+			functions = append(functions, function...)
+			for _, i := range imports {
+				importsMap[i] = common.Null
+			}
+		}
+		if o.Filter {
+			function, imports = rf_gorm(statement, o)
+			functions = append(functions, function...)
+			for _, i := range imports {
+				importsMap[i] = common.Null
+			}
+		}
+		function, imports = d_gorm(statement, o, policy)
+		functions = append(functions, function...)
 		for _, i := range imports {
 			importsMap[i] = common.Null
 		}
+		methods = append(methods, functions)
 	}
-
-	importsLines := make([]string, 0)
-	for i := range importsMap {
-		if i == "" {
-			continue
-		}
-		importsLines = append(importsLines, "\t\""+i+"\"")
-	}
-
-	typeLines := `type Service struct {
-    DB *gorm.DB ` + "`" + `@siu:""` + "`" + `
-}`
-	bannerS := ""
-	if banner {
-		bannerS = fmt.Sprintf("\n/**\n * Auto Generate by github.com/stella-go/stella %s on %s.\n */\n", version.VERSION, time.Now().Format("2006/01/02"))
-
-	}
-	return fmt.Sprintf("package %s\n%s\nimport (\n%s\n)\n\n%s\n\n%s", pkg, bannerS, strings.Join(importsLines, "\n"), typeLines, strings.Join(functions, "\n"))
+	return methods, importsMap
 }
 
-func c_gorm(statement *parser.Statement) (string, []string) {
+func c_gorm(statement *parser.Statement, o *Options) ([]*method, []string) {
+	methods := make([]*method, 0)
 	modelName := generator.FirstUpperCamelCase(statement.TableName.Name)
 
-	funcLines := fmt.Sprintf(`func (p *Service) Create%s(s *model.%s) error {
-    r := p.DB.Model(s).Create(s)
+	zero := ""
+	imports := make([]string, 0)
+	if version := generator.VersionColumn(statement); version != nil {
+		versionName := generator.FirstUpperCamelCase(version.ColumnName.Name)
+		typ := "n.Int"
+		if version.Type == "BIGINT" {
+			typ = "n.Int64"
+		}
+		zero = fmt.Sprintf(`    if s.%s == nil {
+        version := %s(0)
+        s.%s = &version
+    }
+`, versionName, typ, versionName)
+		imports = append(imports, "github.com/stella-go/siu/t/n")
+	}
+	methods = append(methods, &method{
+		Name:    "Create" + modelName,
+		Params:  o.ctx() + "s *model." + modelName,
+		Results: "error",
+		Body: zero + `    r := ` + o.gormDB() + `.Model(s).Create(s)
     return r.Error
-}
-`, modelName, modelName)
-	return funcLines, nil
+`,
+	})
+	return methods, imports
 }
 
-func u_gorm(statement *parser.Statement) (string, []string) {
+func u_gorm(statement *parser.Statement, o *Options) ([]*method, []string) {
+	methods := make([]*method, 0)
 	modelName := generator.FirstUpperCamelCase(statement.TableName.Name)
 	primaryKeys := getPrimaryKeyPairs(statement)
 
+	if versionedUpdate(statement) != "" {
+		version := generator.VersionColumn(statement)
+		versionName := generator.FirstUpperCamelCase(version.ColumnName.Name)
+		methods = append(methods, &method{
+			Name:    "Update" + modelName,
+			Params:  o.ctx() + "s *model." + modelName,
+			Results: "error",
+			Body: fmt.Sprintf(`    if s.%s == nil {
+        return fmt.Errorf("version can not be nil")
+    }
+    version := *s.%s
+    next := version + 1
+    s.%s = &next
+    r := `+o.gormDB()+`.Model(s).Where("%s = ?", version).Updates(s)
+    if r.Error != nil {
+        s.%s = &version
+        return r.Error
+    }
+    if r.RowsAffected == 0 {
+        s.%s = &version
+        return model.ErrStaleObject
+    }
+    return nil
+`, versionName, versionName, versionName, version.ColumnName.Name, versionName, versionName),
+		})
+		return methods, []string{"fmt"}
+	}
 	if len(primaryKeys) != 0 {
-		funcLines := fmt.Sprintf(`func (p *Service) Update%s(s *model.%s) error {
-    r := p.DB.Model(s).Updates(s)
+		methods = append(methods, &method{
+			Name:    "Update" + modelName,
+			Params:  o.ctx() + "s *model." + modelName,
+			Results: "error",
+			Body: `    r := ` + o.gormDB() + `.Model(s).Updates(s)
     return r.Error
+`,
+		})
+		return methods, nil
+	}
+	return nil, nil
 }
-`, modelName, modelName)
-		return funcLines, nil
+
+func uf_gorm(statement *parser.Statement, o *Options) ([]*method, []string) {
+	methods := make([]*method, 0)
+	modelName := generator.FirstUpperCamelCase(statement.TableName.Name)
+	name := getPrimaryKeyName(statement)
+	if name == "" {
+		return nil, nil
 	}
-	return "", nil
+	methods = append(methods, &method{
+		Name:    "Update" + modelName + "Fields",
+		Params:  o.ctx() + "s *model." + modelName + ", fields []model." + modelName + "Column",
+		Results: "error",
+		Body: fmt.Sprintf(`    db, err := p.dataSource()
+    if err != nil {
+        return err
+    }
+    _, err = model.Update%sBy%sFields(`+o.ctxArg()+`db, s, fields)
+    return err
+`, modelName, name),
+	})
+	return methods, nil
 }
 
-func r_gorm(statement *parser.Statement) (string, []string) {
-	funcLines := ""
+func r_gorm(statement *parser.Statement, o *Options, logic *generator.Logic) ([]*method, []string) {
+	methods := make([]*method, 0)
 	modelName := generator.FirstUpperCamelCase(statement.TableName.Name)
-	funcLines += fmt.Sprintf(`func (p *Service) QueryMany%s(s *model.%s, page int, size int) (int, []*model.%s, error) {
-    stmt := p.DB.Model(s).Where(s)
+	orders, ordersArg := "", ""
+	if o.Sort {
+		orders, ordersArg = "orders []model.Order, ", "orders, "
+	}
+	columns, columnsArg, columnsSuffix := "", "", ""
+	if o.Projection {
+		columns, columnsArg, columnsSuffix = "columns []model."+modelName+"Column, ", "columns, ", "Columns"
+	}
+	if logic != nil {
+		methods = append(methods, &method{
+			Name:    "QueryMany" + modelName,
+			Params:  o.ctx() + "s *model." + modelName + ", " + orders + columns + "page int, size int",
+			Results: "(int, []*model." + modelName + ", error)",
+			Body: fmt.Sprintf(`    db, err := p.dataSource()
+    if err != nil {
+        return 0, nil, err
+    }
+    return model.QueryMany%s%s(`+o.ctxArg()+`db, s, %s%spage, size)
+`, modelName, columnsSuffix, ordersArg, columnsArg),
+		})
+		if len(statement.PrimaryKeyPairs) > 0 {
+			methods = append(methods, &method{
+				Name:    "Query" + modelName,
+				Params:  o.ctx() + "s *model." + modelName,
+				Results: "(*model." + modelName + ", error)",
+				Body: fmt.Sprintf(`    db, err := p.dataSource()
+    if err != nil {
+        return nil, err
+    }
+    return model.Query%sBy%s(`+o.ctxArg()+`db, s)
+`, modelName, getPrimaryKeyName(statement)),
+			})
+		}
+		return methods, nil
+	}
+	if o.Sort || o.Projection {
+		methods = append(methods, &method{
+			Name:    "QueryMany" + modelName,
+			Params:  o.ctx() + "s *model." + modelName + ", " + orders + columns + "page int, size int",
+			Results: "(int, []*model." + modelName + ", error)",
+			Body: fmt.Sprintf(`    db, err := p.dataSource()
+    if err != nil {
+        return 0, nil, err
+    }
+    return model.QueryMany%s%s(`+o.ctxArg()+`db, s, %s%spage, size)
+`, modelName, columnsSuffix, ordersArg, columnsArg),
+		})
+	} else {
+		methods = append(methods, &method{
+			Name:    "QueryMany" + modelName,
+			Params:  o.ctx() + "s *model." + modelName + ", page int, size int",
+			Results: "(int, []*model." + modelName + ", error)",
+			Body: fmt.Sprintf(`    stmt := `+o.gormDB()+`.Model(s).Where(s)
     var count int64
     many := make([]*model.%s, 0)
     r := stmt.Count(&count)
@@ -126,8 +311,9 @@ func r_gorm(statement *parser.Statement) (string, []string) {
         }
     }
     return int(count), many, nil
-}
-`, modelName, modelName, modelName, modelName)
+`, modelName),
+		})
+	}
 	primaryKeyNames := make([]string, 0)
 	if len(statement.PrimaryKeyPairs) > 0 {
 		keys := statement.PrimaryKeyPairs[0]
@@ -136,9 +322,12 @@ func r_gorm(statement *parser.Statement) (string, []string) {
 		}
 	}
 	if len(primaryKeyNames) > 0 {
-		funcLines += fmt.Sprintf(`func (p *Service) Query%s(s *model.%s) (*model.%s, error) {
-    ss := &model.%s{}
-    r := p.DB.Model(s).Where(s).Take(&ss)
+		methods = append(methods, &method{
+			Name:    "Query" + modelName,
+			Params:  o.ctx() + "s *model." + modelName,
+			Results: "(*model." + modelName + ", error)",
+			Body: fmt.Sprintf(`    ss := &model.%s{}
+    r := `+o.gormDB()+`.Model(s).Where(s).Take(&ss)
     if r.Error != nil {
         if errors.Is(r.Error, gorm.ErrRecordNotFound) {
             return nil, nil
@@ -147,23 +336,87 @@ func r_gorm(statement *parser.Statement) (string, []string) {
         }
     }
     return ss, nil
+`, modelName),
+		})
+	}
+	return methods, []string{"errors"}
+}
+
+func ra_gorm(statement *parser.Statement, o *Options) ([]*method, []string) {
+	methods := make([]*method, 0)
+	modelName := generator.FirstUpperCamelCase(statement.TableName.Name)
+	if !hasPrimaryKey(statement) {
+		return nil, nil
+	}
+	methods = append(methods, &method{
+		Name:    "QueryMany" + modelName + "After",
+		Params:  o.ctx() + "s *model." + modelName + ", cursor string, size int",
+		Results: "(string, []*model." + modelName + ", error)",
+		Body: fmt.Sprintf(`    db, err := p.dataSource()
+    if err != nil {
+        return "", nil, err
+    }
+    return model.QueryMany%sAfter(`+o.ctxArg()+`db, s, cursor, size)
+`, modelName),
+	})
+	return methods, nil
 }
-`, modelName, modelName, modelName, modelName)
+
+func rf_gorm(statement *parser.Statement, o *Options) ([]*method, []string) {
+	methods := make([]*method, 0)
+	modelName := generator.FirstUpperCamelCase(statement.TableName.Name)
+	orders, ordersArg := "", ""
+	if o.Sort {
+		orders, ordersArg = "orders []model.Order, ", "orders, "
 	}
-	return funcLines, nil
+	columns, columnsArg, columnsSuffix := "", "", ""
+	if o.Projection {
+		columns, columnsArg, columnsSuffix = "columns []model."+modelName+"Column, ", "columns, ", "Columns"
+	}
+	methods = append(methods, &method{
+		Name:    "QueryMany" + modelName + "ByFilter",
+		Params:  o.ctx() + "f *model." + modelName + "Filter, " + orders + columns + "page int, size int",
+		Results: "(int, []*model." + modelName + ", error)",
+		Body: fmt.Sprintf(`    db, err := p.dataSource()
+    if err != nil {
+        return 0, nil, err
+    }
+    return model.QueryMany%sByFilter%s(`+o.ctxArg()+`db, f, %s%spage, size)
+`, modelName, columnsSuffix, ordersArg, columnsArg),
+	})
+	return methods, nil
 }
 
-func d_gorm(statement *parser.Statement) (string, []string) {
+func d_gorm(statement *parser.Statement, o *Options, logic *generator.Logic) ([]*method, []string) {
+	methods := make([]*method, 0)
 	modelName := generator.FirstUpperCamelCase(statement.TableName.Name)
 	primaryKeys := getPrimaryKeyPairs(statement)
 
+	if name := getPrimaryKeyName(statement); name != "" && logic != nil {
+		methods = append(methods, &method{
+			Name:    "Delete" + modelName,
+			Params:  o.ctx() + "s *model." + modelName,
+			Results: "error",
+			Body: fmt.Sprintf(`    db, err := p.dataSource()
+    if err != nil {
+        return err
+    }
+    _, err = model.Delete%sBy%s(`+o.ctxArg()+`db, s)
+    return err
+`, modelName, name),
+		})
+		return methods, nil
+	}
 	if len(primaryKeys) != 0 {
-		funcLines := fmt.Sprintf(`func (p *Service) Delete%s(s *model.%s) error {
-    r := p.DB.Model(s).Delete(s, s)
+		methods = append(methods, &method{
+			Name:    "Delete" + modelName,
+			Params:  o.ctx() + "s *model." + modelName,
+			Results: "error",
+			Body: `    r := ` + o.gormDB() + `.Model(s).Delete(s, s)
     return r.Error
-}
-`, modelName, modelName)
-		return funcLines, nil
+`,
+		})
+		return methods, nil
 	}
-	return "", nil
+	return nil, nil
 }
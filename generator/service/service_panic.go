@@ -25,98 +25,216 @@ import (
 	"github.com/stella-go/stella/version"
 )
 
-func GeneratePanic(pkg string, statements []*parser.Statement, banner bool) string {
-	importsMap := make(map[string]common.Void)
+func GeneratePanic(pkg string, statements []*parser.Statement, o *Options) string {
+	methods, importsMap := panicMethods(statements, o)
 	importsMap["database/sql"] = common.Null
 	importsMap["github.com/stella-go/siu/fn/data"] = common.Null
-	functions := make([]string, 0)
+	if o.Ctx || o.Tx {
+		importsMap["context"] = common.Null
+	}
+	importsLines := make([]string, 0)
+	for i := range importsMap {
+		if i == "" {
+			continue
+		}
+		importsLines = append(importsLines, "\t\""+i+"\"")
+	}
+
+	typeLines := `type Service struct {
+    DB *sql.DB ` + "`" + `@siu:""` + "`" + `
+}`
+	if m := o.withTx(); m != nil {
+		typeLines += "\n\n" + strings.TrimSuffix(m.lines("p *Service"), "\n")
+	}
+	bannerS := ""
+	if o.Banner {
+		bannerS = fmt.Sprintf("\n/**\n * Auto Generate by github.com/stella-go/stella %s on %s.\n */\n", version.VERSION, time.Now().Format("2006/01/02"))
+
+	}
+	hookLines := ""
+	if o.Hooks {
+		methods, hookLines = withHooks(statements, methods)
+	}
+	functionLines := methodLines(statements, methods)
+	typeLines = interfaceLines(exportedMethods(o, methods)) + "\n\n" + typeLines
+	if hookLines != "" {
+		typeLines += "\n\n" + hookLines
+	}
+	return fmt.Sprintf("package %s\n%s\nimport (\n%s\n)\n\n%s\n\n%s", pkg, bannerS, strings.Join(importsLines, "\n"), typeLines, functionLines)
+}
+
+// panicMethods are the panic style methods of every table on the reflection of siu.
+func panicMethods(statements []*parser.Statement, o *Options) ([][]*method, map[string]common.Void) {
+	importsMap := make(map[string]common.Void)
+	methods := make([][]*method, 0)
 
 	for _, statement := range statements {
-		functions = append(functions, "// ==================== "+generator.FirstUpperCamelCase(statement.TableName.Name)+" ====================")
-		function, imports := c_panic(statement)
-		functions = append(functions, function)
+		functions := make([]*method, 0)
+		policy := generator.ParseLogic(statement, o.Logic)
+		function, imports := c_panic(statement, o)
+		functions = append(functions, function...)
 		for _, i := range imports {
 			importsMap[i] = common.Null
 		}
 
-		function, imports = u_panic(statement)
-		functions = append(functions, function)
+		function, imports = u_panic(statement, o)
+		functions = append(functions, function...)
 		for _, i := range imports {
 			importsMap[i] = common.Null
 		}
+		if o.Mask {
+			function, imports = uf_panic(statement, o)
+			functions = append(functions, function...)
+			for _, i := range imports {
+				importsMap[i] = common.Null
+			}
+		}
 
-		function, imports = r_panic(statement)
-		functions = append(functions, function)
+		function, imports = r_panic(statement, o, policy)
+		functions = append(functions, function...)
 		for _, i := range imports {
 			importsMap[i] = common.Null
 		}
-		function, imports = d_panic(statement)
-		functions = append(functions, function)
+		if o.Keyset {
+			function, imports = ra_panic(statement, o)
+			functions = append(functions, function...)
+			for _, i := range imports {
+				importsMap[i] = common.Null
+			}
+		}
+		if o.Filter {
+			function, imports = rf_panic(statement, o)
+			functions = append(functions, function...)
+			for _, i := range imports {
+				importsMap[i] = common.Null
+			}
+		}
+		function, imports = d_panic(statement, o, policy)
+		functions = append(functions, function...)
 		for _, i := range imports {
 			importsMap[i] = common.Null
 		}
+		methods = append(methods, functions)
 	}
-
-	importsLines := make([]string, 0)
-	for i := range importsMap {
-		if i == "" {
-			continue
-		}
-		importsLines = append(importsLines, "\t\""+i+"\"")
-	}
-
-	typeLines := `type Service struct {
-    DB *sql.DB ` + "`" + `@siu:""` + "`" + `
-}`
-	bannerS := ""
-	if banner {
-		bannerS = fmt.Sprintf("\n/**\n * Auto Generate by github.com/stella-go/stella %s on %s.\n */\n", version.VERSION, time.Now().Format("2006/01/02"))
-
-	}
-	return fmt.Sprintf("package %s\n%s\nimport (\n%s\n)\n\n%s\n\n%s", pkg, bannerS, strings.Join(importsLines, "\n"), typeLines, strings.Join(functions, "\n"))
+	return methods, importsMap
 }
 
-func c_panic(statement *parser.Statement) (string, []string) {
+func c_panic(statement *parser.Statement, o *Options) ([]*method, []string) {
+	methods := make([]*method, 0)
 	modelName := generator.FirstUpperCamelCase(statement.TableName.Name)
 
-	funcLines := fmt.Sprintf(`func (p *Service) Create%s(s *model.%s) {
-    _, err := data.Create(p.DB, s)
+	methods = append(methods, &method{
+		Name:    "Create" + modelName,
+		Params:  o.ctx() + "s *model." + modelName,
+		Results: "",
+		Body: `    _, err := data.Create(p.DB, s)
     if err != nil {
         panic(err)
     }
-}
-`, modelName, modelName)
-	return funcLines, nil
+`,
+	})
+	return methods, nil
 }
 
-func u_panic(statement *parser.Statement) (string, []string) {
+func u_panic(statement *parser.Statement, o *Options) ([]*method, []string) {
+	methods := make([]*method, 0)
 	modelName := generator.FirstUpperCamelCase(statement.TableName.Name)
 	primaryKeys := getPrimaryKeyPairs(statement)
 
+	if update := versionedUpdate(statement); update != "" {
+		methods = append(methods, &method{
+			Name:    "Update" + modelName,
+			Params:  o.ctx() + "s *model." + modelName,
+			Results: "",
+			Body: fmt.Sprintf(`    model.%s(`+o.ctxArg()+`p.DB, s)
+`, update),
+		})
+		return methods, nil
+	}
 	if len(primaryKeys) != 0 {
-		funcLines := fmt.Sprintf(`func (p *Service) Update%s(s *model.%s) {
-    _, err := data.Update(p.DB, s)
+		methods = append(methods, &method{
+			Name:    "Update" + modelName,
+			Params:  o.ctx() + "s *model." + modelName,
+			Results: "",
+			Body: `    _, err := data.Update(p.DB, s)
     if err != nil {
         panic(err)
     }
+`,
+		})
+		return methods, nil
+	}
+	return nil, nil
 }
-`, modelName, modelName)
-		return funcLines, nil
+
+func uf_panic(statement *parser.Statement, o *Options) ([]*method, []string) {
+	methods := make([]*method, 0)
+	modelName := generator.FirstUpperCamelCase(statement.TableName.Name)
+	name := getPrimaryKeyName(statement)
+	if name == "" {
+		return nil, nil
 	}
-	return "", nil
+	methods = append(methods, &method{
+		Name:    "Update" + modelName + "Fields",
+		Params:  o.ctx() + "s *model." + modelName + ", fields []model." + modelName + "Column",
+		Results: "",
+		Body: fmt.Sprintf(`    model.Update%sBy%sFields(`+o.ctxArg()+`p.DB, s, fields)
+`, modelName, name),
+	})
+	return methods, nil
 }
 
-func r_panic(statement *parser.Statement) (string, []string) {
-	funcLines := ""
+func r_panic(statement *parser.Statement, o *Options, logic *generator.Logic) ([]*method, []string) {
+	methods := make([]*method, 0)
 	modelName := generator.FirstUpperCamelCase(statement.TableName.Name)
-	funcLines += fmt.Sprintf(`func (p *Service) QueryMany%s(s *model.%s, page int, size int) (int, []*model.%s) {
-    count, many, err := data.QueryMany(p.DB, s, page, size)
+	orders, ordersArg := "", ""
+	if o.Sort {
+		orders, ordersArg = "orders []model.Order, ", "orders, "
+	}
+	columns, columnsArg, columnsSuffix := "", "", ""
+	if o.Projection {
+		columns, columnsArg, columnsSuffix = "columns []model."+modelName+"Column, ", "columns, ", "Columns"
+	}
+	if logic != nil {
+		methods = append(methods, &method{
+			Name:    "QueryMany" + modelName,
+			Params:  o.ctx() + "s *model." + modelName + ", " + orders + columns + "page int, size int",
+			Results: "(int, []*model." + modelName + ")",
+			Body: fmt.Sprintf(`    return model.QueryMany%s%s(`+o.ctxArg()+`p.DB, s, %s%spage, size)
+`, modelName, columnsSuffix, ordersArg, columnsArg),
+		})
+		if len(statement.PrimaryKeyPairs) > 0 {
+			methods = append(methods, &method{
+				Name:    "Query" + modelName,
+				Params:  o.ctx() + "s *model." + modelName,
+				Results: "*model." + modelName,
+				Body: fmt.Sprintf(`    return model.Query%sBy%s(`+o.ctxArg()+`p.DB, s)
+`, modelName, getPrimaryKeyName(statement)),
+			})
+		}
+		return methods, nil
+	}
+	if o.Sort || o.Projection {
+		methods = append(methods, &method{
+			Name:    "QueryMany" + modelName,
+			Params:  o.ctx() + "s *model." + modelName + ", " + orders + columns + "page int, size int",
+			Results: "(int, []*model." + modelName + ")",
+			Body: fmt.Sprintf(`    return model.QueryMany%s%s(`+o.ctxArg()+`p.DB, s, %s%spage, size)
+`, modelName, columnsSuffix, ordersArg, columnsArg),
+		})
+	} else {
+		methods = append(methods, &method{
+			Name:    "QueryMany" + modelName,
+			Params:  o.ctx() + "s *model." + modelName + ", page int, size int",
+			Results: "(int, []*model." + modelName + ")",
+			Body: `    count, many, err := data.QueryMany(p.DB, s, page, size)
     if err != nil {
         panic(err)
     }
     return count, many
-}
-`, modelName, modelName, modelName)
+`,
+		})
+	}
 	primaryKeyNames := make([]string, 0)
 	if len(statement.PrimaryKeyPairs) > 0 {
 		keys := statement.PrimaryKeyPairs[0]
@@ -125,31 +243,85 @@ func r_panic(statement *parser.Statement) (string, []string) {
 		}
 	}
 	if len(primaryKeyNames) > 0 {
-		funcLines += fmt.Sprintf(`func (p *Service) Query%s(s *model.%s) *model.%s {
-    one, err := data.Query(p.DB, s)
+		methods = append(methods, &method{
+			Name:    "Query" + modelName,
+			Params:  o.ctx() + "s *model." + modelName,
+			Results: "*model." + modelName,
+			Body: `    one, err := data.Query(p.DB, s)
     if err != nil {
         panic(err)
     }
     return one
+`,
+		})
+	}
+	return methods, nil
 }
-`, modelName, modelName, modelName)
+
+func ra_panic(statement *parser.Statement, o *Options) ([]*method, []string) {
+	methods := make([]*method, 0)
+	modelName := generator.FirstUpperCamelCase(statement.TableName.Name)
+	if !hasPrimaryKey(statement) {
+		return nil, nil
 	}
-	return funcLines, nil
+	methods = append(methods, &method{
+		Name:    "QueryMany" + modelName + "After",
+		Params:  o.ctx() + "s *model." + modelName + ", cursor string, size int",
+		Results: "(string, []*model." + modelName + ")",
+		Body: fmt.Sprintf(`    return model.QueryMany%sAfter(`+o.ctxArg()+`p.DB, s, cursor, size)
+`, modelName),
+	})
+	return methods, nil
 }
 
-func d_panic(statement *parser.Statement) (string, []string) {
+func rf_panic(statement *parser.Statement, o *Options) ([]*method, []string) {
+	methods := make([]*method, 0)
+	modelName := generator.FirstUpperCamelCase(statement.TableName.Name)
+	orders, ordersArg := "", ""
+	if o.Sort {
+		orders, ordersArg = "orders []model.Order, ", "orders, "
+	}
+	columns, columnsArg, columnsSuffix := "", "", ""
+	if o.Projection {
+		columns, columnsArg, columnsSuffix = "columns []model."+modelName+"Column, ", "columns, ", "Columns"
+	}
+	methods = append(methods, &method{
+		Name:    "QueryMany" + modelName + "ByFilter",
+		Params:  o.ctx() + "f *model." + modelName + "Filter, " + orders + columns + "page int, size int",
+		Results: "(int, []*model." + modelName + ")",
+		Body: fmt.Sprintf(`    return model.QueryMany%sByFilter%s(`+o.ctxArg()+`p.DB, f, %s%spage, size)
+`, modelName, columnsSuffix, ordersArg, columnsArg),
+	})
+	return methods, nil
+}
+
+func d_panic(statement *parser.Statement, o *Options, logic *generator.Logic) ([]*method, []string) {
+	methods := make([]*method, 0)
 	modelName := generator.FirstUpperCamelCase(statement.TableName.Name)
 	primaryKeys := getPrimaryKeyPairs(statement)
 
+	if name := getPrimaryKeyName(statement); name != "" && logic != nil {
+		methods = append(methods, &method{
+			Name:    "Delete" + modelName,
+			Params:  o.ctx() + "s *model." + modelName,
+			Results: "",
+			Body: fmt.Sprintf(`    model.Delete%sBy%s(`+o.ctxArg()+`p.DB, s)
+`, modelName, name),
+		})
+		return methods, nil
+	}
 	if len(primaryKeys) != 0 {
-		funcLines := fmt.Sprintf(`func (p *Service) Delete%s(s *model.%s) {
-    _, err := data.Delete(p.DB, s)
+		methods = append(methods, &method{
+			Name:    "Delete" + modelName,
+			Params:  o.ctx() + "s *model." + modelName,
+			Results: "",
+			Body: `    _, err := data.Delete(p.DB, s)
     if err != nil {
         panic(err)
     }
-}
-`, modelName, modelName)
-		return funcLines, nil
+`,
+		})
+		return methods, nil
 	}
-	return "", nil
+	return nil, nil
 }
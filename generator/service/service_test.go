@@ -34,12 +34,23 @@ create table tb_dept2(
 
 func TestGenerate(t *testing.T) {
 	s := parser.Parse(sql)
-	file := Generate("service", s, true)
-	t.Log(file)
+	o := &Options{Banner: true}
+	t.Log(Generate("service", s, o))
+	t.Log(GenerateMock("servicemock", s, o))
 }
 
 func TestGeneratePanic(t *testing.T) {
 	s := parser.Parse(sql)
-	file := GeneratePanic("service", s, true)
-	t.Log(file)
+	t.Log(GeneratePanic("service", s, &Options{Banner: true, Hooks: true, Panic: true}))
+}
+
+func TestGenerateCurd(t *testing.T) {
+	s := parser.Parse(sql)
+	o := &Options{Banner: true, Ctx: true, Tx: true, Keyset: true, Filter: true, Sort: true, Projection: true, Mask: true, Hooks: true, Curd: true}
+	t.Log(GenerateCurd("service", s, o))
+	t.Log(GenerateCache("service", s, o))
+	t.Log(GenerateRedisCache("service", true))
+	o = &Options{Banner: true, Curd: true, Panic: true}
+	t.Log(GenerateCurdPanic("service", s, o))
+	t.Log(GenerateMock("servicemock", s, o))
 }
@@ -0,0 +1,49 @@
+// Copyright 2010-2024 the original author or authors.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+// 	http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package generator
+
+import (
+	"strings"
+
+	"github.com/stella-go/stella/generator/parser"
+)
+
+// SortableColumns returns the columns a list query may be sorted by,
+// the indexed columns and the columns commented with @sortable.
+func SortableColumns(statement *parser.Statement) []*parser.ColumnDefinition {
+	names := make(map[string]bool)
+	for _, pair := range statement.PrimaryKeyPairs {
+		for _, k := range pair {
+			names[strings.ToLower(k.Name)] = true
+		}
+	}
+	for _, pair := range statement.UniqKeyPairs {
+		for _, k := range pair {
+			names[strings.ToLower(k.Name)] = true
+		}
+	}
+	for _, pair := range statement.IndexKeyPairs {
+		for _, k := range pair {
+			names[strings.ToLower(k.Name)] = true
+		}
+	}
+	columns := make([]*parser.ColumnDefinition, 0)
+	for _, col := range statement.Columns {
+		if col.PrimaryKey || col.UniqueKey || names[strings.ToLower(col.ColumnName.Name)] || (col.Comment != nil && strings.Contains(col.Comment.Comment, "@sortable")) {
+			columns = append(columns, col)
+		}
+	}
+	return columns
+}
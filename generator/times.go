@@ -0,0 +1,114 @@
+// Copyright 2010-2024 the original author or authors.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+// 	http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package generator
+
+import (
+	"github.com/stella-go/stella/generator/parser"
+)
+
+// TimePolicy is how the time columns are written, Round is s, ms or μs for all of them, auto to follow
+// the fractional second precision of every column, or none, and Zone is utc or local to move
+// the DATETIME and DATE values into that time zone, empty to leave them in theirs.
+type TimePolicy struct {
+	Round string
+	Zone  string
+}
+
+// ParseTimePolicy normalizes the -round and -timezone flags, an empty round is auto and an unknown one is none.
+func ParseTimePolicy(round string, zone string) *TimePolicy {
+	switch round {
+	case "auto", "":
+		round = "auto"
+	case "s":
+	case "ms", "milli":
+		round = "ms"
+	case "μs", "us", "micro":
+		round = "μs"
+	default:
+		round = "none"
+	}
+	switch zone {
+	case "utc", "UTC":
+		zone = "utc"
+	case "local", "Local":
+		zone = "local"
+	default:
+		zone = ""
+	}
+	return &TimePolicy{Round: round, Zone: zone}
+}
+
+// IsTime reports whether the column holds a time.
+func IsTime(col *parser.ColumnDefinition) bool {
+	return col.Type == "DATE" || col.Type == "DATETIME" || col.Type == "TIMESTAMP"
+}
+
+// Precision returns the unit the values of the column are rounded to, s, ms, μs or d for the
+// DATE columns which are truncated to the day, empty for the columns which are not rounded.
+func (p *TimePolicy) Precision(col *parser.ColumnDefinition) string {
+	if !IsTime(col) || p.Round == "none" {
+		return ""
+	}
+	if col.Type == "DATE" {
+		return "d"
+	}
+	if p.Round != "auto" {
+		return p.Round
+	}
+	switch {
+	case col.Precision <= 0:
+		return "s"
+	case col.Precision <= 3:
+		return "ms"
+	default:
+		return "μs"
+	}
+}
+
+// Duration returns the go expression of the duration the DATETIME and TIMESTAMP values of the column
+// are rounded to, the exact fractional second precision of the column when Round is auto.
+func (p *TimePolicy) Duration(col *parser.ColumnDefinition) string {
+	if col.Type == "DATE" {
+		return ""
+	}
+	if p.Round == "auto" && col.Precision > 0 && col.Precision != 3 && col.Precision < 6 {
+		return map[int]string{
+			1: "100 * time.Millisecond",
+			2: "10 * time.Millisecond",
+			4: "100 * time.Microsecond",
+			5: "10 * time.Microsecond",
+		}[col.Precision]
+	}
+	return map[string]string{
+		"s":  "time.Second",
+		"ms": "time.Millisecond",
+		"μs": "time.Microsecond",
+	}[p.Precision(col)]
+}
+
+// Location returns the go expression of the time zone of the column, empty when it is left alone,
+// TIMESTAMP values are instants and never moved.
+func (p *TimePolicy) Location(col *parser.ColumnDefinition) string {
+	if col.Type != "DATE" && col.Type != "DATETIME" {
+		return ""
+	}
+	switch p.Zone {
+	case "utc":
+		return "time.UTC"
+	case "local":
+		return "time.Local"
+	}
+	return ""
+}
@@ -0,0 +1,54 @@
+// Copyright 2010-2024 the original author or authors.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+// 	http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package generator
+
+import (
+	"strings"
+
+	"github.com/stella-go/stella/generator/parser"
+)
+
+// MarkVersions turns on optimistic locking, a column commented with @version is the version of its
+// table, with convention an integer column named version is too.
+func MarkVersions(statements []*parser.Statement, convention bool) {
+	for _, statement := range statements {
+		marked := false
+		for _, col := range statement.Columns {
+			if col.Comment != nil && strings.Contains(col.Comment.Comment, "@version") {
+				col.Version, marked = true, true
+				break
+			}
+		}
+		if marked || !convention {
+			continue
+		}
+		for _, col := range statement.Columns {
+			if strings.EqualFold(col.ColumnName.Name, "version") && (col.Type == "TINYINT" || col.Type == "INT" || col.Type == "BIGINT") {
+				col.Version = true
+				break
+			}
+		}
+	}
+}
+
+// VersionColumn returns the column used for optimistic locking, nil when MarkVersions marked none.
+func VersionColumn(statement *parser.Statement) *parser.ColumnDefinition {
+	for _, col := range statement.Columns {
+		if col.Version {
+			return col
+		}
+	}
+	return nil
+}
@@ -13,6 +13,19 @@ func Run(filename string, needSimplify bool) ([]byte, error) {
 		return nil, err
 	}
 
+	res, err := Source(filename, src, needSimplify)
+	if err != nil {
+		return nil, err
+	}
+
+	if bytes.Equal(src, res) {
+		return nil, nil
+	}
+
+	return res, nil
+}
+
+func Source(filename string, src []byte, needSimplify bool) ([]byte, error) {
 	initParserMode()
 
 	file, sourceAdj, indentAdj, err := parse(fileSet, filename, src, false)
@@ -26,14 +39,9 @@ func Run(filename string, needSimplify bool) ([]byte, error) {
 		simplify(file)
 	}
 
-	res, err := format(fileSet, file, sourceAdj, indentAdj, src, printer.Config{Mode: printerMode, Tabwidth: tabWidth})
-	if err != nil {
-		return nil, err
-	}
-
-	if bytes.Equal(src, res) {
-		return nil, nil
-	}
+	return format(fileSet, file, sourceAdj, indentAdj, src, printer.Config{Mode: printerMode, Tabwidth: tabWidth})
+}
 
-	return res, nil
+func Diff(b1, b2 []byte, filename string) ([]byte, error) {
+	return diff(b1, b2, filename)
 }